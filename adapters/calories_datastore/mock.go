@@ -5,6 +5,7 @@
 package calories_datastore
 
 import (
+	context "context"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
 )
@@ -33,16 +34,16 @@ func (m *MockCaloriesDatastore) EXPECT() *MockCaloriesDatastoreMockRecorder {
 }
 
 // GetCalories mocks base method
-func (m *MockCaloriesDatastore) GetCalories(arg0 string) (*int, error) {
+func (m *MockCaloriesDatastore) GetCalories(arg0 context.Context, arg1 string) (*int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCalories", arg0)
+	ret := m.ctrl.Call(m, "GetCalories", arg0, arg1)
 	ret0, _ := ret[0].(*int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetCalories indicates an expected call of GetCalories
-func (mr *MockCaloriesDatastoreMockRecorder) GetCalories(arg0 interface{}) *gomock.Call {
+func (mr *MockCaloriesDatastoreMockRecorder) GetCalories(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCalories", reflect.TypeOf((*MockCaloriesDatastore)(nil).GetCalories), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCalories", reflect.TypeOf((*MockCaloriesDatastore)(nil).GetCalories), arg0, arg1)
 }
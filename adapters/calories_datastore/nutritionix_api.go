@@ -2,6 +2,7 @@ package calories_datastore
 
 import (
 	"calories-counter/models"
+	"context"
 	"encoding/json"
 	log "github.com/sirupsen/logrus"
 	"net/http"
@@ -41,11 +42,11 @@ func NewNutritionixApi(appId, apiKey string) models.CaloriesDatastore {
 	}
 }
 
-func (a *NutritionixApi) GetCalories(mealName string) (*int, error) {
+func (a *NutritionixApi) GetCalories(ctx context.Context, mealName string) (*int, error) {
 	log.Info("nutritionix api called")
 	mealName = strings.ReplaceAll(mealName, " ", "%20")
 	client := http.Client{}
-	request, err := http.NewRequest("GET", apiURL+mealName+"&self=false&detailed=true", nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", apiURL+mealName+"&self=false&detailed=true", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,116 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"sort"
+	"time"
+)
+
+func (d *MemoryStore) GetAccount(ctx context.Context, accountID string) (*models.Account, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	account, ok := d.accounts[accountID]
+	if !ok {
+		return nil, models.ErrAccountNotFound
+	}
+	return &account, nil
+}
+
+func (d *MemoryStore) GetAccountByName(ctx context.Context, name string) (*models.Account, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, account := range d.accounts {
+		if account.Name == name {
+			return &account, nil
+		}
+	}
+	return nil, models.ErrAccountNotFound
+}
+
+func (d *MemoryStore) DeleteAccount(ctx context.Context, accountID string, deletedAt time.Time, restoreTokenHash string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	account, ok := d.accounts[accountID]
+	if !ok || account.DeletedAt != nil {
+		return models.ErrAccountNotFound
+	}
+	deletedAt = time.Unix(deletedAt.Unix(), 0)
+	account.DeletedAt = &deletedAt
+	d.accounts[accountID] = account
+	d.restoreTokens[restoreTokenHash] = accountID
+	return nil
+}
+
+func (d *MemoryStore) RestoreAccount(ctx context.Context, restoreTokenHash string, deletedSince time.Time) (*models.Account, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	accountID, ok := d.restoreTokens[restoreTokenHash]
+	if !ok {
+		return nil, models.ErrAccountNotFound
+	}
+	account := d.accounts[accountID]
+	if account.DeletedAt == nil || account.DeletedAt.Unix() < deletedSince.Unix() {
+		return nil, models.ErrAccountNotFound
+	}
+	delete(d.restoreTokens, restoreTokenHash)
+	account.DeletedAt = nil
+	d.accounts[accountID] = account
+	return &account, nil
+}
+
+func (d *MemoryStore) GetDeletedAccounts(ctx context.Context, before time.Time) ([]models.Account, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	accounts := make([]models.Account, 0)
+	for _, account := range d.accounts {
+		if account.DeletedAt != nil && account.DeletedAt.Unix() < before.Unix() {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if !accounts[i].DeletedAt.Equal(*accounts[j].DeletedAt) {
+			return accounts[i].DeletedAt.Before(*accounts[j].DeletedAt)
+		}
+		return accounts[i].ID < accounts[j].ID
+	})
+	return accounts, nil
+}
+
+func (d *MemoryStore) PurgeAccount(ctx context.Context, accountID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if account, ok := d.accounts[accountID]; !ok || account.DeletedAt == nil {
+		return models.ErrAccountNotFound
+	}
+	for id, u := range d.users {
+		if u.user.AccountID == accountID {
+			d.deleteUser(id)
+		}
+	}
+	delete(d.twoFactorRoles, accountID)
+	delete(d.roles, accountID)
+	for id, invitation := range d.invitations {
+		if invitation.AccountID == accountID {
+			delete(d.invitations, id)
+		}
+	}
+	for hash, id := range d.restoreTokens {
+		if id == accountID {
+			delete(d.restoreTokens, hash)
+		}
+	}
+	delete(d.accounts, accountID)
+	return nil
+}
+
+// accountDeleted has to be called with d.mu held
+func (d *MemoryStore) accountDeleted(accountID string) bool {
+	return d.accounts[accountID].DeletedAt != nil
+}
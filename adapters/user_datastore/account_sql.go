@@ -0,0 +1,160 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// accountTables keep data of an account by account_id, users are deleted after data of each user
+var accountTables = []string{"teams", "roles", "two_factor_roles", "ownership_transfers", "invitations", "users"}
+
+type accountRow struct {
+	ID        string        `db:"id"`
+	Name      string        `db:"name"`
+	DeletedAt sql.NullInt64 `db:"deleted_at"`
+}
+
+func (r accountRow) account() models.Account {
+	account := models.Account{ID: r.ID, Name: r.Name}
+	if r.DeletedAt.Valid {
+		deletedAt := time.Unix(r.DeletedAt.Int64, 0)
+		account.DeletedAt = &deletedAt
+	}
+	return account
+}
+
+func (d *sqlStore) GetAccount(ctx context.Context, accountID string) (*models.Account, error) {
+	return d.getAccount(ctx, `SELECT id, name, deleted_at FROM accounts WHERE id=?`, accountID)
+}
+
+func (d *sqlStore) GetAccountByName(ctx context.Context, name string) (*models.Account, error) {
+	return d.getAccount(ctx, `SELECT id, name, deleted_at FROM accounts WHERE name=?`, name)
+}
+
+func (d *sqlStore) getAccount(ctx context.Context, query string, arg string) (*models.Account, error) {
+	var row accountRow
+	err := d.ext().QueryRowxContext(ctx, d.ext().Rebind(query), arg).StructScan(&row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrAccountNotFound
+		}
+		return nil, err
+	}
+	account := row.account()
+	return &account, nil
+}
+
+func (d *sqlStore) DeleteAccount(ctx context.Context, accountID string, deletedAt time.Time, restoreTokenHash string) error {
+	query := d.ext().Rebind(`UPDATE accounts SET deleted_at=?, restore_token_hash=? WHERE id=? AND deleted_at IS NULL`)
+	return d.updateAccount(ctx, query, deletedAt.Unix(), restoreTokenHash, accountID)
+}
+
+func (d *sqlStore) RestoreAccount(ctx context.Context, restoreTokenHash string, deletedSince time.Time) (*models.Account, error) {
+	var account models.Account
+	err := d.inTx(ctx, func(tx *sqlx.Tx) error {
+		var row accountRow
+		query := tx.Rebind(`SELECT id, name, deleted_at FROM accounts
+								WHERE restore_token_hash=? AND deleted_at IS NOT NULL AND deleted_at >= ?` + d.dialect.forUpdate())
+		err := tx.QueryRowxContext(ctx, query, restoreTokenHash, deletedSince.Unix()).StructScan(&row)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrAccountNotFound
+			}
+			return err
+		}
+
+		query = tx.Rebind(`UPDATE accounts SET deleted_at=NULL, restore_token_hash=NULL WHERE id=?`)
+		_, err = tx.ExecContext(ctx, query, row.ID)
+		account = models.Account{ID: row.ID, Name: row.Name}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// updateAccount runs the update and returns ErrAccountNotFound when no account was updated
+func (d *sqlStore) updateAccount(ctx context.Context, query string, args ...interface{}) error {
+	res, err := d.ext().ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrAccountNotFound
+	}
+
+	return nil
+}
+
+func (d *sqlStore) GetDeletedAccounts(ctx context.Context, before time.Time) ([]models.Account, error) {
+	query := d.ext().Rebind(`SELECT id, name, deleted_at FROM accounts
+								WHERE deleted_at IS NOT NULL AND deleted_at < ?
+								ORDER BY deleted_at, id`)
+	rows, err := d.ext().QueryxContext(ctx, query, before.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	accounts := make([]models.Account, 0)
+	for rows.Next() {
+		var row accountRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, row.account())
+	}
+	return accounts, rows.Err()
+}
+
+func (d *sqlStore) PurgeAccount(ctx context.Context, accountID string) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		// the account row is locked, so a concurrent restore either commits first or waits for the purge
+		var id string
+		query := tx.Rebind(`SELECT id FROM accounts WHERE id=? AND deleted_at IS NOT NULL` + d.dialect.forUpdate())
+		err := tx.QueryRowxContext(ctx, query, accountID).Scan(&id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrAccountNotFound
+			}
+			return err
+		}
+
+		var usernames []string
+		err = tx.SelectContext(ctx, &usernames, tx.Rebind(`SELECT username FROM users WHERE account_id=?`), accountID)
+		if err != nil {
+			return err
+		}
+		for _, username := range usernames {
+			_, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM login_failures WHERE throttle_key=?`), models.UserThrottleKey(accountID, username))
+			if err != nil {
+				return err
+			}
+		}
+		for _, table := range userTables {
+			query := tx.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE user_id IN (SELECT id FROM users WHERE account_id=?)`, table))
+			_, err := tx.ExecContext(ctx, query, accountID)
+			if err != nil {
+				return err
+			}
+		}
+		for _, table := range accountTables {
+			_, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE account_id=?`, table)), accountID)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, tx.Rebind(`DELETE FROM accounts WHERE id=?`), accountID)
+		return err
+	})
+}
@@ -0,0 +1,71 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"sort"
+)
+
+func (d *MemoryStore) SaveAPIKey(ctx context.Context, key models.APIKey) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key.Scopes = append([]string{}, key.Scopes...)
+	d.apiKeys[key.ID] = key
+	return nil
+}
+
+func (d *MemoryStore) GetAPIKeys(ctx context.Context, accountID, userID string) ([]models.APIKey, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0)
+	for _, key := range d.apiKeys {
+		if key.AccountID == accountID && key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (d *MemoryStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, key := range d.apiKeys {
+		if key.KeyHash == keyHash {
+			return &key, nil
+		}
+	}
+	return nil, models.ErrAPIKeyNotFound
+}
+
+func (d *MemoryStore) DeleteAPIKey(ctx context.Context, accountID, userID, keyID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key, ok := d.apiKeys[keyID]
+	if !ok || key.AccountID != accountID || key.UserID != userID {
+		return models.ErrAPIKeyNotFound
+	}
+	delete(d.apiKeys, keyID)
+	return nil
+}
+
+func (d *MemoryStore) DeleteUserAPIKeys(ctx context.Context, accountID, userID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, key := range d.apiKeys {
+		if key.AccountID == accountID && key.UserID == userID {
+			delete(d.apiKeys, id)
+		}
+	}
+	return nil
+}
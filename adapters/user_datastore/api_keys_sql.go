@@ -0,0 +1,104 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type apiKeyRow struct {
+	ID        string        `db:"id"`
+	UserID    string        `db:"user_id"`
+	AccountID string        `db:"account_id"`
+	Name      string        `db:"name"`
+	Prefix    string        `db:"prefix"`
+	KeyHash   string        `db:"key_hash"`
+	Scopes    string        `db:"scopes"`
+	ExpiresAt sql.NullInt64 `db:"expires_at"`
+	CreatedAt int64         `db:"created_at"`
+}
+
+func (r apiKeyRow) apiKey() models.APIKey {
+	key := models.APIKey{
+		ID:        r.ID,
+		UserID:    r.UserID,
+		AccountID: r.AccountID,
+		Name:      r.Name,
+		Prefix:    r.Prefix,
+		KeyHash:   r.KeyHash,
+		Scopes:    splitList(r.Scopes),
+		CreatedAt: time.Unix(r.CreatedAt, 0),
+	}
+	if r.ExpiresAt.Valid {
+		expiresAt := time.Unix(r.ExpiresAt.Int64, 0)
+		key.ExpiresAt = &expiresAt
+	}
+	return key
+}
+
+const apiKeyColumns = `id, user_id, account_id, name, prefix, key_hash, scopes, expires_at, created_at`
+
+func (d *sqlStore) SaveAPIKey(ctx context.Context, key models.APIKey) error {
+	var expiresAt sql.NullInt64
+	if key.ExpiresAt != nil {
+		expiresAt = sql.NullInt64{Int64: key.ExpiresAt.Unix(), Valid: true}
+	}
+	query := d.ext().Rebind(`INSERT INTO api_keys (` + apiKeyColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	_, err := d.ext().ExecContext(ctx, query, key.ID, key.UserID, key.AccountID, key.Name, key.Prefix, key.KeyHash,
+		joinList(key.Scopes), expiresAt, key.CreatedAt.Unix())
+	return err
+}
+
+func (d *sqlStore) GetAPIKeys(ctx context.Context, accountID, userID string) ([]models.APIKey, error) {
+	query := d.ext().Rebind(`SELECT ` + apiKeyColumns + ` FROM api_keys WHERE account_id=? AND user_id=?
+								ORDER BY created_at, id`)
+	var rows []apiKeyRow
+	err := sqlx.SelectContext(ctx, d.ext(), &rows, query, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]models.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row.apiKey())
+	}
+	return keys, nil
+}
+
+func (d *sqlStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := d.ext().Rebind(`SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash=?`)
+	var row apiKeyRow
+	err := d.ext().QueryRowxContext(ctx, query, keyHash).StructScan(&row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	key := row.apiKey()
+	return &key, nil
+}
+
+func (d *sqlStore) DeleteAPIKey(ctx context.Context, accountID, userID, keyID string) error {
+	query := d.ext().Rebind(`DELETE FROM api_keys WHERE account_id=? AND user_id=? AND id=?`)
+	res, err := d.ext().ExecContext(ctx, query, accountID, userID, keyID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (d *sqlStore) DeleteUserAPIKeys(ctx context.Context, accountID, userID string) error {
+	query := d.ext().Rebind(`DELETE FROM api_keys WHERE account_id=? AND user_id=?`)
+	_, err := d.ext().ExecContext(ctx, query, accountID, userID)
+	return err
+}
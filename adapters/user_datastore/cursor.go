@@ -0,0 +1,67 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// mealCursor is a position in the meals list in the default order (date, time descending, id),
+// meals after the cursor are the meals ordered after the meal it was created from
+type mealCursor struct {
+	Date string `json:"d"`
+	Time string `json:"t"`
+	ID   string `json:"i"`
+}
+
+func encodeMealCursor(meal models.Meal) string {
+	b, _ := json.Marshal(mealCursor{Date: meal.Date, Time: meal.Time, ID: meal.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeMealCursor(cursor string) (*mealCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+
+	var c mealCursor
+	err = json.Unmarshal(b, &c)
+	if err != nil || c.ID == "" {
+		return nil, models.ErrInvalidCursor
+	}
+	if _, err := time.Parse("2006-01-02", c.Date); err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	if _, err := time.Parse("15:04:05", c.Time); err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// nextMealCursor returns cursor after the last of meals when they are in the default order
+func nextMealCursor(params models.ListParams, meals []models.Meal) string {
+	if params.Sort != "" || len(meals) == 0 {
+		return ""
+	}
+	return encodeMealCursor(meals[len(meals)-1])
+}
+
+// mealsCursorCondition checks cursor can be used with params and returns condition selecting meals after it
+func mealsCursorCondition(params models.ListParams) (string, []interface{}, error) {
+	if params.Cursor == "" {
+		return "", nil, nil
+	}
+	if params.Sort != "" {
+		return "", nil, models.ErrCursorWithSort
+	}
+	c, err := decodeMealCursor(params.Cursor)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return " AND (m.date > ? OR (m.date = ? AND (m.time < ? OR (m.time = ? AND m.id > ?))))",
+		[]interface{}{c.Date, c.Date, c.Time, c.Time, c.ID}, nil
+}
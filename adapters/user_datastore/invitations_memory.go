@@ -0,0 +1,95 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"sort"
+)
+
+func (d *MemoryStore) SaveInvitation(ctx context.Context, invitation models.Invitation) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.invitations[invitation.ID] = invitation
+	return nil
+}
+
+func (d *MemoryStore) GetInvitations(ctx context.Context, accountID string) ([]models.Invitation, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	invitations := make([]models.Invitation, 0)
+	for _, invitation := range d.invitations {
+		if invitation.AccountID == accountID {
+			invitations = append(invitations, invitation)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		if !invitations[i].CreatedAt.Equal(invitations[j].CreatedAt) {
+			return invitations[i].CreatedAt.Before(invitations[j].CreatedAt)
+		}
+		return invitations[i].ID < invitations[j].ID
+	})
+	return invitations, nil
+}
+
+func (d *MemoryStore) GetInvitation(ctx context.Context, accountID, invitationID string) (*models.Invitation, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	invitation, ok := d.invitations[invitationID]
+	if !ok || invitation.AccountID != accountID {
+		return nil, models.ErrInvitationNotFound
+	}
+	return &invitation, nil
+}
+
+func (d *MemoryStore) UpdateInvitation(ctx context.Context, invitation models.Invitation) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stored, ok := d.invitations[invitation.ID]
+	if !ok || stored.AccountID != invitation.AccountID {
+		return models.ErrInvitationNotFound
+	}
+	stored.TokenID = invitation.TokenID
+	stored.ExpiresAt = invitation.ExpiresAt
+	d.invitations[invitation.ID] = stored
+	return nil
+}
+
+func (d *MemoryStore) DeleteInvitation(ctx context.Context, accountID, invitationID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	invitation, ok := d.invitations[invitationID]
+	if !ok || invitation.AccountID != accountID {
+		return models.ErrInvitationNotFound
+	}
+	delete(d.invitations, invitationID)
+	return nil
+}
+
+func (d *MemoryStore) UseInvitation(ctx context.Context, accountID, invitationID, tokenID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	invitation, ok := d.invitations[invitationID]
+	if !ok || invitation.AccountID != accountID || invitation.TokenID != tokenID {
+		return models.ErrInvitationNotFound
+	}
+	delete(d.invitations, invitationID)
+	return nil
+}
+
+func (d *MemoryStore) DeleteRoleInvitations(ctx context.Context, accountID string, roleID int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, invitation := range d.invitations {
+		if invitation.AccountID == accountID && invitation.RoleID == roleID {
+			delete(d.invitations, id)
+		}
+	}
+	return nil
+}
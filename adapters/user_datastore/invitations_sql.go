@@ -0,0 +1,121 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type invitationRow struct {
+	ID        string `db:"id"`
+	AccountID string `db:"account_id"`
+	Name      string `db:"name"`
+	RoleID    int    `db:"role_id"`
+	InvitedBy string `db:"invited_by"`
+	TokenID   string `db:"token_id"`
+	ExpiresAt int64  `db:"expires_at"`
+	CreatedAt int64  `db:"created_at"`
+}
+
+func (r invitationRow) invitation() models.Invitation {
+	return models.Invitation{
+		ID:        r.ID,
+		AccountID: r.AccountID,
+		Name:      r.Name,
+		RoleID:    r.RoleID,
+		InvitedBy: r.InvitedBy,
+		TokenID:   r.TokenID,
+		ExpiresAt: time.Unix(r.ExpiresAt, 0),
+		CreatedAt: time.Unix(r.CreatedAt, 0),
+	}
+}
+
+const invitationColumns = `id, account_id, name, role_id, invited_by, token_id, expires_at, created_at`
+
+func (d *sqlStore) SaveInvitation(ctx context.Context, invitation models.Invitation) error {
+	query := d.ext().Rebind(`INSERT INTO invitations (` + invitationColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	_, err := d.ext().ExecContext(ctx, query, invitation.ID, invitation.AccountID, invitation.Name, invitation.RoleID,
+		invitation.InvitedBy, invitation.TokenID, invitation.ExpiresAt.Unix(), invitation.CreatedAt.Unix())
+	return err
+}
+
+func (d *sqlStore) GetInvitations(ctx context.Context, accountID string) ([]models.Invitation, error) {
+	query := d.ext().Rebind(`SELECT ` + invitationColumns + ` FROM invitations WHERE account_id=?
+								ORDER BY created_at, id`)
+	var rows []invitationRow
+	err := sqlx.SelectContext(ctx, d.ext(), &rows, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := make([]models.Invitation, 0, len(rows))
+	for _, row := range rows {
+		invitations = append(invitations, row.invitation())
+	}
+	return invitations, nil
+}
+
+func (d *sqlStore) GetInvitation(ctx context.Context, accountID, invitationID string) (*models.Invitation, error) {
+	query := d.ext().Rebind(`SELECT ` + invitationColumns + ` FROM invitations WHERE account_id=? AND id=?`)
+	var row invitationRow
+	err := d.ext().QueryRowxContext(ctx, query, accountID, invitationID).StructScan(&row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	invitation := row.invitation()
+	return &invitation, nil
+}
+
+func (d *sqlStore) UpdateInvitation(ctx context.Context, invitation models.Invitation) error {
+	query := d.ext().Rebind(`UPDATE invitations SET token_id=?, expires_at=? WHERE account_id=? AND id=?`)
+	res, err := d.ext().ExecContext(ctx, query, invitation.TokenID, invitation.ExpiresAt.Unix(),
+		invitation.AccountID, invitation.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (d *sqlStore) DeleteInvitation(ctx context.Context, accountID, invitationID string) error {
+	query := d.ext().Rebind(`DELETE FROM invitations WHERE account_id=? AND id=?`)
+	return d.deleteInvitation(ctx, query, accountID, invitationID)
+}
+
+func (d *sqlStore) UseInvitation(ctx context.Context, accountID, invitationID, tokenID string) error {
+	query := d.ext().Rebind(`DELETE FROM invitations WHERE account_id=? AND id=? AND token_id=?`)
+	return d.deleteInvitation(ctx, query, accountID, invitationID, tokenID)
+}
+
+// deleteInvitation runs the delete and returns ErrInvitationNotFound when no invitation was deleted
+func (d *sqlStore) deleteInvitation(ctx context.Context, query string, args ...interface{}) error {
+	res, err := d.ext().ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (d *sqlStore) DeleteRoleInvitations(ctx context.Context, accountID string, roleID int) error {
+	query := d.ext().Rebind(`DELETE FROM invitations WHERE account_id=? AND role_id=?`)
+	_, err := d.ext().ExecContext(ctx, query, accountID, roleID)
+	return err
+}
@@ -0,0 +1,631 @@
+package user_datastore
+
+import (
+	"calories-counter/filter"
+	"calories-counter/models"
+	"context"
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore implements models.UserDatastore, keeps all data in process memory.
+// It is meant for local development and tests, data is lost on restart.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	accounts map[string]models.Account
+	users    map[string]memoryUser
+	meals    map[string]map[string]models.Meal
+	settings map[string]models.Settings
+	calories map[string]map[string]memoryDailyCalories
+	// refreshTokens are indexed by token hash
+	refreshTokens map[string]models.RefreshToken
+	// revokedTokens are expiration times of revoked access tokens indexed by jti
+	revokedTokens map[string]time.Time
+	// totp are indexed by user id, recoveryCodes by code hash and twoFactorRoles by account id
+	totp           map[string]models.TOTP
+	recoveryCodes  map[string]memoryRecoveryCode
+	twoFactorRoles map[string][]int
+	// loginFailures are indexed by throttle key
+	loginFailures map[string]models.LoginFailures
+	// passwordResetTokens are indexed by token hash
+	passwordResetTokens map[string]models.PasswordResetToken
+	// apiKeys are indexed by key id
+	apiKeys map[string]models.APIKey
+	// roles are indexed by account id and role id
+	roles map[string]map[int]models.Role
+	teams map[memoryTeamMember]bool
+	// ownershipTransfers are indexed by account id
+	ownershipTransfers map[string]models.OwnershipTransfer
+	// invitations are indexed by invitation id
+	invitations map[string]models.Invitation
+	// restoreTokens are account ids of deleted accounts indexed by restore token hash
+	restoreTokens map[string]string
+}
+
+type memoryUser struct {
+	user     models.User
+	password models.Password
+}
+
+type memoryDailyCalories struct {
+	totalCalories   int
+	caloriesDeficit bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts:            make(map[string]models.Account),
+		restoreTokens:       make(map[string]string),
+		users:               make(map[string]memoryUser),
+		meals:               make(map[string]map[string]models.Meal),
+		settings:            make(map[string]models.Settings),
+		calories:            make(map[string]map[string]memoryDailyCalories),
+		refreshTokens:       make(map[string]models.RefreshToken),
+		revokedTokens:       make(map[string]time.Time),
+		totp:                make(map[string]models.TOTP),
+		recoveryCodes:       make(map[string]memoryRecoveryCode),
+		twoFactorRoles:      make(map[string][]int),
+		loginFailures:       make(map[string]models.LoginFailures),
+		passwordResetTokens: make(map[string]models.PasswordResetToken),
+		apiKeys:             make(map[string]models.APIKey),
+		roles:               make(map[string]map[int]models.Role),
+		teams:               make(map[memoryTeamMember]bool),
+		ownershipTransfers:  make(map[string]models.OwnershipTransfer),
+		invitations:         make(map[string]models.Invitation),
+	}
+}
+
+func (d *MemoryStore) Close() error {
+	return nil
+}
+
+// Do implements models.UnitOfWork, fn works on a copy of the data which replaces store data when fn succeeds.
+// Other calls to the store wait until fn returns.
+func (d *MemoryStore) Do(ctx context.Context, fn func(ctx context.Context, repos models.Repositories) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tx := d.clone()
+	err := fn(ctx, repositories(tx))
+	if err != nil {
+		return err
+	}
+
+	d.accounts = tx.accounts
+	d.restoreTokens = tx.restoreTokens
+	d.users = tx.users
+	d.meals = tx.meals
+	d.settings = tx.settings
+	d.calories = tx.calories
+	d.refreshTokens = tx.refreshTokens
+	d.revokedTokens = tx.revokedTokens
+	d.totp = tx.totp
+	d.recoveryCodes = tx.recoveryCodes
+	d.twoFactorRoles = tx.twoFactorRoles
+	d.loginFailures = tx.loginFailures
+	d.passwordResetTokens = tx.passwordResetTokens
+	d.apiKeys = tx.apiKeys
+	d.roles = tx.roles
+	d.teams = tx.teams
+	d.ownershipTransfers = tx.ownershipTransfers
+	d.invitations = tx.invitations
+	return nil
+}
+
+func (d *MemoryStore) GetUser(ctx context.Context, accountID, username string) (*models.User, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	u, ok := d.findUser(accountID, username)
+	if !ok || d.accountDeleted(accountID) {
+		return nil, models.ErrUserNotFound
+	}
+	user := u.user
+	return &user, nil
+}
+
+func (d *MemoryStore) GetUserPassword(ctx context.Context, accountID, username string) (*models.Password, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	u, ok := d.findUser(accountID, username)
+	if !ok || d.accountDeleted(accountID) {
+		return nil, models.ErrUserNotFound
+	}
+	pass := u.password
+	return &pass, nil
+}
+
+func (d *MemoryStore) UpdateUserPassword(ctx context.Context, accountID, userID string, pass models.Password) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	u, ok := d.users[userID]
+	if !ok || u.user.AccountID != accountID {
+		return models.ErrUserNotFound
+	}
+	u.password = pass
+	d.users[userID] = u
+
+	return nil
+}
+
+func (d *MemoryStore) GetUserById(ctx context.Context, accountID, userID string) (*models.User, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	u, ok := d.users[userID]
+	if !ok || u.user.AccountID != accountID || d.accountDeleted(accountID) {
+		return nil, models.ErrUserNotFound
+	}
+	user := u.user
+	return &user, nil
+}
+
+func (d *MemoryStore) SaveUser(ctx context.Context, accountID, username string, pass models.Password, role int) (*models.User, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.findUser(accountID, username); ok {
+		return nil, models.ErrUserAlreadyExists
+	}
+
+	user := models.User{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Username:  username,
+		RoleID:    role,
+	}
+	d.users[user.ID] = memoryUser{user: user, password: pass}
+
+	return &user, nil
+}
+
+func (d *MemoryStore) SaveRootUser(ctx context.Context, accountName, username string, pass models.Password) (*models.User, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, account := range d.accounts {
+		if account.Name == accountName {
+			return nil, models.ErrAccountAlreadyExists
+		}
+	}
+
+	user := models.User{
+		ID:        uuid.New().String(),
+		AccountID: uuid.New().String(),
+		Username:  username,
+		RoleID:    models.OwnerRole,
+	}
+	d.accounts[user.AccountID] = models.Account{ID: user.AccountID, Name: accountName}
+	d.users[user.ID] = memoryUser{user: user, password: pass}
+
+	return &user, nil
+}
+
+func (d *MemoryStore) UpdateUser(ctx context.Context, user models.User) (*models.User, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	u, ok := d.users[user.ID]
+	if !ok || u.user.AccountID != user.AccountID {
+		return &user, nil
+	}
+	if other, ok := d.findUser(user.AccountID, user.Username); ok && other.user.ID != user.ID {
+		return nil, models.ErrUserAlreadyExists
+	}
+	if user.RoleID != models.OwnerRole && d.lastOwner(user.AccountID, user.ID) {
+		return nil, models.ErrLastOwner
+	}
+
+	u.user.Username = user.Username
+	u.user.RoleID = user.RoleID
+	d.users[user.ID] = u
+
+	return &user, nil
+}
+
+func (d *MemoryStore) CountUsersWithRole(ctx context.Context, accountID string, roleID int) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	count := 0
+	for _, u := range d.users {
+		if u.user.AccountID == accountID && u.user.RoleID == roleID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// lastOwner reports whether userID is the only owner of the account, has to be called with d.mu locked
+func (d *MemoryStore) lastOwner(accountID, userID string) bool {
+	for _, u := range d.users {
+		if u.user.AccountID == accountID && u.user.RoleID == models.OwnerRole && u.user.ID != userID {
+			return false
+		}
+	}
+	u, ok := d.users[userID]
+	return ok && u.user.RoleID == models.OwnerRole
+}
+
+// DeleteUser deletes the user together with its data, its teams and ownership transfers from or to it
+func (d *MemoryStore) DeleteUser(ctx context.Context, accountID string, userID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	u, ok := d.users[userID]
+	if !ok || u.user.AccountID != accountID {
+		return models.ErrUserNotFound
+	}
+	if d.lastOwner(accountID, userID) {
+		return models.ErrLastOwner
+	}
+	d.deleteUser(userID)
+
+	return nil
+}
+
+func (d *MemoryStore) GetUsers(ctx context.Context, accountID string, params models.ListParams) (models.UserSlice, error) {
+	res := models.UserSlice{Items: make([]models.User, 0)}
+	expr, err := filter.Parse(params.Filter, filter.UserFields)
+	if err != nil {
+		return res, err
+	}
+	order, err := listSort(params.Sort, filter.UserSortFields, defaultUserSort)
+	if err != nil {
+		return res, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var users []models.User
+	for _, u := range d.users {
+		if u.user.AccountID != accountID {
+			continue
+		}
+		if filter.Eval(expr, userFields(u.user)) {
+			users = append(users, u.user)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return order.Less(userFields(users[i]), userFields(users[j]))
+	})
+
+	from, to := pageBounds(len(users), params.Page, params.PerPage)
+	res.Items = append(res.Items, users[from:to]...)
+	res.Total = len(users)
+	return res, nil
+}
+
+func (d *MemoryStore) SaveMeal(ctx context.Context, userID string, meal models.Meal) (*models.Meal, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	meal.ID = uuid.New().String()
+	if d.meals[userID] == nil {
+		d.meals[userID] = make(map[string]models.Meal)
+	}
+	meal.CaloriesDeficit = false
+	d.meals[userID][meal.ID] = meal
+
+	return &meal, nil
+}
+
+func (d *MemoryStore) UpdateMeal(ctx context.Context, userID string, newMeal models.Meal) (*models.Meal, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.meals[userID][newMeal.ID]; !ok {
+		return nil, models.ErrMealNotFound
+	}
+	newMeal.CaloriesDeficit = false
+	d.meals[userID][newMeal.ID] = newMeal
+
+	return &newMeal, nil
+}
+
+func (d *MemoryStore) GetMeals(ctx context.Context, userID string, params models.ListParams) (models.MealSlice, error) {
+	res := models.MealSlice{Items: make([]models.Meal, 0)}
+	expr, err := filter.Parse(params.Filter, filter.MealFields)
+	if err != nil {
+		return res, err
+	}
+	order, err := listSort(params.Sort, filter.MealSortFields, defaultMealSort)
+	if err != nil {
+		return res, err
+	}
+	if params.Cursor != "" && params.Sort != "" {
+		return res, models.ErrCursorWithSort
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var meals []models.Meal
+	for _, m := range d.meals[userID] {
+		m.CaloriesDeficit = d.calories[userID][m.Date].caloriesDeficit
+		if filter.Eval(expr, mealFields(m)) {
+			meals = append(meals, m)
+		}
+	}
+
+	sort.Slice(meals, func(i, j int) bool {
+		return order.Less(mealFields(meals[i]), mealFields(meals[j]))
+	})
+
+	from, to := pageBounds(len(meals), params.Page, params.PerPage)
+	if params.Cursor != "" {
+		c, err := decodeMealCursor(params.Cursor)
+		if err != nil {
+			return res, err
+		}
+		after := map[string]interface{}{"date": c.Date, "time": c.Time, "id": c.ID}
+		from = sort.Search(len(meals), func(i int) bool {
+			return order.Less(after, mealFields(meals[i]))
+		})
+		to = from + params.PerPage
+		if to > len(meals) {
+			to = len(meals)
+		}
+	}
+
+	res.Items = append(res.Items, meals[from:to]...)
+	if to < len(meals) {
+		res.NextCursor = nextMealCursor(params, res.Items)
+	}
+	res.Total = len(meals)
+	return res, nil
+}
+
+func (d *MemoryStore) GetMeal(ctx context.Context, userID, mealID string) (*models.Meal, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	meal, ok := d.meals[userID][mealID]
+	if !ok {
+		return nil, models.ErrMealNotFound
+	}
+	meal.CaloriesDeficit = d.calories[userID][meal.Date].caloriesDeficit
+
+	return &meal, nil
+}
+
+func (d *MemoryStore) DeleteMeal(ctx context.Context, userID string, mealID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.meals[userID][mealID]; !ok {
+		return models.ErrMealNotFound
+	}
+	delete(d.meals[userID], mealID)
+
+	return nil
+}
+
+func (d *MemoryStore) UpdateDailyTotal(ctx context.Context, userID, date string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.updateCaloriesDeficit(userID, date), nil
+}
+
+func (d *MemoryStore) GetDailyTotals(ctx context.Context, userID, from, to string) ([]models.DailyTotal, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	totals := []models.DailyTotal{}
+	for date, c := range d.calories[userID] {
+		if from != "" && date < from || to != "" && date > to {
+			continue
+		}
+		totals = append(totals, models.DailyTotal{Date: date, TotalCalories: c.totalCalories,
+			CaloriesDeficit: c.caloriesDeficit})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Date < totals[j].Date
+	})
+	return totals, nil
+}
+
+func (d *MemoryStore) UpdateSettings(ctx context.Context, userID string, settings models.Settings) (*models.Settings, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.settings[userID] = settings
+	for date, c := range d.calories[userID] {
+		c.caloriesDeficit = c.totalCalories < settings.ExpectedDailyCalories
+		d.calories[userID][date] = c
+	}
+
+	return &settings, nil
+}
+
+func (d *MemoryStore) GetSettings(ctx context.Context, userID string) (*models.Settings, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	settings := d.settings[userID]
+	return &settings, nil
+}
+
+// clone returns deep copy of the store data, has to be called with d.mu held
+func (d *MemoryStore) clone() *MemoryStore {
+	c := NewMemoryStore()
+	for id, account := range d.accounts {
+		c.accounts[id] = account
+	}
+	for hash, accountID := range d.restoreTokens {
+		c.restoreTokens[hash] = accountID
+	}
+	for id, u := range d.users {
+		c.users[id] = u
+	}
+	for userID, meals := range d.meals {
+		c.meals[userID] = make(map[string]models.Meal, len(meals))
+		for id, m := range meals {
+			c.meals[userID][id] = m
+		}
+	}
+	for userID, settings := range d.settings {
+		c.settings[userID] = settings
+	}
+	for userID, calories := range d.calories {
+		c.calories[userID] = make(map[string]memoryDailyCalories, len(calories))
+		for date, dc := range calories {
+			c.calories[userID][date] = dc
+		}
+	}
+	for hash, token := range d.refreshTokens {
+		c.refreshTokens[hash] = token
+	}
+	for jti, expiresAt := range d.revokedTokens {
+		c.revokedTokens[jti] = expiresAt
+	}
+	for userID, totp := range d.totp {
+		c.totp[userID] = totp
+	}
+	for hash, code := range d.recoveryCodes {
+		c.recoveryCodes[hash] = code
+	}
+	for accountID, roleIDs := range d.twoFactorRoles {
+		c.twoFactorRoles[accountID] = append([]int{}, roleIDs...)
+	}
+	for key, failures := range d.loginFailures {
+		c.loginFailures[key] = failures
+	}
+	for hash, token := range d.passwordResetTokens {
+		c.passwordResetTokens[hash] = token
+	}
+	for id, key := range d.apiKeys {
+		key.Scopes = append([]string{}, key.Scopes...)
+		c.apiKeys[id] = key
+	}
+	for accountID, roles := range d.roles {
+		c.roles[accountID] = make(map[int]models.Role, len(roles))
+		for id, role := range roles {
+			role.Permissions = append([]string{}, role.Permissions...)
+			c.roles[accountID][id] = role
+		}
+	}
+	for member := range d.teams {
+		c.teams[member] = true
+	}
+	for accountID, transfer := range d.ownershipTransfers {
+		c.ownershipTransfers[accountID] = transfer
+	}
+	for id, invitation := range d.invitations {
+		c.invitations[id] = invitation
+	}
+	return c
+}
+
+// deleteUser deletes the user with its data, has to be called with d.mu locked
+func (d *MemoryStore) deleteUser(userID string) {
+	if u, ok := d.users[userID]; ok {
+		delete(d.loginFailures, models.UserThrottleKey(u.user.AccountID, u.user.Username))
+	}
+	delete(d.users, userID)
+	delete(d.meals, userID)
+	delete(d.settings, userID)
+	delete(d.calories, userID)
+	delete(d.totp, userID)
+	for hash, token := range d.refreshTokens {
+		if token.UserID == userID {
+			delete(d.refreshTokens, hash)
+		}
+	}
+	for hash, code := range d.recoveryCodes {
+		if code.userID == userID {
+			delete(d.recoveryCodes, hash)
+		}
+	}
+	for hash, token := range d.passwordResetTokens {
+		if token.UserID == userID {
+			delete(d.passwordResetTokens, hash)
+		}
+	}
+	for id, key := range d.apiKeys {
+		if key.UserID == userID {
+			delete(d.apiKeys, id)
+		}
+	}
+	for member := range d.teams {
+		if member.managerID == userID || member.userID == userID {
+			delete(d.teams, member)
+		}
+	}
+	for accountID, transfer := range d.ownershipTransfers {
+		if transfer.FromUserID == userID || transfer.ToUserID == userID {
+			delete(d.ownershipTransfers, accountID)
+		}
+	}
+}
+
+// findUser has to be called with d.mu held
+func (d *MemoryStore) findUser(accountID, username string) (memoryUser, bool) {
+	for _, u := range d.users {
+		if u.user.AccountID == accountID && u.user.Username == username {
+			return u, true
+		}
+	}
+	return memoryUser{}, false
+}
+
+// updateCaloriesDeficit recalculates users daily total for given date, has to be called with d.mu locked
+func (d *MemoryStore) updateCaloriesDeficit(userID string, date string) bool {
+	var totalCalories int
+	for _, m := range d.meals[userID] {
+		if m.Date == date {
+			totalCalories += m.Calories
+		}
+	}
+
+	caloriesDeficit := totalCalories < d.settings[userID].ExpectedDailyCalories
+	if d.calories[userID] == nil {
+		d.calories[userID] = make(map[string]memoryDailyCalories)
+	}
+	d.calories[userID][date] = memoryDailyCalories{
+		totalCalories:   totalCalories,
+		caloriesDeficit: caloriesDeficit,
+	}
+
+	return caloriesDeficit
+}
+
+// pageBounds returns slice bounds equivalent to LIMIT perPage OFFSET page*perPage
+func pageBounds(total, page, perPage int) (int, int) {
+	from := page * perPage
+	if from > total || from < 0 {
+		from = total
+	}
+	to := from + perPage
+	if to > total {
+		to = total
+	}
+	return from, to
+}
+
+// userFields and mealFields return values of filter and sort fields, see filter.UserFields and filter.MealFields
+func userFields(user models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       user.ID,
+		"username": user.Username,
+		"role_id":  user.RoleID,
+	}
+}
+
+func mealFields(meal models.Meal) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               meal.ID,
+		"date":             meal.Date,
+		"time":             meal.Time,
+		"name":             meal.Name,
+		"calories":         meal.Calories,
+		"calories_deficit": meal.CaloriesDeficit,
+	}
+}
@@ -0,0 +1,13 @@
+package user_datastore
+
+import (
+	"calories-counter/adapters/user_datastore/storetest"
+	"calories-counter/models"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) models.UserDatastore {
+		return NewMemoryStore()
+	})
+}
@@ -0,0 +1,169 @@
+package user_datastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrSchemaAhead = errors.New("database schema is newer than supported by this binary, upgrade the application")
+	ErrNoMigration = errors.New("no migration to revert")
+	ErrNoSchema    = errors.New("in-memory datastore has no schema to migrate")
+)
+
+const schemaVersionTable = `CREATE TABLE IF NOT EXISTS schema_version
+(
+    version      INT          NOT NULL PRIMARY KEY,
+    name         VARCHAR(100) NOT NULL,
+    applied_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+
+// migration is a single schema change, statements are executed one by one in given order
+type migration struct {
+	version int
+	name    string
+	up      []string
+	down    []string
+	// noForeignKeys runs the statements with SQLite foreign key enforcement off, tables referenced by
+	// foreign keys can be rebuilt only that way, see https://www.sqlite.org/lang_altertable.html.
+	// Foreign keys are checked before the migration is committed.
+	noForeignKeys bool
+}
+
+// Migrator applies versioned migrations and records them in schema_version table
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []migration
+}
+
+func newMigrator(db *sqlx.DB, dialect sqlDialect) *Migrator {
+	return &Migrator{db: db, migrations: dialect.migrations()}
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Latest returns the newest schema version known to this binary
+func (m *Migrator) Latest() int {
+	return m.migrations[len(m.migrations)-1].version
+}
+
+// Version returns the schema version of the database, 0 means no migration was applied yet
+func (m *Migrator) Version() (int, error) {
+	_, err := m.db.Exec(schemaVersionTable)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = m.db.QueryRowx(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Up applies all pending migrations, fails with ErrSchemaAhead when database is newer than the binary
+func (m *Migrator) Up() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return ErrSchemaAhead
+	}
+
+	for _, mig := range m.migrations {
+		if mig.version <= version {
+			continue
+		}
+		log.Infof("applying migration %d %s", mig.version, mig.name)
+		err := m.apply(mig, mig.up, m.db.Rebind(`INSERT INTO schema_version (version, name) VALUES (?, ?)`), mig.version, mig.name)
+		if err != nil {
+			return fmt.Errorf("migration %d %s failed: %w", mig.version, mig.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down reverts the most recently applied migration
+func (m *Migrator) Down() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return ErrSchemaAhead
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.version != version {
+			continue
+		}
+		log.Infof("reverting migration %d %s", mig.version, mig.name)
+		err := m.apply(mig, mig.down, m.db.Rebind(`DELETE FROM schema_version WHERE version=?`), mig.version)
+		if err != nil {
+			return fmt.Errorf("revert of migration %d %s failed: %w", mig.version, mig.name, err)
+		}
+		return nil
+	}
+
+	return ErrNoMigration
+}
+
+func (m *Migrator) apply(mig migration, statements []string, record string, args ...interface{}) error {
+	ctx := context.Background()
+	// pragma has to be set outside of the transaction, on the connection running it
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	if mig.noForeignKeys {
+		_, err = conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`)
+		if err != nil {
+			return err
+		}
+		defer func() { _, _ = conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`) }()
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range statements {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if mig.noForeignKeys {
+		var violations int
+		err = tx.QueryRowContext(ctx, `SELECT count(*) FROM pragma_foreign_key_check`).Scan(&violations)
+		if err == nil && violations > 0 {
+			err = fmt.Errorf("%d foreign key violations", violations)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
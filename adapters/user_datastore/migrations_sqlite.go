@@ -0,0 +1,358 @@
+package user_datastore
+
+func (sqliteDialect) migrations() []migration {
+	return []migration{
+		{
+			version: 1,
+			name:    "init",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS users
+(
+    id          CHAR(36) PRIMARY KEY NOT NULL,
+    account_id  CHAR(36)             NOT NULL,
+    username    VARCHAR(50)          NOT NULL,
+    password    VARCHAR(50)          NOT NULL,
+    role_id     INT                  NOT NULL,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_account UNIQUE (account_id, username)
+)`,
+				`CREATE TABLE IF NOT EXISTS users_meals
+(
+    id          CHAR(36) PRIMARY KEY NOT NULL,
+    user_id     CHAR(36)             NOT NULL,
+    name        VARCHAR(50)          NOT NULL,
+    date        DATE                 NOT NULL,
+    time        TIME                 NOT NULL,
+    calories    INT                  NOT NULL,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id)
+)`,
+				`CREATE TABLE IF NOT EXISTS users_settings
+(
+    id                      INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id                 CHAR(36) NOT NULL,
+    expected_daily_calories INT      NOT NULL,
+    create_time             TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    update_time             TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT unique_user_id UNIQUE (user_id)
+)`,
+				`CREATE TABLE IF NOT EXISTS users_calories
+(
+    id               INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id          CHAR(36) NOT NULL,
+    date             DATE     NOT NULL,
+    total_calories   INT      NOT NULL,
+    calories_deficit TINYINT  NOT NULL,
+    create_time      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    update_time      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT unique_user_id_date UNIQUE (user_id, date)
+)`,
+			},
+			down: []string{
+				`DROP TABLE users_calories`,
+				`DROP TABLE users_settings`,
+				`DROP TABLE users_meals`,
+				`DROP TABLE users`,
+			},
+		},
+		{
+			version: 2,
+			name:    "password_hash",
+			up: []string{
+				`ALTER TABLE users ADD COLUMN password_algorithm VARCHAR(20) NOT NULL DEFAULT 'plaintext'`,
+				`ALTER TABLE users ADD COLUMN password_cost INT NOT NULL DEFAULT 0`,
+			},
+			// bundled SQLite can not drop columns, users table is rebuilt without them
+			down: []string{
+				`CREATE TABLE users_old
+(
+    id          CHAR(36) PRIMARY KEY NOT NULL,
+    account_id  CHAR(36)             NOT NULL,
+    username    VARCHAR(50)          NOT NULL,
+    password    VARCHAR(50)          NOT NULL,
+    role_id     INT                  NOT NULL,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_account UNIQUE (account_id, username)
+)`,
+				`INSERT INTO users_old (id, account_id, username, password, role_id, create_time, update_time)
+SELECT id, account_id, username, password, role_id, create_time, update_time
+FROM users`,
+				`DROP TABLE users`,
+				`ALTER TABLE users_old RENAME TO users`,
+			},
+			noForeignKeys: true,
+		},
+		{
+			version: 3,
+			name:    "refresh_tokens",
+			// tokens are not tied to users by foreign key, tokens of deleted users are rejected when used
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    token_hash  CHAR(64) PRIMARY KEY NOT NULL,
+    family_id   CHAR(36)             NOT NULL,
+    user_id     CHAR(36)             NOT NULL,
+    account_id  CHAR(36)             NOT NULL,
+    expires_at  BIGINT               NOT NULL,
+    used        TINYINT              NOT NULL DEFAULT 0,
+    revoked     TINYINT              NOT NULL DEFAULT 0,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`,
+				`CREATE INDEX refresh_tokens_family_id ON refresh_tokens (family_id)`,
+			},
+			down: []string{
+				`DROP TABLE refresh_tokens`,
+			},
+		},
+		{
+			version: 4,
+			name:    "revoked_tokens",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS revoked_tokens
+(
+    jti         CHAR(36) PRIMARY KEY NOT NULL,
+    expires_at  BIGINT               NOT NULL,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`,
+				`CREATE INDEX refresh_tokens_user_id ON refresh_tokens (account_id, user_id)`,
+			},
+			down: []string{
+				`DROP TABLE revoked_tokens`,
+				`DROP INDEX refresh_tokens_user_id`,
+			},
+		},
+		{
+			version: 5,
+			name:    "two_factor",
+			// like refresh tokens, secrets are not tied to users by foreign key
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS users_totp
+(
+    user_id     CHAR(36) PRIMARY KEY NOT NULL,
+    account_id  CHAR(36)             NOT NULL,
+    secret      VARCHAR(64)          NOT NULL,
+    enabled     TINYINT              NOT NULL DEFAULT 0,
+    last_step   BIGINT               NOT NULL DEFAULT 0,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`,
+				`CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    code_hash   CHAR(64) PRIMARY KEY NOT NULL,
+    user_id     CHAR(36)             NOT NULL,
+    account_id  CHAR(36)             NOT NULL,
+    used        TINYINT              NOT NULL DEFAULT 0,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`,
+				`CREATE INDEX recovery_codes_user_id ON recovery_codes (account_id, user_id)`,
+				`CREATE TABLE IF NOT EXISTS two_factor_roles
+(
+    account_id CHAR(36) NOT NULL,
+    role_id    INT      NOT NULL,
+    PRIMARY KEY (account_id, role_id)
+)`,
+			},
+			down: []string{
+				`DROP TABLE two_factor_roles`,
+				`DROP TABLE recovery_codes`,
+				`DROP TABLE users_totp`,
+			},
+		},
+		{
+			version: 6,
+			name:    "login_failures",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS login_failures
+(
+    throttle_key VARCHAR(255) PRIMARY KEY NOT NULL,
+    failures     INT                      NOT NULL,
+    last_failure BIGINT                   NOT NULL
+)`,
+			},
+			down: []string{
+				`DROP TABLE login_failures`,
+			},
+		},
+		{
+			version: 7,
+			name:    "password_reset_tokens",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS password_reset_tokens
+(
+    token_hash CHAR(64) PRIMARY KEY NOT NULL,
+    user_id    CHAR(36)             NOT NULL,
+    account_id CHAR(36)             NOT NULL,
+    expires_at BIGINT               NOT NULL,
+    UNIQUE (account_id, user_id)
+)`,
+			},
+			down: []string{
+				`DROP TABLE password_reset_tokens`,
+			},
+		},
+		{
+			version: 8,
+			name:    "accounts",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS accounts
+(
+    id          CHAR(36) PRIMARY KEY NOT NULL,
+    name        VARCHAR(50)          NOT NULL,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_account_name UNIQUE (name)
+)`,
+				// accounts created before are named by their owner, which was unique, or by their ID
+				// when the owner was deleted
+				`INSERT INTO accounts (id, name) SELECT account_id, username FROM users WHERE role_id = 3`,
+				`INSERT INTO accounts (id, name)
+SELECT DISTINCT account_id, account_id FROM users WHERE account_id NOT IN (SELECT id FROM accounts)`,
+			},
+			down: []string{
+				`DROP TABLE accounts`,
+			},
+		},
+		{
+			version: 9,
+			name:    "api_keys",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS api_keys
+(
+    id         CHAR(36) PRIMARY KEY NOT NULL,
+    user_id    CHAR(36)             NOT NULL,
+    account_id CHAR(36)             NOT NULL,
+    name       VARCHAR(50)          NOT NULL,
+    prefix     VARCHAR(16)          NOT NULL,
+    key_hash   CHAR(64)             NOT NULL,
+    scopes     VARCHAR(255)         NOT NULL,
+    expires_at BIGINT,
+    created_at BIGINT               NOT NULL,
+    CONSTRAINT unique_key_hash UNIQUE (key_hash)
+)`,
+				`CREATE INDEX api_keys_user_id ON api_keys (account_id, user_id)`,
+			},
+			down: []string{
+				`DROP TABLE api_keys`,
+			},
+		},
+		{
+			version: 10,
+			name:    "roles",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS roles
+(
+    account_id  CHAR(36)     NOT NULL,
+    id          INT          NOT NULL,
+    name        VARCHAR(50)  NOT NULL,
+    permissions VARCHAR(255) NOT NULL,
+    PRIMARY KEY (account_id, id),
+    CONSTRAINT unique_role_name UNIQUE (account_id, name)
+)`,
+			},
+			down: []string{
+				`DROP TABLE roles`,
+			},
+		},
+		{
+			version: 11,
+			name:    "teams",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS teams
+(
+    account_id CHAR(36) NOT NULL,
+    manager_id CHAR(36) NOT NULL,
+    user_id    CHAR(36) NOT NULL,
+    PRIMARY KEY (manager_id, user_id)
+)`,
+				`CREATE INDEX teams_user_id ON teams (user_id)`,
+			},
+			down: []string{
+				`DROP TABLE teams`,
+			},
+		},
+		{
+			version: 12,
+			name:    "ownership_transfers",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS ownership_transfers
+(
+    account_id     CHAR(36) PRIMARY KEY NOT NULL,
+    from_user_id   CHAR(36)             NOT NULL,
+    to_user_id     CHAR(36)             NOT NULL,
+    keep_ownership TINYINT              NOT NULL,
+    expires_at     BIGINT               NOT NULL
+)`,
+			},
+			down: []string{
+				`DROP TABLE ownership_transfers`,
+			},
+		},
+		{
+			version: 13,
+			name:    "invitations",
+			up: []string{
+				`CREATE TABLE IF NOT EXISTS invitations
+(
+    id         CHAR(36) PRIMARY KEY NOT NULL,
+    account_id CHAR(36)             NOT NULL,
+    name       VARCHAR(50)          NOT NULL,
+    role_id    INT                  NOT NULL,
+    invited_by CHAR(36)             NOT NULL,
+    token_id   CHAR(36)             NOT NULL,
+    expires_at BIGINT               NOT NULL,
+    created_at BIGINT               NOT NULL
+)`,
+				`CREATE INDEX invitations_account_id ON invitations (account_id)`,
+			},
+			down: []string{
+				`DROP TABLE invitations`,
+			},
+		},
+		{
+			version: 14,
+			name:    "account_deletion",
+			up: []string{
+				`ALTER TABLE accounts ADD COLUMN deleted_at BIGINT`,
+			},
+			// bundled SQLite can not drop columns, accounts table is rebuilt without it
+			down: []string{
+				`CREATE TABLE accounts_old
+(
+    id          CHAR(36) PRIMARY KEY NOT NULL,
+    name        VARCHAR(50)          NOT NULL,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_account_name UNIQUE (name)
+)`,
+				`INSERT INTO accounts_old (id, name, create_time) SELECT id, name, create_time FROM accounts`,
+				`DROP TABLE accounts`,
+				`ALTER TABLE accounts_old RENAME TO accounts`,
+			},
+		},
+		{
+			version: 15,
+			name:    "account_restore_tokens",
+			up: []string{
+				`ALTER TABLE accounts ADD COLUMN restore_token_hash CHAR(64)`,
+				`CREATE UNIQUE INDEX accounts_restore_token_hash ON accounts (restore_token_hash)`,
+			},
+			// bundled SQLite can not drop columns, accounts table is rebuilt without it
+			down: []string{
+				`DROP INDEX accounts_restore_token_hash`,
+				`CREATE TABLE accounts_old
+(
+    id          CHAR(36) PRIMARY KEY NOT NULL,
+    name        VARCHAR(50)          NOT NULL,
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  BIGINT,
+    CONSTRAINT unique_account_name UNIQUE (name)
+)`,
+				`INSERT INTO accounts_old (id, name, create_time, deleted_at) SELECT id, name, create_time, deleted_at FROM accounts`,
+				`DROP TABLE accounts`,
+				`ALTER TABLE accounts_old RENAME TO accounts`,
+			},
+		},
+	}
+}
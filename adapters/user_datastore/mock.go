@@ -6,8 +6,10 @@ package user_datastore
 
 import (
 	models "calories-counter/models"
+	context "context"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockUserDatastore is a mock of UserDatastore interface
@@ -33,225 +35,1021 @@ func (m *MockUserDatastore) EXPECT() *MockUserDatastoreMockRecorder {
 	return m.recorder
 }
 
+// AddLoginFailure mocks base method
+func (m *MockUserDatastore) AddLoginFailure(arg0 context.Context, arg1 string, arg2, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddLoginFailure", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddLoginFailure indicates an expected call of AddLoginFailure
+func (mr *MockUserDatastoreMockRecorder) AddLoginFailure(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLoginFailure", reflect.TypeOf((*MockUserDatastore)(nil).AddLoginFailure), arg0, arg1, arg2, arg3)
+}
+
+// AddTeamMember mocks base method
+func (m *MockUserDatastore) AddTeamMember(arg0 context.Context, arg1, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddTeamMember", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddTeamMember indicates an expected call of AddTeamMember
+func (mr *MockUserDatastoreMockRecorder) AddTeamMember(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTeamMember", reflect.TypeOf((*MockUserDatastore)(nil).AddTeamMember), arg0, arg1, arg2, arg3)
+}
+
+// CountUsersWithRole mocks base method
+func (m *MockUserDatastore) CountUsersWithRole(arg0 context.Context, arg1 string, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsersWithRole", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsersWithRole indicates an expected call of CountUsersWithRole
+func (mr *MockUserDatastoreMockRecorder) CountUsersWithRole(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsersWithRole", reflect.TypeOf((*MockUserDatastore)(nil).CountUsersWithRole), arg0, arg1, arg2)
+}
+
+// DeleteAPIKey mocks base method
+func (m *MockUserDatastore) DeleteAPIKey(arg0 context.Context, arg1, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey
+func (mr *MockUserDatastoreMockRecorder) DeleteAPIKey(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockUserDatastore)(nil).DeleteAPIKey), arg0, arg1, arg2, arg3)
+}
+
+// DeleteAccount mocks base method
+func (m *MockUserDatastore) DeleteAccount(arg0 context.Context, arg1 string, arg2 time.Time, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount
+func (mr *MockUserDatastoreMockRecorder) DeleteAccount(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockUserDatastore)(nil).DeleteAccount), arg0, arg1, arg2, arg3)
+}
+
+// DeleteInvitation mocks base method
+func (m *MockUserDatastore) DeleteInvitation(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteInvitation", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteInvitation indicates an expected call of DeleteInvitation
+func (mr *MockUserDatastoreMockRecorder) DeleteInvitation(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteInvitation", reflect.TypeOf((*MockUserDatastore)(nil).DeleteInvitation), arg0, arg1, arg2)
+}
+
 // DeleteMeal mocks base method
-func (m *MockUserDatastore) DeleteMeal(arg0, arg1 string) error {
+func (m *MockUserDatastore) DeleteMeal(arg0 context.Context, arg1, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteMeal", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteMeal", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteMeal indicates an expected call of DeleteMeal
-func (mr *MockUserDatastoreMockRecorder) DeleteMeal(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) DeleteMeal(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMeal", reflect.TypeOf((*MockUserDatastore)(nil).DeleteMeal), arg0, arg1, arg2)
+}
+
+// DeleteOwnershipTransfer mocks base method
+func (m *MockUserDatastore) DeleteOwnershipTransfer(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOwnershipTransfer", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOwnershipTransfer indicates an expected call of DeleteOwnershipTransfer
+func (mr *MockUserDatastoreMockRecorder) DeleteOwnershipTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOwnershipTransfer", reflect.TypeOf((*MockUserDatastore)(nil).DeleteOwnershipTransfer), arg0, arg1)
+}
+
+// DeleteRole mocks base method
+func (m *MockUserDatastore) DeleteRole(arg0 context.Context, arg1 string, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRole", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRole indicates an expected call of DeleteRole
+func (mr *MockUserDatastoreMockRecorder) DeleteRole(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRole", reflect.TypeOf((*MockUserDatastore)(nil).DeleteRole), arg0, arg1, arg2)
+}
+
+// DeleteRoleInvitations mocks base method
+func (m *MockUserDatastore) DeleteRoleInvitations(arg0 context.Context, arg1 string, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRoleInvitations", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRoleInvitations indicates an expected call of DeleteRoleInvitations
+func (mr *MockUserDatastoreMockRecorder) DeleteRoleInvitations(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRoleInvitations", reflect.TypeOf((*MockUserDatastore)(nil).DeleteRoleInvitations), arg0, arg1, arg2)
+}
+
+// DeleteTOTP mocks base method
+func (m *MockUserDatastore) DeleteTOTP(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTP", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTP indicates an expected call of DeleteTOTP
+func (mr *MockUserDatastoreMockRecorder) DeleteTOTP(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMeal", reflect.TypeOf((*MockUserDatastore)(nil).DeleteMeal), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTP", reflect.TypeOf((*MockUserDatastore)(nil).DeleteTOTP), arg0, arg1, arg2)
 }
 
 // DeleteUser mocks base method
-func (m *MockUserDatastore) DeleteUser(arg0, arg1 string) error {
+func (m *MockUserDatastore) DeleteUser(arg0 context.Context, arg1, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteUser", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteUser indicates an expected call of DeleteUser
-func (mr *MockUserDatastoreMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) DeleteUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserDatastore)(nil).DeleteUser), arg0, arg1, arg2)
+}
+
+// DeleteUserAPIKeys mocks base method
+func (m *MockUserDatastore) DeleteUserAPIKeys(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserAPIKeys", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserAPIKeys indicates an expected call of DeleteUserAPIKeys
+func (mr *MockUserDatastoreMockRecorder) DeleteUserAPIKeys(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserAPIKeys", reflect.TypeOf((*MockUserDatastore)(nil).DeleteUserAPIKeys), arg0, arg1, arg2)
+}
+
+// Do mocks base method
+func (m *MockUserDatastore) Do(arg0 context.Context, arg1 func(context.Context, models.Repositories) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Do", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Do indicates an expected call of Do
+func (mr *MockUserDatastoreMockRecorder) Do(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockUserDatastore)(nil).Do), arg0, arg1)
+}
+
+// EnableTOTP mocks base method
+func (m *MockUserDatastore) EnableTOTP(arg0 context.Context, arg1, arg2 string, arg3 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP
+func (mr *MockUserDatastoreMockRecorder) EnableTOTP(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockUserDatastore)(nil).EnableTOTP), arg0, arg1, arg2, arg3)
+}
+
+// GetAPIKeyByHash mocks base method
+func (m *MockUserDatastore) GetAPIKeyByHash(arg0 context.Context, arg1 string) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", arg0, arg1)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash
+func (mr *MockUserDatastoreMockRecorder) GetAPIKeyByHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockUserDatastore)(nil).GetAPIKeyByHash), arg0, arg1)
+}
+
+// GetAPIKeys mocks base method
+func (m *MockUserDatastore) GetAPIKeys(arg0 context.Context, arg1, arg2 string) ([]models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeys", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys
+func (mr *MockUserDatastoreMockRecorder) GetAPIKeys(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockUserDatastore)(nil).GetAPIKeys), arg0, arg1, arg2)
+}
+
+// GetAccount mocks base method
+func (m *MockUserDatastore) GetAccount(arg0 context.Context, arg1 string) (*models.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", arg0, arg1)
+	ret0, _ := ret[0].(*models.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount
+func (mr *MockUserDatastoreMockRecorder) GetAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockUserDatastore)(nil).GetAccount), arg0, arg1)
+}
+
+// GetAccountByName mocks base method
+func (m *MockUserDatastore) GetAccountByName(arg0 context.Context, arg1 string) (*models.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountByName", arg0, arg1)
+	ret0, _ := ret[0].(*models.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountByName indicates an expected call of GetAccountByName
+func (mr *MockUserDatastoreMockRecorder) GetAccountByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountByName", reflect.TypeOf((*MockUserDatastore)(nil).GetAccountByName), arg0, arg1)
+}
+
+// GetDailyTotals mocks base method
+func (m *MockUserDatastore) GetDailyTotals(arg0 context.Context, arg1, arg2, arg3 string) ([]models.DailyTotal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDailyTotals", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]models.DailyTotal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDailyTotals indicates an expected call of GetDailyTotals
+func (mr *MockUserDatastoreMockRecorder) GetDailyTotals(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDailyTotals", reflect.TypeOf((*MockUserDatastore)(nil).GetDailyTotals), arg0, arg1, arg2, arg3)
+}
+
+// GetDeletedAccounts mocks base method
+func (m *MockUserDatastore) GetDeletedAccounts(arg0 context.Context, arg1 time.Time) ([]models.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletedAccounts", arg0, arg1)
+	ret0, _ := ret[0].([]models.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletedAccounts indicates an expected call of GetDeletedAccounts
+func (mr *MockUserDatastoreMockRecorder) GetDeletedAccounts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedAccounts", reflect.TypeOf((*MockUserDatastore)(nil).GetDeletedAccounts), arg0, arg1)
+}
+
+// GetInvitation mocks base method
+func (m *MockUserDatastore) GetInvitation(arg0 context.Context, arg1, arg2 string) (*models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitation", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitation indicates an expected call of GetInvitation
+func (mr *MockUserDatastoreMockRecorder) GetInvitation(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitation", reflect.TypeOf((*MockUserDatastore)(nil).GetInvitation), arg0, arg1, arg2)
+}
+
+// GetInvitations mocks base method
+func (m *MockUserDatastore) GetInvitations(arg0 context.Context, arg1 string) ([]models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitations", arg0, arg1)
+	ret0, _ := ret[0].([]models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitations indicates an expected call of GetInvitations
+func (mr *MockUserDatastoreMockRecorder) GetInvitations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitations", reflect.TypeOf((*MockUserDatastore)(nil).GetInvitations), arg0, arg1)
+}
+
+// GetLoginFailures mocks base method
+func (m *MockUserDatastore) GetLoginFailures(arg0 context.Context, arg1 string) (models.LoginFailures, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginFailures", arg0, arg1)
+	ret0, _ := ret[0].(models.LoginFailures)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginFailures indicates an expected call of GetLoginFailures
+func (mr *MockUserDatastoreMockRecorder) GetLoginFailures(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserDatastore)(nil).DeleteUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginFailures", reflect.TypeOf((*MockUserDatastore)(nil).GetLoginFailures), arg0, arg1)
 }
 
 // GetMeal mocks base method
-func (m *MockUserDatastore) GetMeal(arg0, arg1 string) (*models.Meal, error) {
+func (m *MockUserDatastore) GetMeal(arg0 context.Context, arg1, arg2 string) (*models.Meal, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMeal", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetMeal", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.Meal)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetMeal indicates an expected call of GetMeal
-func (mr *MockUserDatastoreMockRecorder) GetMeal(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) GetMeal(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMeal", reflect.TypeOf((*MockUserDatastore)(nil).GetMeal), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMeal", reflect.TypeOf((*MockUserDatastore)(nil).GetMeal), arg0, arg1, arg2)
 }
 
 // GetMeals mocks base method
-func (m *MockUserDatastore) GetMeals(arg0 string, arg1, arg2 int, arg3 string) (models.MealSlice, error) {
+func (m *MockUserDatastore) GetMeals(arg0 context.Context, arg1 string, arg2 models.ListParams) (models.MealSlice, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMeals", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "GetMeals", arg0, arg1, arg2)
 	ret0, _ := ret[0].(models.MealSlice)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetMeals indicates an expected call of GetMeals
-func (mr *MockUserDatastoreMockRecorder) GetMeals(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) GetMeals(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMeals", reflect.TypeOf((*MockUserDatastore)(nil).GetMeals), arg0, arg1, arg2)
+}
+
+// GetOwnershipTransfer mocks base method
+func (m *MockUserDatastore) GetOwnershipTransfer(arg0 context.Context, arg1 string) (*models.OwnershipTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwnershipTransfer", arg0, arg1)
+	ret0, _ := ret[0].(*models.OwnershipTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwnershipTransfer indicates an expected call of GetOwnershipTransfer
+func (mr *MockUserDatastoreMockRecorder) GetOwnershipTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnershipTransfer", reflect.TypeOf((*MockUserDatastore)(nil).GetOwnershipTransfer), arg0, arg1)
+}
+
+// GetRole mocks base method
+func (m *MockUserDatastore) GetRole(arg0 context.Context, arg1 string, arg2 int) (*models.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRole", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRole indicates an expected call of GetRole
+func (mr *MockUserDatastoreMockRecorder) GetRole(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMeals", reflect.TypeOf((*MockUserDatastore)(nil).GetMeals), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRole", reflect.TypeOf((*MockUserDatastore)(nil).GetRole), arg0, arg1, arg2)
+}
+
+// GetRoles mocks base method
+func (m *MockUserDatastore) GetRoles(arg0 context.Context, arg1 string) ([]models.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoles", arg0, arg1)
+	ret0, _ := ret[0].([]models.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoles indicates an expected call of GetRoles
+func (mr *MockUserDatastoreMockRecorder) GetRoles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoles", reflect.TypeOf((*MockUserDatastore)(nil).GetRoles), arg0, arg1)
 }
 
 // GetSettings mocks base method
-func (m *MockUserDatastore) GetSettings(arg0 string) (*models.Settings, error) {
+func (m *MockUserDatastore) GetSettings(arg0 context.Context, arg1 string) (*models.Settings, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSettings", arg0)
+	ret := m.ctrl.Call(m, "GetSettings", arg0, arg1)
 	ret0, _ := ret[0].(*models.Settings)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetSettings indicates an expected call of GetSettings
-func (mr *MockUserDatastoreMockRecorder) GetSettings(arg0 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) GetSettings(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSettings", reflect.TypeOf((*MockUserDatastore)(nil).GetSettings), arg0, arg1)
+}
+
+// GetTOTP mocks base method
+func (m *MockUserDatastore) GetTOTP(arg0 context.Context, arg1, arg2 string) (*models.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP
+func (mr *MockUserDatastoreMockRecorder) GetTOTP(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockUserDatastore)(nil).GetTOTP), arg0, arg1, arg2)
+}
+
+// GetTeamManagers mocks base method
+func (m *MockUserDatastore) GetTeamManagers(arg0 context.Context, arg1, arg2 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTeamManagers", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTeamManagers indicates an expected call of GetTeamManagers
+func (mr *MockUserDatastoreMockRecorder) GetTeamManagers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTeamManagers", reflect.TypeOf((*MockUserDatastore)(nil).GetTeamManagers), arg0, arg1, arg2)
+}
+
+// GetTeamMembers mocks base method
+func (m *MockUserDatastore) GetTeamMembers(arg0 context.Context, arg1, arg2 string) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTeamMembers", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTeamMembers indicates an expected call of GetTeamMembers
+func (mr *MockUserDatastoreMockRecorder) GetTeamMembers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTeamMembers", reflect.TypeOf((*MockUserDatastore)(nil).GetTeamMembers), arg0, arg1, arg2)
+}
+
+// GetTwoFactorRoles mocks base method
+func (m *MockUserDatastore) GetTwoFactorRoles(arg0 context.Context, arg1 string) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTwoFactorRoles", arg0, arg1)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTwoFactorRoles indicates an expected call of GetTwoFactorRoles
+func (mr *MockUserDatastoreMockRecorder) GetTwoFactorRoles(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSettings", reflect.TypeOf((*MockUserDatastore)(nil).GetSettings), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTwoFactorRoles", reflect.TypeOf((*MockUserDatastore)(nil).GetTwoFactorRoles), arg0, arg1)
 }
 
 // GetUser mocks base method
-func (m *MockUserDatastore) GetUser(arg0, arg1 string) (*models.User, error) {
+func (m *MockUserDatastore) GetUser(arg0 context.Context, arg1, arg2 string) (*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetUser", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUser indicates an expected call of GetUser
-func (mr *MockUserDatastoreMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) GetUser(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserDatastore)(nil).GetUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserDatastore)(nil).GetUser), arg0, arg1, arg2)
 }
 
 // GetUserById mocks base method
-func (m *MockUserDatastore) GetUserById(arg0, arg1 string) (*models.User, error) {
+func (m *MockUserDatastore) GetUserById(arg0 context.Context, arg1, arg2 string) (*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserById", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetUserById", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserById indicates an expected call of GetUserById
-func (mr *MockUserDatastoreMockRecorder) GetUserById(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) GetUserById(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserById", reflect.TypeOf((*MockUserDatastore)(nil).GetUserById), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserById", reflect.TypeOf((*MockUserDatastore)(nil).GetUserById), arg0, arg1, arg2)
 }
 
 // GetUserPassword mocks base method
-func (m *MockUserDatastore) GetUserPassword(arg0, arg1 string) (*string, error) {
+func (m *MockUserDatastore) GetUserPassword(arg0 context.Context, arg1, arg2 string) (*models.Password, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserPassword", arg0, arg1)
-	ret0, _ := ret[0].(*string)
+	ret := m.ctrl.Call(m, "GetUserPassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Password)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserPassword indicates an expected call of GetUserPassword
-func (mr *MockUserDatastoreMockRecorder) GetUserPassword(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) GetUserPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserPassword", reflect.TypeOf((*MockUserDatastore)(nil).GetUserPassword), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserPassword", reflect.TypeOf((*MockUserDatastore)(nil).GetUserPassword), arg0, arg1, arg2)
 }
 
 // GetUsers mocks base method
-func (m *MockUserDatastore) GetUsers(arg0 string, arg1, arg2 int, arg3 string) (models.UserSlice, error) {
+func (m *MockUserDatastore) GetUsers(arg0 context.Context, arg1 string, arg2 models.ListParams) (models.UserSlice, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUsers", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "GetUsers", arg0, arg1, arg2)
 	ret0, _ := ret[0].(models.UserSlice)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUsers indicates an expected call of GetUsers
-func (mr *MockUserDatastoreMockRecorder) GetUsers(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) GetUsers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockUserDatastore)(nil).GetUsers), arg0, arg1, arg2)
+}
+
+// IsAccessTokenRevoked mocks base method
+func (m *MockUserDatastore) IsAccessTokenRevoked(arg0 context.Context, arg1, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAccessTokenRevoked", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsAccessTokenRevoked indicates an expected call of IsAccessTokenRevoked
+func (mr *MockUserDatastoreMockRecorder) IsAccessTokenRevoked(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAccessTokenRevoked", reflect.TypeOf((*MockUserDatastore)(nil).IsAccessTokenRevoked), arg0, arg1, arg2)
+}
+
+// PurgeAccount mocks base method
+func (m *MockUserDatastore) PurgeAccount(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeAccount", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeAccount indicates an expected call of PurgeAccount
+func (mr *MockUserDatastoreMockRecorder) PurgeAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeAccount", reflect.TypeOf((*MockUserDatastore)(nil).PurgeAccount), arg0, arg1)
+}
+
+// RemoveTeamMember mocks base method
+func (m *MockUserDatastore) RemoveTeamMember(arg0 context.Context, arg1, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveTeamMember", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveTeamMember indicates an expected call of RemoveTeamMember
+func (mr *MockUserDatastoreMockRecorder) RemoveTeamMember(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTeamMember", reflect.TypeOf((*MockUserDatastore)(nil).RemoveTeamMember), arg0, arg1, arg2, arg3)
+}
+
+// ResetLoginFailures mocks base method
+func (m *MockUserDatastore) ResetLoginFailures(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginFailures", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetLoginFailures indicates an expected call of ResetLoginFailures
+func (mr *MockUserDatastoreMockRecorder) ResetLoginFailures(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginFailures", reflect.TypeOf((*MockUserDatastore)(nil).ResetLoginFailures), arg0, arg1)
+}
+
+// RestoreAccount mocks base method
+func (m *MockUserDatastore) RestoreAccount(arg0 context.Context, arg1 string, arg2 time.Time) (*models.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreAccount", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreAccount indicates an expected call of RestoreAccount
+func (mr *MockUserDatastoreMockRecorder) RestoreAccount(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreAccount", reflect.TypeOf((*MockUserDatastore)(nil).RestoreAccount), arg0, arg1, arg2)
+}
+
+// RevokeAccessToken mocks base method
+func (m *MockUserDatastore) RevokeAccessToken(arg0 context.Context, arg1 string, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAccessToken", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAccessToken indicates an expected call of RevokeAccessToken
+func (mr *MockUserDatastoreMockRecorder) RevokeAccessToken(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccessToken", reflect.TypeOf((*MockUserDatastore)(nil).RevokeAccessToken), arg0, arg1, arg2)
+}
+
+// RevokeRefreshTokenFamily mocks base method
+func (m *MockUserDatastore) RevokeRefreshTokenFamily(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshTokenFamily", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshTokenFamily indicates an expected call of RevokeRefreshTokenFamily
+func (mr *MockUserDatastoreMockRecorder) RevokeRefreshTokenFamily(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokenFamily", reflect.TypeOf((*MockUserDatastore)(nil).RevokeRefreshTokenFamily), arg0, arg1)
+}
+
+// RevokeUserTokenFamilies mocks base method
+func (m *MockUserDatastore) RevokeUserTokenFamilies(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokenFamilies", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokenFamilies indicates an expected call of RevokeUserTokenFamilies
+func (mr *MockUserDatastoreMockRecorder) RevokeUserTokenFamilies(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockUserDatastore)(nil).GetUsers), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokenFamilies", reflect.TypeOf((*MockUserDatastore)(nil).RevokeUserTokenFamilies), arg0, arg1, arg2)
+}
+
+// SaveAPIKey mocks base method
+func (m *MockUserDatastore) SaveAPIKey(arg0 context.Context, arg1 models.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAPIKey indicates an expected call of SaveAPIKey
+func (mr *MockUserDatastoreMockRecorder) SaveAPIKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAPIKey", reflect.TypeOf((*MockUserDatastore)(nil).SaveAPIKey), arg0, arg1)
+}
+
+// SaveInvitation mocks base method
+func (m *MockUserDatastore) SaveInvitation(arg0 context.Context, arg1 models.Invitation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveInvitation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveInvitation indicates an expected call of SaveInvitation
+func (mr *MockUserDatastoreMockRecorder) SaveInvitation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveInvitation", reflect.TypeOf((*MockUserDatastore)(nil).SaveInvitation), arg0, arg1)
 }
 
 // SaveMeal mocks base method
-func (m *MockUserDatastore) SaveMeal(arg0 string, arg1 models.Meal) (*models.Meal, error) {
+func (m *MockUserDatastore) SaveMeal(arg0 context.Context, arg1 string, arg2 models.Meal) (*models.Meal, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveMeal", arg0, arg1)
+	ret := m.ctrl.Call(m, "SaveMeal", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.Meal)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveMeal indicates an expected call of SaveMeal
-func (mr *MockUserDatastoreMockRecorder) SaveMeal(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) SaveMeal(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMeal", reflect.TypeOf((*MockUserDatastore)(nil).SaveMeal), arg0, arg1, arg2)
+}
+
+// SaveOwnershipTransfer mocks base method
+func (m *MockUserDatastore) SaveOwnershipTransfer(arg0 context.Context, arg1 models.OwnershipTransfer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveOwnershipTransfer", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveOwnershipTransfer indicates an expected call of SaveOwnershipTransfer
+func (mr *MockUserDatastoreMockRecorder) SaveOwnershipTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOwnershipTransfer", reflect.TypeOf((*MockUserDatastore)(nil).SaveOwnershipTransfer), arg0, arg1)
+}
+
+// SavePasswordResetToken mocks base method
+func (m *MockUserDatastore) SavePasswordResetToken(arg0 context.Context, arg1 models.PasswordResetToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePasswordResetToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePasswordResetToken indicates an expected call of SavePasswordResetToken
+func (mr *MockUserDatastoreMockRecorder) SavePasswordResetToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePasswordResetToken", reflect.TypeOf((*MockUserDatastore)(nil).SavePasswordResetToken), arg0, arg1)
+}
+
+// SaveRefreshToken mocks base method
+func (m *MockUserDatastore) SaveRefreshToken(arg0 context.Context, arg1 models.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRefreshToken indicates an expected call of SaveRefreshToken
+func (mr *MockUserDatastoreMockRecorder) SaveRefreshToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRefreshToken", reflect.TypeOf((*MockUserDatastore)(nil).SaveRefreshToken), arg0, arg1)
+}
+
+// SaveRole mocks base method
+func (m *MockUserDatastore) SaveRole(arg0 context.Context, arg1 models.Role) (*models.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRole", arg0, arg1)
+	ret0, _ := ret[0].(*models.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveRole indicates an expected call of SaveRole
+func (mr *MockUserDatastoreMockRecorder) SaveRole(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMeal", reflect.TypeOf((*MockUserDatastore)(nil).SaveMeal), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRole", reflect.TypeOf((*MockUserDatastore)(nil).SaveRole), arg0, arg1)
 }
 
 // SaveRootUser mocks base method
-func (m *MockUserDatastore) SaveRootUser(arg0, arg1 string) (*models.User, error) {
+func (m *MockUserDatastore) SaveRootUser(arg0 context.Context, arg1, arg2 string, arg3 models.Password) (*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveRootUser", arg0, arg1)
+	ret := m.ctrl.Call(m, "SaveRootUser", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(*models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveRootUser indicates an expected call of SaveRootUser
-func (mr *MockUserDatastoreMockRecorder) SaveRootUser(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) SaveRootUser(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRootUser", reflect.TypeOf((*MockUserDatastore)(nil).SaveRootUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRootUser", reflect.TypeOf((*MockUserDatastore)(nil).SaveRootUser), arg0, arg1, arg2, arg3)
+}
+
+// SaveTOTP mocks base method
+func (m *MockUserDatastore) SaveTOTP(arg0 context.Context, arg1 models.TOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTP", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTP indicates an expected call of SaveTOTP
+func (mr *MockUserDatastoreMockRecorder) SaveTOTP(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTP", reflect.TypeOf((*MockUserDatastore)(nil).SaveTOTP), arg0, arg1)
 }
 
 // SaveUser mocks base method
-func (m *MockUserDatastore) SaveUser(arg0, arg1, arg2 string, arg3 int) (*models.User, error) {
+func (m *MockUserDatastore) SaveUser(arg0 context.Context, arg1, arg2 string, arg3 models.Password, arg4 int) (*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveUser", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "SaveUser", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(*models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveUser indicates an expected call of SaveUser
-func (mr *MockUserDatastoreMockRecorder) SaveUser(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) SaveUser(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUser", reflect.TypeOf((*MockUserDatastore)(nil).SaveUser), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUser", reflect.TypeOf((*MockUserDatastore)(nil).SaveUser), arg0, arg1, arg2, arg3, arg4)
+}
+
+// SetTwoFactorRoles mocks base method
+func (m *MockUserDatastore) SetTwoFactorRoles(arg0 context.Context, arg1 string, arg2 []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTwoFactorRoles", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTwoFactorRoles indicates an expected call of SetTwoFactorRoles
+func (mr *MockUserDatastoreMockRecorder) SetTwoFactorRoles(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTwoFactorRoles", reflect.TypeOf((*MockUserDatastore)(nil).SetTwoFactorRoles), arg0, arg1, arg2)
+}
+
+// UpdateDailyTotal mocks base method
+func (m *MockUserDatastore) UpdateDailyTotal(arg0 context.Context, arg1, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDailyTotal", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateDailyTotal indicates an expected call of UpdateDailyTotal
+func (mr *MockUserDatastoreMockRecorder) UpdateDailyTotal(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDailyTotal", reflect.TypeOf((*MockUserDatastore)(nil).UpdateDailyTotal), arg0, arg1, arg2)
+}
+
+// UpdateInvitation mocks base method
+func (m *MockUserDatastore) UpdateInvitation(arg0 context.Context, arg1 models.Invitation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateInvitation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateInvitation indicates an expected call of UpdateInvitation
+func (mr *MockUserDatastoreMockRecorder) UpdateInvitation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInvitation", reflect.TypeOf((*MockUserDatastore)(nil).UpdateInvitation), arg0, arg1)
 }
 
 // UpdateMeal mocks base method
-func (m *MockUserDatastore) UpdateMeal(arg0 string, arg1 models.Meal) (*models.Meal, error) {
+func (m *MockUserDatastore) UpdateMeal(arg0 context.Context, arg1 string, arg2 models.Meal) (*models.Meal, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateMeal", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateMeal", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.Meal)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateMeal indicates an expected call of UpdateMeal
-func (mr *MockUserDatastoreMockRecorder) UpdateMeal(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) UpdateMeal(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMeal", reflect.TypeOf((*MockUserDatastore)(nil).UpdateMeal), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMeal", reflect.TypeOf((*MockUserDatastore)(nil).UpdateMeal), arg0, arg1, arg2)
+}
+
+// UpdateRole mocks base method
+func (m *MockUserDatastore) UpdateRole(arg0 context.Context, arg1 models.Role) (*models.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", arg0, arg1)
+	ret0, _ := ret[0].(*models.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateRole indicates an expected call of UpdateRole
+func (mr *MockUserDatastoreMockRecorder) UpdateRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockUserDatastore)(nil).UpdateRole), arg0, arg1)
 }
 
 // UpdateSettings mocks base method
-func (m *MockUserDatastore) UpdateSettings(arg0 string, arg1 models.Settings) (*models.Settings, error) {
+func (m *MockUserDatastore) UpdateSettings(arg0 context.Context, arg1 string, arg2 models.Settings) (*models.Settings, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateSettings", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateSettings", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.Settings)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateSettings indicates an expected call of UpdateSettings
-func (mr *MockUserDatastoreMockRecorder) UpdateSettings(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) UpdateSettings(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSettings", reflect.TypeOf((*MockUserDatastore)(nil).UpdateSettings), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSettings", reflect.TypeOf((*MockUserDatastore)(nil).UpdateSettings), arg0, arg1, arg2)
 }
 
 // UpdateUser mocks base method
-func (m *MockUserDatastore) UpdateUser(arg0 models.User) (*models.User, error) {
+func (m *MockUserDatastore) UpdateUser(arg0 context.Context, arg1 models.User) (*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", arg0)
+	ret := m.ctrl.Call(m, "UpdateUser", arg0, arg1)
 	ret0, _ := ret[0].(*models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateUser indicates an expected call of UpdateUser
-func (mr *MockUserDatastoreMockRecorder) UpdateUser(arg0 interface{}) *gomock.Call {
+func (mr *MockUserDatastoreMockRecorder) UpdateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserDatastore)(nil).UpdateUser), arg0, arg1)
+}
+
+// UpdateUserPassword mocks base method
+func (m *MockUserDatastore) UpdateUserPassword(arg0 context.Context, arg1, arg2 string, arg3 models.Password) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword
+func (mr *MockUserDatastoreMockRecorder) UpdateUserPassword(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockUserDatastore)(nil).UpdateUserPassword), arg0, arg1, arg2, arg3)
+}
+
+// UseInvitation mocks base method
+func (m *MockUserDatastore) UseInvitation(arg0 context.Context, arg1, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseInvitation", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseInvitation indicates an expected call of UseInvitation
+func (mr *MockUserDatastoreMockRecorder) UseInvitation(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseInvitation", reflect.TypeOf((*MockUserDatastore)(nil).UseInvitation), arg0, arg1, arg2, arg3)
+}
+
+// UseOwnershipTransfer mocks base method
+func (m *MockUserDatastore) UseOwnershipTransfer(arg0 context.Context, arg1 models.OwnershipTransfer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseOwnershipTransfer", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseOwnershipTransfer indicates an expected call of UseOwnershipTransfer
+func (mr *MockUserDatastoreMockRecorder) UseOwnershipTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseOwnershipTransfer", reflect.TypeOf((*MockUserDatastore)(nil).UseOwnershipTransfer), arg0, arg1)
+}
+
+// UsePasswordResetToken mocks base method
+func (m *MockUserDatastore) UsePasswordResetToken(arg0 context.Context, arg1 string) (*models.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsePasswordResetToken", arg0, arg1)
+	ret0, _ := ret[0].(*models.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UsePasswordResetToken indicates an expected call of UsePasswordResetToken
+func (mr *MockUserDatastoreMockRecorder) UsePasswordResetToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsePasswordResetToken", reflect.TypeOf((*MockUserDatastore)(nil).UsePasswordResetToken), arg0, arg1)
+}
+
+// UseRecoveryCode mocks base method
+func (m *MockUserDatastore) UseRecoveryCode(arg0 context.Context, arg1, arg2, arg3 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode
+func (mr *MockUserDatastoreMockRecorder) UseRecoveryCode(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockUserDatastore)(nil).UseRecoveryCode), arg0, arg1, arg2, arg3)
+}
+
+// UseRefreshToken mocks base method
+func (m *MockUserDatastore) UseRefreshToken(arg0 context.Context, arg1 string) (*models.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(*models.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRefreshToken indicates an expected call of UseRefreshToken
+func (mr *MockUserDatastoreMockRecorder) UseRefreshToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRefreshToken", reflect.TypeOf((*MockUserDatastore)(nil).UseRefreshToken), arg0, arg1)
+}
+
+// UseTOTPStep mocks base method
+func (m *MockUserDatastore) UseTOTPStep(arg0 context.Context, arg1, arg2 string, arg3 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep
+func (mr *MockUserDatastoreMockRecorder) UseTOTPStep(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserDatastore)(nil).UpdateUser), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockUserDatastore)(nil).UseTOTPStep), arg0, arg1, arg2, arg3)
 }
@@ -0,0 +1,62 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"github.com/jmoiron/sqlx"
+	"io"
+	"strings"
+)
+
+const (
+	sqliteScheme     = "sqlite://"
+	mysqlScheme      = "mysql://"
+	postgresScheme   = "postgres://"
+	postgresqlScheme = "postgresql://"
+)
+
+// Store is models.UserDatastore holding resources which have to be released with Close
+type Store interface {
+	models.UserDatastore
+	io.Closer
+}
+
+// Open picks the datastore backend based on the dbSource scheme: empty source opens in-memory store,
+// sqlite://<path> opens SQLite database file, postgres:// and postgresql:// URLs connect to PostgreSQL,
+// mysql://<dsn> or dsn without scheme connects to MySQL.
+func Open(dbSource string) (Store, error) {
+	switch {
+	case dbSource == "":
+		return NewMemoryStore(), nil
+	case strings.HasPrefix(dbSource, sqliteScheme):
+		return NewSQLiteStore(strings.TrimPrefix(dbSource, sqliteScheme))
+	case strings.HasPrefix(dbSource, postgresScheme) || strings.HasPrefix(dbSource, postgresqlScheme):
+		return NewPostgresStore(dbSource)
+	default:
+		return NewMySQLStore(strings.TrimPrefix(dbSource, mysqlScheme))
+	}
+}
+
+// OpenMigrator connects to the database selected the same way as in Open without applying any migration
+func OpenMigrator(dbSource string) (*Migrator, error) {
+	var db *sqlx.DB
+	var dialect sqlDialect
+	var err error
+	switch {
+	case dbSource == "":
+		return nil, ErrNoSchema
+	case strings.HasPrefix(dbSource, sqliteScheme):
+		db, err = openSQLite(strings.TrimPrefix(dbSource, sqliteScheme))
+		dialect = sqliteDialect{}
+	case strings.HasPrefix(dbSource, postgresScheme) || strings.HasPrefix(dbSource, postgresqlScheme):
+		db, err = openDB("postgres", dbSource)
+		dialect = postgresDialect{}
+	default:
+		db, err = openDB("mysql", strings.TrimPrefix(dbSource, mysqlScheme))
+		dialect = mysqlDialect{}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newMigrator(db, dialect), nil
+}
@@ -0,0 +1,49 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+)
+
+func (d *MemoryStore) SaveOwnershipTransfer(ctx context.Context, transfer models.OwnershipTransfer) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ownershipTransfers[transfer.AccountID] = transfer
+	return nil
+}
+
+func (d *MemoryStore) GetOwnershipTransfer(ctx context.Context, accountID string) (*models.OwnershipTransfer, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	transfer, ok := d.ownershipTransfers[accountID]
+	if !ok {
+		return nil, models.ErrOwnershipTransferNotFound
+	}
+	return &transfer, nil
+}
+
+func (d *MemoryStore) DeleteOwnershipTransfer(ctx context.Context, accountID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.ownershipTransfers[accountID]; !ok {
+		return models.ErrOwnershipTransferNotFound
+	}
+	delete(d.ownershipTransfers, accountID)
+	return nil
+}
+
+func (d *MemoryStore) UseOwnershipTransfer(ctx context.Context, transfer models.OwnershipTransfer) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending, ok := d.ownershipTransfers[transfer.AccountID]
+	if !ok || pending.FromUserID != transfer.FromUserID || pending.ToUserID != transfer.ToUserID ||
+		pending.KeepOwnership != transfer.KeepOwnership || pending.ExpiresAt.Unix() != transfer.ExpiresAt.Unix() {
+		return models.ErrOwnershipTransferNotFound
+	}
+	delete(d.ownershipTransfers, transfer.AccountID)
+	return nil
+}
@@ -0,0 +1,69 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+func (d *sqlStore) SaveOwnershipTransfer(ctx context.Context, transfer models.OwnershipTransfer) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`DELETE FROM ownership_transfers WHERE account_id=?`)
+		_, err := tx.ExecContext(ctx, query, transfer.AccountID)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`INSERT INTO ownership_transfers (account_id, from_user_id, to_user_id, keep_ownership, expires_at)
+								VALUES (?, ?, ?, ?, ?)`)
+		_, err = tx.ExecContext(ctx, query, transfer.AccountID, transfer.FromUserID, transfer.ToUserID,
+			transfer.KeepOwnership, transfer.ExpiresAt.Unix())
+		return err
+	})
+}
+
+func (d *sqlStore) GetOwnershipTransfer(ctx context.Context, accountID string) (*models.OwnershipTransfer, error) {
+	query := d.ext().Rebind(`SELECT from_user_id, to_user_id, keep_ownership, expires_at
+								FROM ownership_transfers WHERE account_id=?`)
+	transfer := models.OwnershipTransfer{AccountID: accountID}
+	var expiresAt int64
+	err := d.ext().QueryRowxContext(ctx, query, accountID).
+		Scan(&transfer.FromUserID, &transfer.ToUserID, &transfer.KeepOwnership, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrOwnershipTransferNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	transfer.ExpiresAt = time.Unix(expiresAt, 0)
+	return &transfer, nil
+}
+
+func (d *sqlStore) DeleteOwnershipTransfer(ctx context.Context, accountID string) error {
+	query := d.ext().Rebind(`DELETE FROM ownership_transfers WHERE account_id=?`)
+	return d.deleteOwnershipTransfer(ctx, query, accountID)
+}
+
+func (d *sqlStore) UseOwnershipTransfer(ctx context.Context, transfer models.OwnershipTransfer) error {
+	query := d.ext().Rebind(`DELETE FROM ownership_transfers
+								WHERE account_id=? AND from_user_id=? AND to_user_id=? AND keep_ownership=? AND expires_at=?`)
+	return d.deleteOwnershipTransfer(ctx, query, transfer.AccountID, transfer.FromUserID, transfer.ToUserID,
+		transfer.KeepOwnership, transfer.ExpiresAt.Unix())
+}
+
+// deleteOwnershipTransfer runs the delete and returns ErrOwnershipTransferNotFound when no transfer was deleted
+func (d *sqlStore) deleteOwnershipTransfer(ctx context.Context, query string, args ...interface{}) error {
+	res, err := d.ext().ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrOwnershipTransferNotFound
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+)
+
+func (d *MemoryStore) SavePasswordResetToken(ctx context.Context, token models.PasswordResetToken) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for hash, t := range d.passwordResetTokens {
+		if t.AccountID == token.AccountID && t.UserID == token.UserID {
+			delete(d.passwordResetTokens, hash)
+		}
+	}
+	d.passwordResetTokens[token.TokenHash] = token
+	return nil
+}
+
+func (d *MemoryStore) UsePasswordResetToken(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	token, ok := d.passwordResetTokens[tokenHash]
+	if !ok {
+		return nil, models.ErrPasswordResetTokenNotFound
+	}
+	delete(d.passwordResetTokens, tokenHash)
+	return &token, nil
+}
@@ -0,0 +1,60 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+func (d *sqlStore) SavePasswordResetToken(ctx context.Context, token models.PasswordResetToken) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		// expired tokens of other users are removed on the way
+		query := tx.Rebind(`DELETE FROM password_reset_tokens WHERE (account_id=? AND user_id=?) OR expires_at < ?`)
+		_, err := tx.ExecContext(ctx, query, token.AccountID, token.UserID, time.Now().Unix())
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`INSERT INTO password_reset_tokens (token_hash, user_id, account_id, expires_at)
+								VALUES (?, ?, ?, ?)`)
+		_, err = tx.ExecContext(ctx, query, token.TokenHash, token.UserID, token.AccountID, token.ExpiresAt.Unix())
+		return err
+	})
+}
+
+func (d *sqlStore) UsePasswordResetToken(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := d.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`SELECT token_hash, user_id, account_id, expires_at FROM password_reset_tokens WHERE token_hash=?`)
+		var expiresAt int64
+		err := tx.QueryRowxContext(ctx, query, tokenHash).Scan(&token.TokenHash, &token.UserID, &token.AccountID, &expiresAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrPasswordResetTokenNotFound
+			}
+			return err
+		}
+		token.ExpiresAt = time.Unix(expiresAt, 0)
+
+		// only one of concurrent calls deletes the token
+		query = tx.Rebind(`DELETE FROM password_reset_tokens WHERE token_hash=?`)
+		res, err := tx.ExecContext(ctx, query, tokenHash)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrPasswordResetTokenNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,59 @@
+package user_datastore
+
+import (
+	"github.com/lib/pq"
+)
+
+// PostgresStore implements models.UserDatastore
+type PostgresStore struct {
+	sqlStore
+}
+
+type postgresDialect struct{}
+
+const (
+	pgUniqueViolation   = "23505"
+	pgSyntaxError       = "42601"
+	pgUndefinedColumn   = "42703"
+	pgUndefinedFunction = "42883"
+)
+
+// NewPostgresStore connects to PostgreSQL, dbSourceName is a lib/pq connection string or URL
+func NewPostgresStore(dbSourceName string) (*PostgresStore, error) {
+	db, err := openDB("postgres", dbSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newSQLStore(db, postgresDialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresStore{store}, nil
+}
+
+func (postgresDialect) isDuplicateKeyError(err error) bool {
+	switch pErr := err.(type) {
+	case *pq.Error:
+		if pErr.Code == pgUniqueViolation {
+			return true
+		}
+	}
+	return false
+}
+
+func (postgresDialect) isInvalidQuery(err error) bool {
+	switch pErr := err.(type) {
+	case *pq.Error:
+		// undefined function is returned for filters comparing incompatible types, e.g. boolean with integer
+		if pErr.Code == pgSyntaxError || pErr.Code == pgUndefinedColumn || pErr.Code == pgUndefinedFunction {
+			return true
+		}
+	}
+	return false
+}
+
+func (postgresDialect) forUpdate() string {
+	return " FOR UPDATE"
+}
@@ -0,0 +1,81 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"sort"
+)
+
+func (d *MemoryStore) SaveRole(ctx context.Context, role models.Role) (*models.Role, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	role.ID = models.CustomRoleMinID
+	for id, r := range d.roles[role.AccountID] {
+		if r.Name == role.Name {
+			return nil, models.ErrRoleAlreadyExists
+		}
+		if id >= role.ID {
+			role.ID = id + 1
+		}
+	}
+	role.Permissions = append([]string{}, role.Permissions...)
+	if d.roles[role.AccountID] == nil {
+		d.roles[role.AccountID] = make(map[int]models.Role)
+	}
+	d.roles[role.AccountID][role.ID] = role
+	return &role, nil
+}
+
+func (d *MemoryStore) GetRoles(ctx context.Context, accountID string) ([]models.Role, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	roles := make([]models.Role, 0, len(d.roles[accountID]))
+	for _, role := range d.roles[accountID] {
+		role.Permissions = append([]string{}, role.Permissions...)
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].ID < roles[j].ID })
+	return roles, nil
+}
+
+func (d *MemoryStore) GetRole(ctx context.Context, accountID string, roleID int) (*models.Role, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	role, ok := d.roles[accountID][roleID]
+	if !ok {
+		return nil, models.ErrRoleNotFound
+	}
+	role.Permissions = append([]string{}, role.Permissions...)
+	return &role, nil
+}
+
+func (d *MemoryStore) UpdateRole(ctx context.Context, role models.Role) (*models.Role, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.roles[role.AccountID][role.ID]; !ok {
+		return nil, models.ErrRoleNotFound
+	}
+	for id, r := range d.roles[role.AccountID] {
+		if id != role.ID && r.Name == role.Name {
+			return nil, models.ErrRoleAlreadyExists
+		}
+	}
+	role.Permissions = append([]string{}, role.Permissions...)
+	d.roles[role.AccountID][role.ID] = role
+	return &role, nil
+}
+
+func (d *MemoryStore) DeleteRole(ctx context.Context, accountID string, roleID int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.roles[accountID][roleID]; !ok {
+		return models.ErrRoleNotFound
+	}
+	delete(d.roles[accountID], roleID)
+	return nil
+}
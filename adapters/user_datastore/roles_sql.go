@@ -0,0 +1,113 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+type roleRow struct {
+	AccountID   string `db:"account_id"`
+	ID          int    `db:"id"`
+	Name        string `db:"name"`
+	Permissions string `db:"permissions"`
+}
+
+func (r roleRow) role() models.Role {
+	return models.Role{ID: r.ID, AccountID: r.AccountID, Name: r.Name, Permissions: splitList(r.Permissions)}
+}
+
+func (d *sqlStore) SaveRole(ctx context.Context, role models.Role) (*models.Role, error) {
+	role.Permissions = append([]string{}, role.Permissions...)
+	err := d.inTx(ctx, func(tx *sqlx.Tx) error {
+		var maxID sql.NullInt64
+		query := tx.Rebind(`SELECT MAX(id) FROM roles WHERE account_id=?`)
+		if err := tx.QueryRowxContext(ctx, query, role.AccountID).Scan(&maxID); err != nil {
+			return err
+		}
+		role.ID = models.CustomRoleMinID
+		if maxID.Valid && int(maxID.Int64) >= role.ID {
+			role.ID = int(maxID.Int64) + 1
+		}
+
+		query = tx.Rebind(`INSERT INTO roles (account_id, id, name, permissions) VALUES (?, ?, ?, ?)`)
+		_, err := tx.ExecContext(ctx, query, role.AccountID, role.ID, role.Name, joinList(role.Permissions))
+		if err != nil && d.dialect.isDuplicateKeyError(err) {
+			return models.ErrRoleAlreadyExists
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (d *sqlStore) GetRoles(ctx context.Context, accountID string) ([]models.Role, error) {
+	query := d.ext().Rebind(`SELECT account_id, id, name, permissions FROM roles WHERE account_id=? ORDER BY id`)
+	var rows []roleRow
+	err := sqlx.SelectContext(ctx, d.ext(), &rows, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]models.Role, 0, len(rows))
+	for _, row := range rows {
+		roles = append(roles, row.role())
+	}
+	return roles, nil
+}
+
+func (d *sqlStore) GetRole(ctx context.Context, accountID string, roleID int) (*models.Role, error) {
+	query := d.ext().Rebind(`SELECT account_id, id, name, permissions FROM roles WHERE account_id=? AND id=?`)
+	var row roleRow
+	err := d.ext().QueryRowxContext(ctx, query, accountID, roleID).StructScan(&row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrRoleNotFound
+		}
+		return nil, err
+	}
+	role := row.role()
+	return &role, nil
+}
+
+func (d *sqlStore) UpdateRole(ctx context.Context, role models.Role) (*models.Role, error) {
+	query := d.ext().Rebind(`UPDATE roles SET name=?, permissions=? WHERE account_id=? AND id=?`)
+	res, err := d.ext().ExecContext(ctx, query, role.Name, joinList(role.Permissions), role.AccountID, role.ID)
+	if err != nil {
+		if d.dialect.isDuplicateKeyError(err) {
+			return nil, models.ErrRoleAlreadyExists
+		}
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	// MySQL does not count rows which were not changed
+	if n == 0 {
+		if _, err := d.GetRole(ctx, role.AccountID, role.ID); err != nil {
+			return nil, err
+		}
+	}
+	role.Permissions = append([]string{}, role.Permissions...)
+	return &role, nil
+}
+
+func (d *sqlStore) DeleteRole(ctx context.Context, accountID string, roleID int) error {
+	query := d.ext().Rebind(`DELETE FROM roles WHERE account_id=? AND id=?`)
+	res, err := d.ext().ExecContext(ctx, query, accountID, roleID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrRoleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,735 @@
+package user_datastore
+
+import (
+	"calories-counter/filter"
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// sqlStore implements models.UserDatastore on top of database/sql,
+// differences between databases are handled by sqlDialect.
+// When tx is set all queries run in that transaction, see Do.
+type sqlStore struct {
+	db      *sqlx.DB
+	tx      *sqlx.Tx
+	dialect sqlDialect
+}
+
+type sqlDialect interface {
+	isDuplicateKeyError(err error) bool
+	isInvalidQuery(err error) bool
+	// forUpdate is appended to SELECT to lock the selected rows until the transaction ends
+	forUpdate() string
+	migrations() []migration
+}
+
+func openDB(driver, dbSourceName string) (*sqlx.DB, error) {
+	log.Info("connecting to DB")
+	sqldb, err := sql.Open(driver, dbSourceName)
+	if err != nil {
+		log.WithError(err).Error("couldn't open db")
+		return nil, err
+	}
+
+	db := sqlx.NewDb(sqldb, driver)
+
+	err = db.Ping()
+	if err != nil {
+		log.WithError(err).Error("couldn't ping db")
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// newSQLStore brings database schema up to date, db is closed when it fails
+func newSQLStore(db *sqlx.DB, dialect sqlDialect) (sqlStore, error) {
+	err := newMigrator(db, dialect).Up()
+	if err != nil {
+		log.WithError(err).Error("couldn't migrate db")
+		_ = db.Close()
+		return sqlStore{}, err
+	}
+
+	return sqlStore{db: db, dialect: dialect}, nil
+}
+
+func (d *sqlStore) Close() error {
+	return d.db.Close()
+}
+
+// ext returns the transaction the store is bound to or the db itself
+func (d *sqlStore) ext() sqlx.ExtContext {
+	if d.tx != nil {
+		return d.tx
+	}
+	return d.db
+}
+
+// inTx runs fn in the transaction the store is bound to or in a new one
+func (d *sqlStore) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	if d.tx != nil {
+		return fn(d.tx)
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// activeAccount is a condition of users queries, users of deleted accounts are not found
+const activeAccount = `account_id NOT IN (SELECT id FROM accounts WHERE deleted_at IS NOT NULL)`
+
+// userTables keep data of a user by user_id, they are cleared before the user is deleted
+var userTables = []string{"users_meals", "users_settings", "users_calories", "refresh_tokens", "users_totp",
+	"recovery_codes", "password_reset_tokens", "api_keys"}
+
+// joinList stores lists such as API key scopes and role permissions comma separated
+func joinList(items []string) string {
+	return strings.Join(items, ",")
+}
+
+func splitList(items string) []string {
+	if items == "" {
+		return []string{}
+	}
+	return strings.Split(items, ",")
+}
+
+// repositories returns repositories of store which shares one database with all of them
+func repositories(store models.UserDatastore) models.Repositories {
+	return models.Repositories{
+		Credentials:        store,
+		Revocations:        store,
+		APIKeys:            store,
+		Users:              store,
+		Meals:              store,
+		DailyTotals:        store,
+		Settings:           store,
+		OwnershipTransfers: store,
+		Roles:              store,
+		TwoFactor:          store,
+		Teams:              store,
+		Invitations:        store,
+	}
+}
+
+// Do implements models.UnitOfWork
+func (d *sqlStore) Do(ctx context.Context, fn func(ctx context.Context, repos models.Repositories) error) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		return fn(ctx, repositories(&sqlStore{db: d.db, tx: tx, dialect: d.dialect}))
+	})
+}
+
+func (d *sqlStore) GetUser(ctx context.Context, accountID, username string) (*models.User, error) {
+	query := d.ext().Rebind(`SELECT id, account_id, username, role_id FROM users
+								WHERE account_id=? AND username=? AND ` + activeAccount)
+	row := d.ext().QueryRowxContext(ctx, query, accountID, username)
+
+	var user models.User
+	err := row.StructScan(&user)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (d *sqlStore) GetUserPassword(ctx context.Context, accountID, username string) (*models.Password, error) {
+	query := d.ext().Rebind(`SELECT password, password_algorithm, password_cost FROM users
+								WHERE account_id=? AND username=? AND ` + activeAccount)
+	row := d.ext().QueryRowxContext(ctx, query, accountID, username)
+
+	var pass models.Password
+	err := row.StructScan(&pass)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &pass, nil
+}
+
+func (d *sqlStore) UpdateUserPassword(ctx context.Context, accountID, userID string, pass models.Password) error {
+	query := d.ext().Rebind(`UPDATE users SET password=?, password_algorithm=?, password_cost=? WHERE account_id=? AND id=?`)
+	res, err := d.ext().ExecContext(ctx, query, pass.Hash, pass.Algorithm, pass.Cost, accountID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (d *sqlStore) GetUserById(ctx context.Context, accountID, userID string) (*models.User, error) {
+	query := d.ext().Rebind(`SELECT id, account_id, username, role_id FROM users
+								WHERE account_id=? AND id=? AND ` + activeAccount)
+	row := d.ext().QueryRowxContext(ctx, query, accountID, userID)
+
+	var user models.User
+	err := row.StructScan(&user)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (d *sqlStore) SaveUser(ctx context.Context, accountID, username string, pass models.Password, role int) (*models.User, error) {
+	id := uuid.New().String()
+
+	query := d.ext().Rebind(`INSERT INTO users (id, account_id, username, password, password_algorithm, password_cost, role_id)
+								VALUES (?, ?, ?, ?, ?, ?, ?);`)
+	_, err := d.ext().ExecContext(ctx, query, id, accountID, username, pass.Hash, pass.Algorithm, pass.Cost, role)
+	if err != nil {
+		if d.dialect.isDuplicateKeyError(err) {
+			return nil, models.ErrUserAlreadyExists
+		}
+		return nil, err
+	}
+
+	return &models.User{
+		ID:        id,
+		AccountID: accountID,
+		Username:  username,
+		RoleID:    role,
+	}, nil
+}
+
+func (d *sqlStore) SaveRootUser(ctx context.Context, accountName, username string, pass models.Password) (*models.User, error) {
+	user := models.User{
+		ID:        uuid.New().String(),
+		AccountID: uuid.New().String(),
+		Username:  username,
+		RoleID:    models.OwnerRole,
+	}
+
+	err := d.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`INSERT INTO accounts (id, name) VALUES (?, ?)`)
+		_, err := tx.ExecContext(ctx, query, user.AccountID, accountName)
+		if err != nil {
+			if d.dialect.isDuplicateKeyError(err) {
+				return models.ErrAccountAlreadyExists
+			}
+			return err
+		}
+
+		query = tx.Rebind(`INSERT INTO users (id, account_id, username, password, password_algorithm, password_cost, role_id)
+								VALUES (?, ?, ?, ?, ?, ?, ?);`)
+		_, err = tx.ExecContext(ctx, query, user.ID, user.AccountID, username, pass.Hash, pass.Algorithm, pass.Cost, models.OwnerRole)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (d *sqlStore) CountUsersWithRole(ctx context.Context, accountID string, roleID int) (int, error) {
+	query := d.ext().Rebind(`SELECT COUNT(*) FROM users WHERE account_id=? AND role_id=?`)
+	var count int
+	err := d.ext().QueryRowxContext(ctx, query, accountID, roleID).Scan(&count)
+	return count, err
+}
+
+func (d *sqlStore) UpdateUser(ctx context.Context, user models.User) (*models.User, error) {
+	err := d.inTx(ctx, func(tx *sqlx.Tx) error {
+		if user.RoleID != models.OwnerRole {
+			if err := d.checkOwnerRemains(ctx, tx, user.AccountID, user.ID); err != nil {
+				return err
+			}
+		}
+		query := tx.Rebind(`UPDATE users SET username=:username, role_id=:role_id WHERE account_id=:account_id AND id=:id`)
+		_, err := sqlx.NamedExecContext(ctx, tx, query, user)
+		return err
+	})
+	if err != nil {
+		if d.dialect.isDuplicateKeyError(err) {
+			return nil, models.ErrUserAlreadyExists
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// checkOwnerRemains returns ErrLastOwner when userID is the only owner of the account. Owners stay locked
+// until tx ends, so concurrent demotions and deletions of owners are checked one after another.
+func (d *sqlStore) checkOwnerRemains(ctx context.Context, tx *sqlx.Tx, accountID, userID string) error {
+	var ownerIDs []string
+	query := tx.Rebind(`SELECT id FROM users WHERE account_id=? AND role_id=?` + d.dialect.forUpdate())
+	err := sqlx.SelectContext(ctx, tx, &ownerIDs, query, accountID, models.OwnerRole)
+	if err != nil {
+		return err
+	}
+	if len(ownerIDs) == 1 && ownerIDs[0] == userID {
+		return models.ErrLastOwner
+	}
+	return nil
+}
+
+// DeleteUser deletes the user together with its data, its teams and ownership transfers from or to it
+func (d *sqlStore) DeleteUser(ctx context.Context, accountID string, userID string) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		var username string
+		err := tx.QueryRowxContext(ctx, tx.Rebind(`SELECT username FROM users WHERE account_id=? AND id=?`), accountID, userID).Scan(&username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrUserNotFound
+			}
+			return err
+		}
+		if err := d.checkOwnerRemains(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(`DELETE FROM login_failures WHERE throttle_key=?`), models.UserThrottleKey(accountID, username))
+		if err != nil {
+			return err
+		}
+
+		for _, table := range userTables {
+			_, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE user_id=?`, table)), userID)
+			if err != nil {
+				return err
+			}
+		}
+		query := tx.Rebind(`DELETE FROM teams WHERE account_id=? AND (manager_id=? OR user_id=?)`)
+		_, err = tx.ExecContext(ctx, query, accountID, userID, userID)
+		if err != nil {
+			return err
+		}
+		query = tx.Rebind(`DELETE FROM ownership_transfers WHERE account_id=? AND (from_user_id=? OR to_user_id=?)`)
+		_, err = tx.ExecContext(ctx, query, accountID, userID, userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, tx.Rebind(`DELETE FROM users WHERE account_id=? AND id=?`), accountID, userID)
+		return err
+	})
+}
+
+func (d *sqlStore) SaveMeal(ctx context.Context, userID string, meal models.Meal) (*models.Meal, error) {
+	meal.ID = uuid.New().String()
+	query := d.ext().Rebind(`INSERT INTO users_meals (id, user_id, name, date, time, calories) VALUES (?, ?, ?, ?, ?, ?);`)
+	_, err := d.ext().ExecContext(ctx, query, meal.ID, userID, meal.Name, meal.Date, meal.Time, meal.Calories)
+	if err != nil {
+		return nil, err
+	}
+
+	meal.CaloriesDeficit = false
+	return &meal, nil
+}
+
+func (d *sqlStore) UpdateMeal(ctx context.Context, userID string, meal models.Meal) (*models.Meal, error) {
+	// rows affected by an update can't tell missing meal, MySQL does not count rows which keep their values
+	var id string
+	query := d.ext().Rebind(`SELECT id FROM users_meals WHERE user_id=? AND id=?`)
+	err := d.ext().QueryRowxContext(ctx, query, userID, meal.ID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrMealNotFound
+		}
+		return nil, err
+	}
+
+	query = d.ext().Rebind(`UPDATE users_meals SET name=?, date=?, time=?, calories=? WHERE user_id=? AND id=?`)
+	_, err = d.ext().ExecContext(ctx, query, meal.Name, meal.Date, meal.Time, meal.Calories, userID, meal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	meal.CaloriesDeficit = false
+	return &meal, nil
+}
+
+func (d *sqlStore) GetUsers(ctx context.Context, accountID string, params models.ListParams) (models.UserSlice, error) {
+	res := models.UserSlice{Items: make([]models.User, 0)}
+	cond, filterArgs, err := buildFilterQuery(params.Filter, filter.UserFields, userColumns)
+	if err != nil {
+		return res, err
+	}
+	order, err := listSort(params.Sort, filter.UserSortFields, defaultUserSort)
+	if err != nil {
+		return res, err
+	}
+
+	query := d.ext().Rebind(fmt.Sprintf(`SELECT count(*)
+								FROM users
+								WHERE account_id = ? %s`, cond))
+	row := d.ext().QueryRowxContext(ctx, query, append([]interface{}{accountID}, filterArgs...)...)
+	var total int
+	err = row.Scan(&total)
+	if err != nil {
+		if d.dialect.isInvalidQuery(err) {
+			return res, models.ErrInvalidQuery
+		}
+		return res, err
+	}
+
+	query = d.ext().Rebind(fmt.Sprintf(`SELECT id, account_id, username, role_id
+								FROM users
+								WHERE account_id = ? %s
+								ORDER BY %s
+								LIMIT ? OFFSET ?;`, cond, order.SQL(userColumns)))
+	args := append(append([]interface{}{accountID}, filterArgs...), params.PerPage, params.Page*params.PerPage)
+	rows, err := d.ext().QueryxContext(ctx, query, args...)
+	if err != nil {
+		if d.dialect.isInvalidQuery(err) {
+			return res, models.ErrInvalidQuery
+		}
+		return res, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var user models.User
+		err := rows.StructScan(&user)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return res, nil
+			}
+			return res, err
+		}
+		res.Items = append(res.Items, user)
+	}
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	res.Total = total
+	return res, nil
+}
+
+func (d *sqlStore) GetMeals(ctx context.Context, userID string, params models.ListParams) (models.MealSlice, error) {
+	res := models.MealSlice{Items: make([]models.Meal, 0)}
+	cond, filterArgs, err := buildFilterQuery(params.Filter, filter.MealFields, mealColumns)
+	if err != nil {
+		return res, err
+	}
+	order, err := listSort(params.Sort, filter.MealSortFields, defaultMealSort)
+	if err != nil {
+		return res, err
+	}
+	cursorCond, cursorArgs, err := mealsCursorCondition(params)
+	if err != nil {
+		return res, err
+	}
+
+	query := d.ext().Rebind(fmt.Sprintf(`SELECT count(*)
+								FROM users_meals AS m
+								LEFT JOIN users_calories AS c ON m.user_id = c.user_id AND m.date = c.date
+								WHERE m.user_id=? %s`, cond))
+	row := d.ext().QueryRowxContext(ctx, query, append([]interface{}{userID}, filterArgs...)...)
+	var total int
+	err = row.Scan(&total)
+	if err != nil {
+		if d.dialect.isInvalidQuery(err) {
+			return res, models.ErrInvalidQuery
+		}
+		return res, err
+	}
+
+	query = d.ext().Rebind(fmt.Sprintf(`SELECT m.id, m.date, m.time, m.name, m.calories, c.calories_deficit
+								FROM users_meals AS m
+								LEFT JOIN users_calories AS c ON m.user_id = c.user_id AND m.date = c.date
+								WHERE m.user_id=? %s %s
+								ORDER BY %s
+								LIMIT ? OFFSET ?;`, cond, cursorCond, order.SQL(mealColumns)))
+	offset := params.Page * params.PerPage
+	if params.Cursor != "" {
+		offset = 0
+	}
+	args := append([]interface{}{userID}, filterArgs...)
+	args = append(args, cursorArgs...)
+	// one more meal is selected to find out whether there is a next page
+	args = append(args, params.PerPage+1, offset)
+	rows, err := d.ext().QueryxContext(ctx, query, args...)
+	if err != nil {
+		if d.dialect.isInvalidQuery(err) {
+			return res, models.ErrInvalidQuery
+		}
+		return res, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var meal models.Meal
+		var dateStr, timeStr string
+		var caloriesDeficit sql.NullBool
+		err := rows.Scan(&meal.ID, &dateStr, &timeStr, &meal.Name, &meal.Calories, &caloriesDeficit)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return res, nil
+			}
+			return res, err
+		}
+		meal.Date = dateStr[:10]
+		meal.Time = timeOfDay(timeStr)
+		meal.CaloriesDeficit = caloriesDeficit.Bool
+		res.Items = append(res.Items, meal)
+	}
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	if len(res.Items) > params.PerPage {
+		res.Items = res.Items[:params.PerPage]
+		res.NextCursor = nextMealCursor(params, res.Items)
+	}
+	res.Total = total
+	return res, nil
+}
+
+func (d *sqlStore) GetMeal(ctx context.Context, userID, mealID string) (*models.Meal, error) {
+	query := d.ext().Rebind(`SELECT m.id, m.date, m.time, m.name, m.calories, c.calories_deficit
+								FROM users_meals AS m
+								LEFT JOIN users_calories AS c ON m.user_id = c.user_id AND m.date = c.date
+								WHERE m.user_id=? AND m.id=?`)
+	row := d.ext().QueryRowxContext(ctx, query, userID, mealID)
+	var meal models.Meal
+	var dateStr, timeStr string
+	// the day has no total until it is updated after the meal write
+	var caloriesDeficit sql.NullBool
+	err := row.Scan(&meal.ID, &dateStr, &timeStr, &meal.Name, &meal.Calories, &caloriesDeficit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrMealNotFound
+		}
+		return nil, err
+	}
+	meal.Date = dateStr[:10]
+	meal.Time = timeOfDay(timeStr)
+	meal.CaloriesDeficit = caloriesDeficit.Bool
+
+	return &meal, nil
+}
+
+func (d *sqlStore) DeleteMeal(ctx context.Context, userID string, mealID string) error {
+	query := d.ext().Rebind(`DELETE FROM users_meals WHERE user_id=? AND id=?`)
+	res, err := d.ext().ExecContext(ctx, query, userID, mealID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrMealNotFound
+	}
+
+	return nil
+}
+
+func (d *sqlStore) UpdateDailyTotal(ctx context.Context, userID, date string) (bool, error) {
+	var caloriesDeficit bool
+	err := d.inTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		caloriesDeficit, err = updateCaloriesDeficit(ctx, tx, userID, date)
+		return err
+	})
+	return caloriesDeficit, err
+}
+
+func (d *sqlStore) GetDailyTotals(ctx context.Context, userID, from, to string) ([]models.DailyTotal, error) {
+	query := `SELECT date, total_calories, calories_deficit FROM users_calories WHERE user_id=?`
+	args := []interface{}{userID}
+	if from != "" {
+		query += ` AND date>=?`
+		args = append(args, from)
+	}
+	if to != "" {
+		query += ` AND date<=?`
+		args = append(args, to)
+	}
+	rows, err := d.ext().QueryxContext(ctx, d.ext().Rebind(query+` ORDER BY date`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []models.DailyTotal{}
+	for rows.Next() {
+		var total models.DailyTotal
+		var dateStr string
+		if err := rows.Scan(&dateStr, &total.TotalCalories, &total.CaloriesDeficit); err != nil {
+			return nil, err
+		}
+		total.Date = dateStr[:10]
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+func (d *sqlStore) UpdateSettings(ctx context.Context, userID string, settings models.Settings) (*models.Settings, error) {
+	err := d.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`SELECT id FROM users_settings WHERE user_id=?`)
+		row := tx.QueryRowxContext(ctx, query, userID)
+		var id string
+		err := row.Scan(&id)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				return err
+			}
+			query = tx.Rebind(`INSERT INTO users_settings (user_id, expected_daily_calories) VALUES (?, ?)`)
+			_, err = tx.ExecContext(ctx, query, userID, settings.ExpectedDailyCalories)
+		} else {
+			query = tx.Rebind(`UPDATE users_settings SET expected_daily_calories=? WHERE user_id=?`)
+			_, err = tx.ExecContext(ctx, query, settings.ExpectedDailyCalories, userID)
+		}
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`UPDATE users_calories SET calories_deficit=? WHERE user_id=? AND total_calories<?`)
+		_, err = tx.ExecContext(ctx, query, true, userID, settings.ExpectedDailyCalories)
+		if err != nil {
+			return err
+		}
+		query = tx.Rebind(`UPDATE users_calories SET calories_deficit=? WHERE user_id=? AND total_calories>=?`)
+		_, err = tx.ExecContext(ctx, query, false, userID, settings.ExpectedDailyCalories)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (d *sqlStore) GetSettings(ctx context.Context, userID string) (*models.Settings, error) {
+	query := d.ext().Rebind(`SELECT expected_daily_calories FROM users_settings WHERE user_id=?`)
+	row := d.ext().QueryRowxContext(ctx, query, userID)
+
+	var settings models.Settings
+	_ = row.StructScan(&settings)
+
+	return &settings, nil
+}
+
+func updateCaloriesDeficit(ctx context.Context, tx *sqlx.Tx, userID string, date string) (bool, error) {
+	query := tx.Rebind(`SELECT COALESCE(SUM(calories), 0) FROM users_meals WHERE user_id=? AND date=?`)
+	row := tx.QueryRowxContext(ctx, query, userID, date)
+	var totalCalories int
+	err := row.Scan(&totalCalories)
+	if err != nil {
+		return false, err
+	}
+
+	query = tx.Rebind(`SELECT expected_daily_calories FROM users_settings WHERE user_id=?`)
+	row = tx.QueryRowxContext(ctx, query, userID)
+	var settings models.Settings
+	_ = row.StructScan(&settings)
+
+	var caloriesDeficit bool
+	if totalCalories < settings.ExpectedDailyCalories {
+		caloriesDeficit = true
+	}
+
+	query = tx.Rebind(`SELECT id FROM users_calories WHERE user_id=? AND date=?`)
+	row = tx.QueryRowxContext(ctx, query, userID, date)
+	var id string
+	err = row.Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			query = tx.Rebind(`INSERT INTO users_calories (user_id, date, total_calories, calories_deficit) VALUES (?, ?, ?, ?)`)
+			_, err = tx.ExecContext(ctx, query, userID, date, totalCalories, caloriesDeficit)
+			if err != nil {
+				return false, err
+			}
+		} else {
+			return false, err
+		}
+	} else {
+		query = tx.Rebind(`UPDATE users_calories SET total_calories=?, calories_deficit=? WHERE user_id=? AND date=?`)
+		_, err := tx.ExecContext(ctx, query, totalCalories, caloriesDeficit, userID, date)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return caloriesDeficit, nil
+}
+
+// timeOfDay strips the date part which some drivers (lib/pq) add when TIME column is scanned into string
+func timeOfDay(s string) string {
+	if i := strings.IndexByte(s, 'T'); i >= 0 && len(s) >= i+9 {
+		return s[i+1 : i+9]
+	}
+	return s
+}
+
+// userColumns and mealColumns map filter fields to the columns of GetUsers and GetMeals queries
+var (
+	userColumns = map[string]string{
+		"id":       "id",
+		"username": "username",
+		"role_id":  "role_id",
+	}
+
+	mealColumns = map[string]string{
+		"id":               "m.id",
+		"date":             "m.date",
+		"time":             "m.time",
+		"name":             "m.name",
+		"calories":         "m.calories",
+		"calories_deficit": "c.calories_deficit",
+	}
+)
+
+var (
+	defaultUserSort = filter.Sort{{Field: "username"}}
+	defaultMealSort = filter.Sort{{Field: "date"}, {Field: "time", Desc: true}}
+)
+
+// listSort parses sort of a resource, empty sort is replaced by defaultSort.
+// Lists are always sorted by id last, so pages are stable when other fields are equal.
+func listSort(query string, fields filter.Fields, defaultSort filter.Sort) (filter.Sort, error) {
+	sort, err := filter.ParseSort(query, fields)
+	if err != nil {
+		return nil, err
+	}
+	if sort == nil {
+		sort = defaultSort
+	}
+	return sort.Then(filter.SortField{Field: "id"}), nil
+}
+
+// buildFilterQuery parses filter of a resource and returns condition to be appended to WHERE clause with its arguments
+func buildFilterQuery(query string, fields filter.Fields, columns map[string]string) (string, []interface{}, error) {
+	expr, err := filter.Parse(query, fields)
+	if err != nil || expr == nil {
+		return "", nil, err
+	}
+
+	cond, args := filter.SQL(expr, columns)
+	return " AND " + cond, args, nil
+}
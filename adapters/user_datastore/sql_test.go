@@ -0,0 +1,58 @@
+package user_datastore
+
+import (
+	"calories-counter/adapters/user_datastore/storetest"
+	"calories-counter/models"
+	"os"
+	"regexp"
+	"testing"
+)
+
+// MySQL and PostgreSQL suites run only when a test database is provided,
+// e.g. TEST_MYSQL_DB_SOURCE=root:my-secret-password@tcp(localhost:3306)/user_data_test
+
+func TestMySQLStore(t *testing.T) {
+	dbSource := os.Getenv("TEST_MYSQL_DB_SOURCE")
+	if dbSource == "" {
+		t.Skip("TEST_MYSQL_DB_SOURCE is not set")
+	}
+	storetest.Run(t, func(t *testing.T) models.UserDatastore {
+		store, err := NewMySQLStore(dbSource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	})
+}
+
+func TestPostgresStore(t *testing.T) {
+	dbSource := os.Getenv("TEST_POSTGRES_DB_SOURCE")
+	if dbSource == "" {
+		t.Skip("TEST_POSTGRES_DB_SOURCE is not set")
+	}
+	storetest.Run(t, func(t *testing.T) models.UserDatastore {
+		store, err := NewPostgresStore(dbSource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	})
+}
+
+// idempotentStatement matches statements which can run again after MySQL committed them
+var idempotentStatement = regexp.MustCompile(`(?s)^(CREATE TABLE IF NOT EXISTS|DROP TABLE IF EXISTS|INSERT INTO .+ NOT IN \(SELECT)`)
+
+func TestMySQLMigrationsRerunnable(t *testing.T) {
+	for _, mig := range (mysqlDialect{}).migrations() {
+		for direction, statements := range map[string][]string{"up": mig.up, "down": mig.down} {
+			// the last statement does not need to be idempotent, schema_version is bumped only after it succeeds
+			for _, stmt := range statements[:len(statements)-1] {
+				if !idempotentStatement.MatchString(stmt) {
+					t.Errorf("Migration %d %s %s can't be re-run when it fails after `%s`", mig.version, mig.name, direction, stmt)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package user_datastore
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore implements models.UserDatastore, keeps data in a single database file
+type SQLiteStore struct {
+	sqlStore
+}
+
+type sqliteDialect struct{}
+
+// NewSQLiteStore opens (and creates if needed) database file under given path
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newSQLStore(db, sqliteDialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLiteStore{store}, nil
+}
+
+func openSQLite(path string) (*sqlx.DB, error) {
+	db, err := openDB("sqlite3", "file:"+path+"?_foreign_keys=1&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	// sqlite allows only one writer, transactions would fail with "database is locked" otherwise
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+func (sqliteDialect) isDuplicateKeyError(err error) bool {
+	switch sErr := err.(type) {
+	case sqlite3.Error:
+		if sErr.ExtendedCode == sqlite3.ErrConstraintUnique || sErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return true
+		}
+	}
+	return false
+}
+
+func (sqliteDialect) isInvalidQuery(err error) bool {
+	switch sErr := err.(type) {
+	case sqlite3.Error:
+		// sqlite reports unknown columns and syntax errors with generic SQLITE_ERROR code
+		if sErr.Code == sqlite3.ErrError {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlite has a single connection, see openSQLite, so transactions never overlap
+func (sqliteDialect) forUpdate() string {
+	return ""
+}
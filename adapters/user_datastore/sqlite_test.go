@@ -0,0 +1,103 @@
+package user_datastore
+
+import (
+	"calories-counter/adapters/user_datastore/storetest"
+	"calories-counter/models"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) models.UserDatastore {
+		dir, err := ioutil.TempDir("", "calories-counter")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = os.RemoveAll(dir) })
+		store, err := NewSQLiteStore(filepath.Join(dir, "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	})
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calories-counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	db, err := openSQLite(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newMigrator(db, sqliteDialect{})
+	t.Cleanup(func() { _ = m.Close() })
+
+	// user created by the first schema version keeps plaintext password
+	err = m.Up()
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	for v := m.Latest(); v > 1; v-- {
+		if err := m.Down(); err != nil {
+			t.Fatalf("Down from %d failed: %v", v, err)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO users (id, account_id, username, password, role_id) VALUES ('1', '1', 'owner', 'Xyz123', 3)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO users_settings (user_id, expected_daily_calories) VALUES ('1', 100)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// account whose owner was deleted
+	_, err = db.Exec(`INSERT INTO users (id, account_id, username, password, role_id) VALUES ('2', '2', 'user', 'Xyz123', 0)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up()
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	store := sqlStore{db: db, dialect: sqliteDialect{}}
+	pass, err := store.GetUserPassword(context.Background(), "1", "owner")
+	if err != nil {
+		t.Fatalf("GetUserPassword failed: %v", err)
+	}
+	if *pass != (models.Password{Hash: "Xyz123", Algorithm: "plaintext"}) {
+		t.Errorf("Expected legacy plaintext password but was %+v", pass)
+	}
+	for name, accountID := range map[string]string{"owner": "1", "2": "2"} {
+		account, err := store.GetAccountByName(context.Background(), name)
+		if err != nil || account.ID != accountID {
+			t.Errorf("Expected account %s named `%s` but was %+v, err: %v", accountID, name, account, err)
+		}
+	}
+
+	// every migration can be reverted and applied again
+	for v := m.Latest(); v > 0; v-- {
+		if err := m.Down(); err != nil {
+			t.Fatalf("Down from %d failed: %v", v, err)
+		}
+	}
+	err = m.Down()
+	if err != ErrNoMigration {
+		t.Errorf("Expected error `%v` but was `%v`", ErrNoMigration, err)
+	}
+	err = m.Up()
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	version, err := m.Version()
+	if err != nil || version != m.Latest() {
+		t.Errorf("Expected version %d but was %d, err: %v", m.Latest(), version, err)
+	}
+}
@@ -0,0 +1,315 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+)
+
+// userData is data of a user which has to be deleted together with it
+type userData struct {
+	tokenHash  string
+	apiKeyHash string
+}
+
+// saveUserData gives user a meal, settings, refresh token, API key, TOTP secret, failed sign in
+// and puts it into team of manager
+func saveUserData(t *testing.T, store models.UserDatastore, user *models.User, managerID string) userData {
+	t.Helper()
+	data := userData{
+		tokenHash:  uuid.New().String() + uuid.New().String()[:28],
+		apiKeyHash: uuid.New().String() + uuid.New().String()[:28],
+	}
+	saveMeal(t, store, user.ID, "2021-01-01", "10:00", 500)
+	updateSettings(t, store, user.ID, 2000)
+	err := store.SaveRefreshToken(ctx, models.RefreshToken{
+		TokenHash: data.tokenHash,
+		FamilyID:  uuid.New().String(),
+		UserID:    user.ID,
+		AccountID: user.AccountID,
+		ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second),
+	})
+	if err != nil {
+		t.Fatalf("SaveRefreshToken failed: %v", err)
+	}
+	err = store.SaveAPIKey(ctx, models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		AccountID: user.AccountID,
+		Name:      "script",
+		Prefix:    "cc_script",
+		KeyHash:   data.apiKeyHash,
+		Scopes:    []string{},
+		CreatedAt: time.Now().Truncate(time.Second),
+	})
+	if err != nil {
+		t.Fatalf("SaveAPIKey failed: %v", err)
+	}
+	err = store.SaveTOTP(ctx, models.TOTP{UserID: user.ID, AccountID: user.AccountID, Secret: "JBSWY3DPEHPK3PXP"})
+	if err != nil {
+		t.Fatalf("SaveTOTP failed: %v", err)
+	}
+	err = store.AddLoginFailure(ctx, models.UserThrottleKey(user.AccountID, user.Username), time.Now(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("AddLoginFailure failed: %v", err)
+	}
+	err = store.AddTeamMember(ctx, user.AccountID, managerID, user.ID)
+	if err != nil {
+		t.Fatalf("AddTeamMember failed: %v", err)
+	}
+	return data
+}
+
+// expectUserDataDeleted checks data saved by saveUserData is gone
+func expectUserDataDeleted(t *testing.T, store models.UserDatastore, user *models.User, managerID string, data userData) {
+	t.Helper()
+	meals, err := store.GetMeals(ctx, user.ID, models.ListParams{PerPage: 10})
+	if err != nil || meals.Total != 0 {
+		t.Errorf("Expected meals of deleted user to be deleted but were %+v, err: %v", meals, err)
+	}
+	settings, err := store.GetSettings(ctx, user.ID)
+	if err != nil || *settings != (models.Settings{}) {
+		t.Errorf("Expected settings of deleted user to be deleted but were %+v, err: %v", settings, err)
+	}
+	_, err = store.UseRefreshToken(ctx, data.tokenHash)
+	expectError(t, err, models.ErrRefreshTokenNotFound)
+	_, err = store.GetAPIKeyByHash(ctx, data.apiKeyHash)
+	expectError(t, err, models.ErrAPIKeyNotFound)
+	_, err = store.GetTOTP(ctx, user.AccountID, user.ID)
+	expectError(t, err, models.ErrTOTPNotFound)
+	failures, err := store.GetLoginFailures(ctx, models.UserThrottleKey(user.AccountID, user.Username))
+	if err != nil || failures.Failures != 0 {
+		t.Errorf("Expected failed sign ins of deleted user to be deleted but were %+v, err: %v", failures, err)
+	}
+	managers, err := store.GetTeamManagers(ctx, user.AccountID, user.ID)
+	if err != nil || len(managers) != 0 {
+		t.Errorf("Expected deleted user to leave team of %s but its managers were %v, err: %v", managerID, managers, err)
+	}
+}
+
+// expectDeletedAccounts checks which of the accounts GetDeletedAccounts lists, other tests may leave
+// deleted accounts in the store
+func expectDeletedAccounts(t *testing.T, store models.UserDatastore, before time.Time, listed map[string]bool) {
+	t.Helper()
+	accounts, err := store.GetDeletedAccounts(ctx, before)
+	if err != nil {
+		t.Fatalf("GetDeletedAccounts failed: %v", err)
+	}
+	found := make(map[string]bool)
+	for _, account := range accounts {
+		if account.DeletedAt == nil || !account.DeletedAt.Before(before) {
+			t.Errorf("Unexpected account %+v deleted before %v", account, before)
+		}
+		found[account.ID] = true
+	}
+	for accountID, expected := range listed {
+		if found[accountID] != expected {
+			t.Errorf("Expected account %s to be listed %t but it was %t", accountID, expected, found[accountID])
+		}
+	}
+}
+
+func testDeleteUserData(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	user := newUser(t, store, owner.AccountID)
+	data := saveUserData(t, store, user, owner.ID)
+	err := store.SaveOwnershipTransfer(ctx, models.OwnershipTransfer{
+		AccountID:  owner.AccountID,
+		FromUserID: owner.ID,
+		ToUserID:   user.ID,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("SaveOwnershipTransfer failed: %v", err)
+	}
+
+	err = store.DeleteUser(ctx, owner.AccountID, user.ID)
+	if err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	expectUserDataDeleted(t, store, user, owner.ID, data)
+	_, err = store.GetOwnershipTransfer(ctx, owner.AccountID)
+	expectError(t, err, models.ErrOwnershipTransferNotFound)
+
+	// the manager's team is deleted with the manager
+	manager := newUser(t, store, owner.AccountID)
+	member := newUser(t, store, owner.AccountID)
+	if err := store.AddTeamMember(ctx, owner.AccountID, manager.ID, member.ID); err != nil {
+		t.Fatalf("AddTeamMember failed: %v", err)
+	}
+	err = store.DeleteUser(ctx, owner.AccountID, manager.ID)
+	if err != nil {
+		t.Fatalf("DeleteUser of manager failed: %v", err)
+	}
+	managers, err := store.GetTeamManagers(ctx, owner.AccountID, member.ID)
+	if err != nil || len(managers) != 0 {
+		t.Errorf("Expected team of deleted manager to be deleted but managers of member were %v, err: %v", managers, err)
+	}
+}
+
+func testDeleteAccount(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	user := newUser(t, store, owner.AccountID)
+	other := newAccount(t, store)
+	deletedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	restoreTokenHash := uuid.New().String() + uuid.New().String()[:28]
+
+	err := store.DeleteAccount(ctx, owner.AccountID, deletedAt, restoreTokenHash)
+	if err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+	account, err := store.GetAccount(ctx, owner.AccountID)
+	if err != nil || account.DeletedAt == nil || !account.DeletedAt.Equal(deletedAt) {
+		t.Errorf("Expected account to be deleted at %v but was %+v, err: %v", deletedAt, account, err)
+	}
+	err = store.DeleteAccount(ctx, owner.AccountID, time.Now(), uuid.New().String()+uuid.New().String()[:28])
+	expectError(t, err, models.ErrAccountNotFound)
+
+	// users of deleted account can't sign in nor use their tokens
+	_, err = store.GetUserById(ctx, owner.AccountID, user.ID)
+	expectError(t, err, models.ErrUserNotFound)
+	_, err = store.GetUser(ctx, owner.AccountID, owner.Username)
+	expectError(t, err, models.ErrUserNotFound)
+	_, err = store.GetUserPassword(ctx, owner.AccountID, owner.Username)
+	expectError(t, err, models.ErrUserNotFound)
+	_, err = store.GetUserById(ctx, other.AccountID, other.ID)
+	if err != nil {
+		t.Errorf("Expected user of other account to be found, err: %v", err)
+	}
+
+	expectDeletedAccounts(t, store, deletedAt.Add(time.Second), map[string]bool{owner.AccountID: true, other.AccountID: false})
+	expectDeletedAccounts(t, store, deletedAt, map[string]bool{owner.AccountID: false})
+
+	// accounts deleted before the grace period are not restored
+	_, err = store.RestoreAccount(ctx, restoreTokenHash, deletedAt.Add(time.Second))
+	expectError(t, err, models.ErrAccountNotFound)
+	_, err = store.RestoreAccount(ctx, uuid.New().String()+uuid.New().String()[:28], deletedAt)
+	expectError(t, err, models.ErrAccountNotFound)
+
+	account, err = store.RestoreAccount(ctx, restoreTokenHash, deletedAt)
+	if err != nil || account.ID != owner.AccountID || account.DeletedAt != nil {
+		t.Fatalf("Expected account %s to be restored but was %+v, err: %v", owner.AccountID, account, err)
+	}
+	account, err = store.GetAccount(ctx, owner.AccountID)
+	if err != nil || account.DeletedAt != nil {
+		t.Errorf("Expected account to be restored but was %+v, err: %v", account, err)
+	}
+	_, err = store.GetUserById(ctx, owner.AccountID, user.ID)
+	if err != nil {
+		t.Errorf("Expected user of restored account to be found, err: %v", err)
+	}
+
+	// the restore token is used up, also when the account is deleted again
+	err = store.DeleteAccount(ctx, owner.AccountID, deletedAt, uuid.New().String()+uuid.New().String()[:28])
+	if err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+	_, err = store.RestoreAccount(ctx, restoreTokenHash, deletedAt)
+	expectError(t, err, models.ErrAccountNotFound)
+}
+
+func testPurgeAccount(t *testing.T, store models.UserDatastore) {
+	accountName := uniqueName("account")
+	owner, err := store.SaveRootUser(ctx, accountName, uniqueName("owner"), testPassword)
+	if err != nil {
+		t.Fatalf("SaveRootUser failed: %v", err)
+	}
+	user := newUser(t, store, owner.AccountID)
+	data := saveUserData(t, store, user, owner.ID)
+	_, err = store.SaveRole(ctx, models.Role{AccountID: owner.AccountID, Name: "dietitian", Permissions: []string{}})
+	if err != nil {
+		t.Fatalf("SaveRole failed: %v", err)
+	}
+	now := time.Now().Truncate(time.Second)
+	err = store.SaveInvitation(ctx, models.Invitation{ID: uuid.New().String(), AccountID: owner.AccountID, Name: "Jane",
+		InvitedBy: owner.ID, TokenID: uuid.New().String(), ExpiresAt: now.Add(time.Hour), CreatedAt: now})
+	if err != nil {
+		t.Fatalf("SaveInvitation failed: %v", err)
+	}
+	other := newAccount(t, store)
+	otherUser := newUser(t, store, other.AccountID)
+	otherData := saveUserData(t, store, otherUser, other.ID)
+
+	err = store.PurgeAccount(ctx, owner.AccountID)
+	expectError(t, err, models.ErrAccountNotFound)
+	err = store.DeleteAccount(ctx, owner.AccountID, now, uuid.New().String()+uuid.New().String()[:28])
+	if err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+
+	err = store.PurgeAccount(ctx, owner.AccountID)
+	if err != nil {
+		t.Fatalf("PurgeAccount failed: %v", err)
+	}
+	_, err = store.GetAccount(ctx, owner.AccountID)
+	expectError(t, err, models.ErrAccountNotFound)
+	users, err := store.GetUsers(ctx, owner.AccountID, models.ListParams{PerPage: 10})
+	if err != nil || users.Total != 0 {
+		t.Errorf("Expected users of purged account to be deleted but were %+v, err: %v", users, err)
+	}
+	expectUserDataDeleted(t, store, user, owner.ID, data)
+	roles, err := store.GetRoles(ctx, owner.AccountID)
+	if err != nil || len(roles) != 0 {
+		t.Errorf("Expected roles of purged account to be deleted but were %+v, err: %v", roles, err)
+	}
+	invitations, err := store.GetInvitations(ctx, owner.AccountID)
+	if err != nil || len(invitations) != 0 {
+		t.Errorf("Expected invitations of purged account to be deleted but were %+v, err: %v", invitations, err)
+	}
+	err = store.PurgeAccount(ctx, owner.AccountID)
+	expectError(t, err, models.ErrAccountNotFound)
+
+	// data of other accounts is kept and the name can be used again
+	if _, err := store.GetAPIKeyByHash(ctx, otherData.apiKeyHash); err != nil {
+		t.Errorf("Expected API key of other account to be kept, err: %v", err)
+	}
+	failures, err := store.GetLoginFailures(ctx, models.UserThrottleKey(otherUser.AccountID, otherUser.Username))
+	if err != nil || failures.Failures != 1 {
+		t.Errorf("Expected failed sign in of other account to be kept but were %+v, err: %v", failures, err)
+	}
+	meals, err := store.GetMeals(ctx, otherUser.ID, models.ListParams{PerPage: 10})
+	if err != nil || meals.Total != 1 {
+		t.Errorf("Expected meal of other account to be kept but meals were %+v, err: %v", meals, err)
+	}
+	_, err = store.SaveRootUser(ctx, accountName, uniqueName("owner"), testPassword)
+	if err != nil {
+		t.Errorf("SaveRootUser with name of purged account failed: %v", err)
+	}
+}
+
+func testConcurrentRestoreAndPurge(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	restoreTokenHash := uuid.New().String() + uuid.New().String()[:28]
+	deletedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := store.DeleteAccount(ctx, owner.AccountID, deletedAt, restoreTokenHash); err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var restoreErr, purgeErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, restoreErr = store.RestoreAccount(ctx, restoreTokenHash, deletedAt)
+	}()
+	go func() {
+		defer wg.Done()
+		purgeErr = store.PurgeAccount(ctx, owner.AccountID)
+	}()
+	wg.Wait()
+
+	// exactly one of them wins, restored account is not purged
+	account, err := store.GetAccount(ctx, owner.AccountID)
+	switch {
+	case restoreErr == nil && purgeErr == models.ErrAccountNotFound:
+		if err != nil || account.DeletedAt != nil {
+			t.Errorf("Expected restored account to be kept but was %+v, err: %v", account, err)
+		}
+	case purgeErr == nil && restoreErr == models.ErrAccountNotFound:
+		expectError(t, err, models.ErrAccountNotFound)
+	default:
+		t.Errorf("Expected either restore or purge to succeed but errors were `%v` and `%v`", restoreErr, purgeErr)
+	}
+}
@@ -0,0 +1,82 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testAPIKeys(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	now := time.Now().Truncate(time.Second)
+	expiresAt := now.Add(time.Hour)
+	newKey := func(name string, createdAt time.Time, scopes []string, expiresAt *time.Time) models.APIKey {
+		return models.APIKey{
+			ID:        uuid.New().String(),
+			UserID:    owner.ID,
+			AccountID: owner.AccountID,
+			Name:      name,
+			Prefix:    "cc_" + name,
+			KeyHash:   uuid.New().String() + uuid.New().String()[:28],
+			Scopes:    scopes,
+			ExpiresAt: expiresAt,
+			CreatedAt: createdAt,
+		}
+	}
+	full := newKey("full", now, []string{}, nil)
+	scoped := newKey("scoped", now.Add(time.Second), []string{models.ScopeMealsRead, models.ScopeMealsWrite}, &expiresAt)
+
+	for _, key := range []models.APIKey{scoped, full} {
+		if err := store.SaveAPIKey(ctx, key); err != nil {
+			t.Fatalf("SaveAPIKey failed: %v", err)
+		}
+	}
+
+	keys, err := store.GetAPIKeys(ctx, owner.AccountID, owner.ID)
+	if err != nil {
+		t.Fatalf("GetAPIKeys failed: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []models.APIKey{full, scoped}) {
+		t.Errorf("Expected keys %+v but was %+v", []models.APIKey{full, scoped}, keys)
+	}
+
+	key, err := store.GetAPIKeyByHash(ctx, scoped.KeyHash)
+	if err != nil {
+		t.Fatalf("GetAPIKeyByHash failed: %v", err)
+	}
+	if !reflect.DeepEqual(*key, scoped) {
+		t.Errorf("Expected key %+v but was %+v", scoped, key)
+	}
+
+	// keys of other users can not be deleted
+	other := newAccount(t, store)
+	err = store.DeleteAPIKey(ctx, other.AccountID, other.ID, scoped.ID)
+	expectError(t, err, models.ErrAPIKeyNotFound)
+
+	err = store.DeleteAPIKey(ctx, owner.AccountID, owner.ID, scoped.ID)
+	if err != nil {
+		t.Fatalf("DeleteAPIKey failed: %v", err)
+	}
+	_, err = store.GetAPIKeyByHash(ctx, scoped.KeyHash)
+	expectError(t, err, models.ErrAPIKeyNotFound)
+	err = store.DeleteAPIKey(ctx, owner.AccountID, owner.ID, scoped.ID)
+	expectError(t, err, models.ErrAPIKeyNotFound)
+
+	// keys of other users are kept when all keys of the user are deleted
+	otherKey := newKey("other", now, []string{}, nil)
+	otherKey.UserID, otherKey.AccountID = other.ID, other.AccountID
+	if err := store.SaveAPIKey(ctx, otherKey); err != nil {
+		t.Fatalf("SaveAPIKey failed: %v", err)
+	}
+	err = store.DeleteUserAPIKeys(ctx, owner.AccountID, owner.ID)
+	if err != nil {
+		t.Fatalf("DeleteUserAPIKeys failed: %v", err)
+	}
+	_, err = store.GetAPIKeyByHash(ctx, full.KeyHash)
+	expectError(t, err, models.ErrAPIKeyNotFound)
+	if _, err := store.GetAPIKeyByHash(ctx, otherKey.KeyHash); err != nil {
+		t.Errorf("Expected key of other user to be kept, err: %v", err)
+	}
+}
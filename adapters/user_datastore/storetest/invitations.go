@@ -0,0 +1,114 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testInvitations(t *testing.T, store models.UserDatastore) {
+	accountID, otherAccountID := uuid.New().String(), uuid.New().String()
+	now := time.Now().Truncate(time.Second)
+	newInvitation := func(accountID, name string, createdAt time.Time) models.Invitation {
+		return models.Invitation{
+			ID:        uuid.New().String(),
+			AccountID: accountID,
+			Name:      name,
+			RoleID:    models.UserManagerRole,
+			InvitedBy: uuid.New().String(),
+			TokenID:   uuid.New().String(),
+			ExpiresAt: createdAt.Add(time.Hour),
+			CreatedAt: createdAt,
+		}
+	}
+	second := newInvitation(accountID, "second", now)
+	first := newInvitation(accountID, "first", now.Add(-time.Minute))
+	other := newInvitation(otherAccountID, "other", now)
+	for _, invitation := range []models.Invitation{second, first, other} {
+		if err := store.SaveInvitation(ctx, invitation); err != nil {
+			t.Fatalf("SaveInvitation failed: %v", err)
+		}
+	}
+
+	invitations, err := store.GetInvitations(ctx, accountID)
+	if err != nil {
+		t.Fatalf("GetInvitations failed: %v", err)
+	}
+	if !reflect.DeepEqual(invitations, []models.Invitation{first, second}) {
+		t.Errorf("Expected invitations %+v but were %+v", []models.Invitation{first, second}, invitations)
+	}
+
+	_, err = store.GetInvitation(ctx, otherAccountID, first.ID)
+	expectError(t, err, models.ErrInvitationNotFound)
+
+	resent := first
+	resent.TokenID = uuid.New().String()
+	resent.ExpiresAt = now.Add(2 * time.Hour)
+	// only token and expiry change
+	changed := resent
+	changed.Name = "changed"
+	if err := store.UpdateInvitation(ctx, changed); err != nil {
+		t.Fatalf("UpdateInvitation failed: %v", err)
+	}
+	invitation, err := store.GetInvitation(ctx, accountID, first.ID)
+	if err != nil {
+		t.Fatalf("GetInvitation failed: %v", err)
+	}
+	if !reflect.DeepEqual(*invitation, resent) {
+		t.Errorf("Expected invitation %+v but was %+v", resent, *invitation)
+	}
+	changed.AccountID = otherAccountID
+	expectError(t, store.UpdateInvitation(ctx, changed), models.ErrInvitationNotFound)
+
+	// token replaced by resending can't be used
+	expectError(t, store.UseInvitation(ctx, accountID, first.ID, first.TokenID), models.ErrInvitationNotFound)
+	expectError(t, store.UseInvitation(ctx, otherAccountID, second.ID, second.TokenID), models.ErrInvitationNotFound)
+	if err := store.UseInvitation(ctx, accountID, second.ID, second.TokenID); err != nil {
+		t.Fatalf("UseInvitation failed: %v", err)
+	}
+	expectError(t, store.UseInvitation(ctx, accountID, second.ID, second.TokenID), models.ErrInvitationNotFound)
+
+	expectError(t, store.DeleteInvitation(ctx, otherAccountID, first.ID), models.ErrInvitationNotFound)
+	if err := store.DeleteInvitation(ctx, accountID, first.ID); err != nil {
+		t.Fatalf("DeleteInvitation failed: %v", err)
+	}
+	expectError(t, store.DeleteInvitation(ctx, accountID, first.ID), models.ErrInvitationNotFound)
+	_, err = store.GetInvitation(ctx, accountID, first.ID)
+	expectError(t, err, models.ErrInvitationNotFound)
+}
+
+func testDeleteRoleInvitations(t *testing.T, store models.UserDatastore) {
+	accountID, otherAccountID := uuid.New().String(), uuid.New().String()
+	newInvitation := func(accountID string, roleID int) models.Invitation {
+		invitation := models.Invitation{
+			ID:        uuid.New().String(),
+			AccountID: accountID,
+			Name:      "invitee",
+			RoleID:    roleID,
+			InvitedBy: uuid.New().String(),
+			TokenID:   uuid.New().String(),
+			ExpiresAt: time.Now().Add(time.Hour),
+			CreatedAt: time.Now(),
+		}
+		if err := store.SaveInvitation(ctx, invitation); err != nil {
+			t.Fatalf("SaveInvitation failed: %v", err)
+		}
+		return invitation
+	}
+	revoked := newInvitation(accountID, models.CustomRoleMinID)
+	kept := newInvitation(accountID, models.UserRole)
+	other := newInvitation(otherAccountID, models.CustomRoleMinID)
+
+	if err := store.DeleteRoleInvitations(ctx, accountID, models.CustomRoleMinID); err != nil {
+		t.Fatalf("DeleteRoleInvitations failed: %v", err)
+	}
+	_, err := store.GetInvitation(ctx, accountID, revoked.ID)
+	expectError(t, err, models.ErrInvitationNotFound)
+	for _, invitation := range []models.Invitation{kept, other} {
+		if _, err := store.GetInvitation(ctx, invitation.AccountID, invitation.ID); err != nil {
+			t.Errorf("Expected invitation of other role or account to be kept but was `%v`", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testOwnershipTransfers(t *testing.T, store models.UserDatastore) {
+	accountID := uuid.New().String()
+	if _, err := store.GetOwnershipTransfer(ctx, accountID); err != models.ErrOwnershipTransferNotFound {
+		t.Errorf("Expected ErrOwnershipTransferNotFound but was `%v`", err)
+	}
+
+	first := models.OwnershipTransfer{
+		AccountID:  accountID,
+		FromUserID: uuid.New().String(),
+		ToUserID:   uuid.New().String(),
+		ExpiresAt:  time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	second := first
+	second.ToUserID = uuid.New().String()
+	second.KeepOwnership = true
+	for _, transfer := range []models.OwnershipTransfer{first, second} {
+		if err := store.SaveOwnershipTransfer(ctx, transfer); err != nil {
+			t.Fatalf("SaveOwnershipTransfer failed: %v", err)
+		}
+	}
+
+	// a new transfer replaces the previous one
+	transfer, err := store.GetOwnershipTransfer(ctx, accountID)
+	if err != nil {
+		t.Fatalf("GetOwnershipTransfer failed: %v", err)
+	}
+	if transfer.AccountID != accountID || transfer.FromUserID != second.FromUserID ||
+		transfer.ToUserID != second.ToUserID || !transfer.KeepOwnership || !transfer.ExpiresAt.Equal(second.ExpiresAt) {
+		t.Errorf("Expected transfer %+v but was %+v", second, transfer)
+	}
+
+	// the replaced transfer can't be used, the pending one only once
+	err = store.UseOwnershipTransfer(ctx, first)
+	expectError(t, err, models.ErrOwnershipTransferNotFound)
+	if err := store.UseOwnershipTransfer(ctx, second); err != nil {
+		t.Fatalf("UseOwnershipTransfer failed: %v", err)
+	}
+	err = store.UseOwnershipTransfer(ctx, second)
+	expectError(t, err, models.ErrOwnershipTransferNotFound)
+
+	if err := store.SaveOwnershipTransfer(ctx, first); err != nil {
+		t.Fatalf("SaveOwnershipTransfer failed: %v", err)
+	}
+	if err := store.DeleteOwnershipTransfer(ctx, accountID); err != nil {
+		t.Fatalf("DeleteOwnershipTransfer failed: %v", err)
+	}
+	if err := store.DeleteOwnershipTransfer(ctx, accountID); err != models.ErrOwnershipTransferNotFound {
+		t.Errorf("Expected deleted transfer not to be found but was `%v`", err)
+	}
+}
+
+func testLastOwner(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	demoted := *owner
+	demoted.RoleID = models.AdminRole
+	_, err := store.UpdateUser(ctx, demoted)
+	expectError(t, err, models.ErrLastOwner)
+	err = store.DeleteUser(ctx, owner.AccountID, owner.ID)
+	expectError(t, err, models.ErrLastOwner)
+
+	renamed := *owner
+	renamed.Username = "renamed owner"
+	if _, err := store.UpdateUser(ctx, renamed); err != nil {
+		t.Errorf("Expected last owner to be renamed but was `%v`", err)
+	}
+
+	second := newUser(t, store, owner.AccountID)
+	second.RoleID = models.OwnerRole
+	if _, err := store.UpdateUser(ctx, *second); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if count, err := store.CountUsersWithRole(ctx, owner.AccountID, models.OwnerRole); err != nil || count != 2 {
+		t.Errorf("Expected 2 owners but was %d, err: %v", count, err)
+	}
+	if err := store.DeleteUser(ctx, owner.AccountID, owner.ID); err != nil {
+		t.Errorf("Expected one of owners to be deleted but was `%v`", err)
+	}
+	err = store.DeleteUser(ctx, owner.AccountID, second.ID)
+	expectError(t, err, models.ErrLastOwner)
+}
+
+func testConcurrentOwnerDemotions(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	second := newUser(t, store, owner.AccountID)
+	second.RoleID = models.OwnerRole
+	if _, err := store.UpdateUser(ctx, *second); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, user := range []models.User{*owner, *second} {
+		wg.Add(1)
+		go func(i int, user models.User) {
+			defer wg.Done()
+			user.RoleID = models.AdminRole
+			_, errs[i] = store.UpdateUser(ctx, user)
+		}(i, user)
+	}
+	wg.Wait()
+
+	if count, err := store.CountUsersWithRole(ctx, owner.AccountID, models.OwnerRole); err != nil || count != 1 {
+		t.Errorf("Expected one owner to remain but was %d, errs: %v, err: %v", count, errs, err)
+	}
+}
@@ -0,0 +1,45 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func testPasswordResetTokens(t *testing.T, store models.UserDatastore) {
+	accountID, userID := uuid.New().String(), uuid.New().String()
+	first := models.PasswordResetToken{
+		TokenHash: uuid.New().String() + uuid.New().String()[:28],
+		UserID:    userID,
+		AccountID: accountID,
+		ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	second := first
+	second.TokenHash = uuid.New().String() + uuid.New().String()[:28]
+
+	for _, token := range []models.PasswordResetToken{first, second} {
+		if err := store.SavePasswordResetToken(ctx, token); err != nil {
+			t.Fatalf("SavePasswordResetToken failed: %v", err)
+		}
+	}
+
+	// a new token replaces the previous one
+	_, err := store.UsePasswordResetToken(ctx, first.TokenHash)
+	if err != models.ErrPasswordResetTokenNotFound {
+		t.Errorf("Expected replaced token not to be found but was `%v`", err)
+	}
+
+	token, err := store.UsePasswordResetToken(ctx, second.TokenHash)
+	if err != nil {
+		t.Fatalf("UsePasswordResetToken failed: %v", err)
+	}
+	if token.UserID != userID || token.AccountID != accountID || !token.ExpiresAt.Equal(second.ExpiresAt) {
+		t.Errorf("Expected token %+v but was %+v", second, token)
+	}
+
+	_, err = store.UsePasswordResetToken(ctx, second.TokenHash)
+	if err != models.ErrPasswordResetTokenNotFound {
+		t.Errorf("Expected used token not to be found but was `%v`", err)
+	}
+}
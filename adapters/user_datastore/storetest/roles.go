@@ -0,0 +1,71 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"reflect"
+	"testing"
+)
+
+func testRoles(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	other := newAccount(t, store)
+
+	dietitian, err := store.SaveRole(ctx, models.Role{AccountID: owner.AccountID, Name: "dietitian",
+		Permissions: []string{"meals:read:any", "meals:write:any"}})
+	if err != nil {
+		t.Fatalf("SaveRole failed: %v", err)
+	}
+	auditor, err := store.SaveRole(ctx, models.Role{AccountID: owner.AccountID, Name: "auditor", Permissions: []string{}})
+	if err != nil {
+		t.Fatalf("SaveRole failed: %v", err)
+	}
+	if dietitian.ID != models.CustomRoleMinID || auditor.ID != models.CustomRoleMinID+1 {
+		t.Errorf("Expected role IDs %d and %d but were %d and %d",
+			models.CustomRoleMinID, models.CustomRoleMinID+1, dietitian.ID, auditor.ID)
+	}
+	_, err = store.SaveRole(ctx, models.Role{AccountID: owner.AccountID, Name: "dietitian", Permissions: []string{}})
+	expectError(t, err, models.ErrRoleAlreadyExists)
+	// role names and IDs are per account
+	otherRole, err := store.SaveRole(ctx, models.Role{AccountID: other.AccountID, Name: "dietitian", Permissions: []string{}})
+	if err != nil {
+		t.Fatalf("SaveRole failed: %v", err)
+	}
+	if otherRole.ID != models.CustomRoleMinID {
+		t.Errorf("Expected role ID %d but was %d", models.CustomRoleMinID, otherRole.ID)
+	}
+
+	roles, err := store.GetRoles(ctx, owner.AccountID)
+	if err != nil {
+		t.Fatalf("GetRoles failed: %v", err)
+	}
+	if !reflect.DeepEqual(roles, []models.Role{*dietitian, *auditor}) {
+		t.Errorf("Expected roles %+v but was %+v", []models.Role{*dietitian, *auditor}, roles)
+	}
+
+	auditor.Permissions = []string{"meals:read:any", "users:read"}
+	if _, err := store.UpdateRole(ctx, *auditor); err != nil {
+		t.Fatalf("UpdateRole failed: %v", err)
+	}
+	role, err := store.GetRole(ctx, owner.AccountID, auditor.ID)
+	if err != nil {
+		t.Fatalf("GetRole failed: %v", err)
+	}
+	if !reflect.DeepEqual(role, auditor) {
+		t.Errorf("Expected role %+v but was %+v", auditor, role)
+	}
+	auditor.Name = dietitian.Name
+	_, err = store.UpdateRole(ctx, *auditor)
+	expectError(t, err, models.ErrRoleAlreadyExists)
+	_, err = store.UpdateRole(ctx, models.Role{AccountID: other.AccountID, ID: auditor.ID, Name: "auditor"})
+	expectError(t, err, models.ErrRoleNotFound)
+
+	err = store.DeleteRole(ctx, other.AccountID, auditor.ID)
+	expectError(t, err, models.ErrRoleNotFound)
+	if err := store.DeleteRole(ctx, owner.AccountID, auditor.ID); err != nil {
+		t.Fatalf("DeleteRole failed: %v", err)
+	}
+	_, err = store.GetRole(ctx, owner.AccountID, auditor.ID)
+	expectError(t, err, models.ErrRoleNotFound)
+	err = store.DeleteRole(ctx, owner.AccountID, auditor.ID)
+	expectError(t, err, models.ErrRoleNotFound)
+}
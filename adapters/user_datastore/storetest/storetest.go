@@ -0,0 +1,837 @@
+// Package storetest provides the contract tests which every models.UserDatastore implementation has to pass
+package storetest
+
+import (
+	"calories-counter/common"
+	"calories-counter/models"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var ctx = context.Background()
+
+// testPassword is bcrypt hash of "Xyz123"
+var testPassword = models.Password{
+	Hash:      "$2a$04$ph6NWiB2ldXhHRgrukHsZOnc3n/HQRvblrkF/mokshnvMTk57uFA6",
+	Algorithm: "bcrypt",
+	Cost:      4,
+}
+
+// Factory returns a datastore ready to use, it is called once per test.
+// Stores may share data between calls, tests create their own accounts with unique names.
+type Factory func(t *testing.T) models.UserDatastore
+
+// Run runs the whole suite against datastores created by newStore
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store models.UserDatastore)
+	}{
+		{"SaveRootUser", testSaveRootUser},
+		{"GetAccount", testGetAccount},
+		{"GetUser", testGetUser},
+		{"UpdateUserPassword", testUpdateUserPassword},
+		{"SaveUser", testSaveUser},
+		{"UpdateUser", testUpdateUser},
+		{"DeleteUser", testDeleteUser},
+		{"DeleteUserData", testDeleteUserData},
+		{"DeleteAccount", testDeleteAccount},
+		{"PurgeAccount", testPurgeAccount},
+		{"ConcurrentRestoreAndPurge", testConcurrentRestoreAndPurge},
+		{"GetUsersPagination", testGetUsersPagination},
+		{"GetUsersFilter", testGetUsersFilter},
+		{"GetUsersSort", testGetUsersSort},
+		{"MealNotFound", testMealNotFound},
+		{"SaveMeal", testSaveMeal},
+		{"GetMealsPagination", testGetMealsPagination},
+		{"GetMealsOrder", testGetMealsOrder},
+		{"GetMealsFilter", testGetMealsFilter},
+		{"GetMealsSort", testGetMealsSort},
+		{"GetMealsCursor", testGetMealsCursor},
+		{"CaloriesDeficitAfterSaveMeal", testCaloriesDeficitAfterSaveMeal},
+		{"CaloriesDeficitAfterUpdateMeal", testCaloriesDeficitAfterUpdateMeal},
+		{"CaloriesDeficitAfterMealDateMove", testCaloriesDeficitAfterMealDateMove},
+		{"CaloriesDeficitAfterDeleteMeal", testCaloriesDeficitAfterDeleteMeal},
+		{"CaloriesDeficitAfterUpdateSettings", testCaloriesDeficitAfterUpdateSettings},
+		{"Settings", testSettings},
+		{"UpdateDailyTotal", testUpdateDailyTotal},
+		{"GetDailyTotals", testGetDailyTotals},
+		{"RefreshTokenRotation", testRefreshTokenRotation},
+		{"RefreshTokenFamilyRevocation", testRefreshTokenFamilyRevocation},
+		{"AccessTokenRevocation", testAccessTokenRevocation},
+		{"TOTPEnrollment", testTOTPEnrollment},
+		{"TOTPReplay", testTOTPReplay},
+		{"TwoFactorRoles", testTwoFactorRoles},
+		{"LoginFailures", testLoginFailures},
+		{"PasswordResetTokens", testPasswordResetTokens},
+		{"APIKeys", testAPIKeys},
+		{"Roles", testRoles},
+		{"Teams", testTeams},
+		{"OwnershipTransfers", testOwnershipTransfers},
+		{"LastOwner", testLastOwner},
+		{"ConcurrentOwnerDemotions", testConcurrentOwnerDemotions},
+		{"Invitations", testInvitations},
+		{"DeleteRoleInvitations", testDeleteRoleInvitations},
+		{"UnitOfWorkCommit", testUnitOfWorkCommit},
+		{"UnitOfWorkRollback", testUnitOfWorkRollback},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newStore(t))
+		})
+	}
+}
+
+// uniqueName returns valid username which does not collide with data left by previous runs
+func uniqueName(prefix string) string {
+	return prefix + "-" + uuid.New().String()[:8]
+}
+
+func newAccount(t *testing.T, store models.UserDatastore) *models.User {
+	t.Helper()
+	owner, err := store.SaveRootUser(ctx, uniqueName("account"), uniqueName("owner"), testPassword)
+	if err != nil {
+		t.Fatalf("SaveRootUser failed: %v", err)
+	}
+	return owner
+}
+
+func newUser(t *testing.T, store models.UserDatastore, accountID string) *models.User {
+	t.Helper()
+	user, err := store.SaveUser(ctx, accountID, uniqueName("user"), testPassword, models.UserRole)
+	if err != nil {
+		t.Fatalf("SaveUser failed: %v", err)
+	}
+	return user
+}
+
+// saveMeal saves meal and updates its daily total in one unit of work, the way the server does
+func saveMeal(t *testing.T, store models.UserDatastore, userID, date, time string, calories int) *models.Meal {
+	t.Helper()
+	var meal *models.Meal
+	err := store.Do(ctx, func(ctx context.Context, repos models.Repositories) error {
+		var err error
+		meal, err = repos.Meals.SaveMeal(ctx, userID, models.Meal{Date: date, Time: time, Name: "meal", Calories: calories})
+		if err != nil {
+			return err
+		}
+		meal.CaloriesDeficit, err = repos.DailyTotals.UpdateDailyTotal(ctx, userID, date)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("SaveMeal failed: %v", err)
+	}
+	return meal
+}
+
+// updateMeal updates meal and daily totals of its old and new date in one unit of work
+func updateMeal(t *testing.T, store models.UserDatastore, userID string, meal models.Meal, oldDate string) *models.Meal {
+	t.Helper()
+	var updated *models.Meal
+	err := store.Do(ctx, func(ctx context.Context, repos models.Repositories) error {
+		var err error
+		updated, err = repos.Meals.UpdateMeal(ctx, userID, meal)
+		if err != nil {
+			return err
+		}
+		updated.CaloriesDeficit, err = repos.DailyTotals.UpdateDailyTotal(ctx, userID, meal.Date)
+		if err != nil || oldDate == meal.Date {
+			return err
+		}
+		_, err = repos.DailyTotals.UpdateDailyTotal(ctx, userID, oldDate)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("UpdateMeal failed: %v", err)
+	}
+	return updated
+}
+
+// deleteMeal deletes meal and updates its daily total in one unit of work
+func deleteMeal(t *testing.T, store models.UserDatastore, userID string, meal models.Meal) {
+	t.Helper()
+	err := store.Do(ctx, func(ctx context.Context, repos models.Repositories) error {
+		err := repos.Meals.DeleteMeal(ctx, userID, meal.ID)
+		if err != nil {
+			return err
+		}
+		_, err = repos.DailyTotals.UpdateDailyTotal(ctx, userID, meal.Date)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("DeleteMeal failed: %v", err)
+	}
+}
+
+func updateSettings(t *testing.T, store models.UserDatastore, userID string, expected int) {
+	t.Helper()
+	_, err := store.UpdateSettings(ctx, userID, models.Settings{ExpectedDailyCalories: expected})
+	if err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+}
+
+func expectError(t *testing.T, err, expected error) {
+	t.Helper()
+	if err != expected {
+		t.Errorf("Expected error `%v` but was `%v`", expected, err)
+	}
+}
+
+// expectBadRequest checks err is a bad request error wrapping sentinel, e.g. positioned models.ErrInvalidFilter
+func expectBadRequest(t *testing.T, err error, sentinel error) {
+	t.Helper()
+	apiErr, ok := err.(common.ApiErr)
+	if !ok || apiErr.Code != http.StatusBadRequest || !errors.Is(err, sentinel) {
+		t.Errorf("Expected `%v` error but was `%v`", sentinel, err)
+	}
+}
+
+func expectDeficit(t *testing.T, store models.UserDatastore, userID, mealID string, expected bool) {
+	t.Helper()
+	meal, err := store.GetMeal(ctx, userID, mealID)
+	if err != nil {
+		t.Fatalf("GetMeal failed: %v", err)
+	}
+	if meal.CaloriesDeficit != expected {
+		t.Errorf("Expected calories deficit of meal on %s to be %t but was %t", meal.Date, expected, meal.CaloriesDeficit)
+	}
+}
+
+func testSaveRootUser(t *testing.T, store models.UserDatastore) {
+	accountName, username := uniqueName("account"), uniqueName("owner")
+	owner, err := store.SaveRootUser(ctx, accountName, username, testPassword)
+	if err != nil {
+		t.Fatalf("SaveRootUser failed: %v", err)
+	}
+	if owner.ID == "" || owner.AccountID == "" || owner.Username != username || owner.RoleID != models.OwnerRole {
+		t.Errorf("Unexpected owner %+v", owner)
+	}
+
+	_, err = store.SaveRootUser(ctx, accountName, uniqueName("owner"), testPassword)
+	expectError(t, err, models.ErrAccountAlreadyExists)
+
+	// owners of different accounts can have the same username
+	_, err = store.SaveRootUser(ctx, uniqueName("account"), username, testPassword)
+	if err != nil {
+		t.Errorf("SaveRootUser of another account failed: %v", err)
+	}
+}
+
+func testGetAccount(t *testing.T, store models.UserDatastore) {
+	accountName := uniqueName("account")
+	owner, err := store.SaveRootUser(ctx, accountName, uniqueName("owner"), testPassword)
+	if err != nil {
+		t.Fatalf("SaveRootUser failed: %v", err)
+	}
+	expected := models.Account{ID: owner.AccountID, Name: accountName}
+
+	account, err := store.GetAccount(ctx, owner.AccountID)
+	if err != nil {
+		t.Fatalf("GetAccount failed: %v", err)
+	}
+	if *account != expected {
+		t.Errorf("Expected account %+v but was %+v", expected, account)
+	}
+	account, err = store.GetAccountByName(ctx, accountName)
+	if err != nil {
+		t.Fatalf("GetAccountByName failed: %v", err)
+	}
+	if *account != expected {
+		t.Errorf("Expected account %+v but was %+v", expected, account)
+	}
+
+	_, err = store.GetAccount(ctx, uuid.New().String())
+	expectError(t, err, models.ErrAccountNotFound)
+	_, err = store.GetAccountByName(ctx, uniqueName("account"))
+	expectError(t, err, models.ErrAccountNotFound)
+}
+
+func testUpdateUserPassword(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	other := newAccount(t, store)
+	user := newUser(t, store, owner.AccountID)
+
+	newPassword := models.Password{Hash: "$2a$10$abc", Algorithm: "bcrypt", Cost: 10}
+	err := store.UpdateUserPassword(ctx, owner.AccountID, user.ID, newPassword)
+	if err != nil {
+		t.Fatalf("UpdateUserPassword failed: %v", err)
+	}
+	pass, err := store.GetUserPassword(ctx, owner.AccountID, user.Username)
+	if err != nil || *pass != newPassword {
+		t.Errorf("Expected password to be %+v but was %+v, err: %v", newPassword, pass, err)
+	}
+	pass, err = store.GetUserPassword(ctx, owner.AccountID, owner.Username)
+	if err != nil || *pass != testPassword {
+		t.Errorf("Expected password of other user to stay %+v but was %+v, err: %v", testPassword, pass, err)
+	}
+
+	err = store.UpdateUserPassword(ctx, other.AccountID, user.ID, newPassword)
+	expectError(t, err, models.ErrUserNotFound)
+}
+
+func testGetUser(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	other := newAccount(t, store)
+
+	user, err := store.GetUser(ctx, owner.AccountID, owner.Username)
+	if err != nil || *user != *owner {
+		t.Errorf("Expected GetUser to return %+v but was %+v, err: %v", owner, user, err)
+	}
+	user, err = store.GetUserById(ctx, owner.AccountID, owner.ID)
+	if err != nil || *user != *owner {
+		t.Errorf("Expected GetUserById to return %+v but was %+v, err: %v", owner, user, err)
+	}
+	pass, err := store.GetUserPassword(ctx, owner.AccountID, owner.Username)
+	if err != nil || pass == nil || *pass != testPassword {
+		t.Errorf("Expected GetUserPassword to return %+v but was %+v, err: %v", testPassword, pass, err)
+	}
+
+	_, err = store.GetUser(ctx, owner.AccountID, "unknown")
+	expectError(t, err, models.ErrUserNotFound)
+	_, err = store.GetUserPassword(ctx, owner.AccountID, "unknown")
+	expectError(t, err, models.ErrUserNotFound)
+	_, err = store.GetUserById(ctx, other.AccountID, owner.ID)
+	expectError(t, err, models.ErrUserNotFound)
+}
+
+func testSaveUser(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	user, err := store.SaveUser(ctx, owner.AccountID, "manager", testPassword, models.UserManagerRole)
+	if err != nil {
+		t.Fatalf("SaveUser failed: %v", err)
+	}
+	if user.ID == "" || user.AccountID != owner.AccountID || user.Username != "manager" || user.RoleID != models.UserManagerRole {
+		t.Errorf("Unexpected user %+v", user)
+	}
+
+	_, err = store.SaveUser(ctx, owner.AccountID, "manager", testPassword, models.UserRole)
+	expectError(t, err, models.ErrUserAlreadyExists)
+
+	// usernames are unique only within an account
+	other := newAccount(t, store)
+	_, err = store.SaveUser(ctx, other.AccountID, "manager", testPassword, models.UserRole)
+	if err != nil {
+		t.Errorf("SaveUser in other account failed: %v", err)
+	}
+}
+
+func testUpdateUser(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	user := newUser(t, store, owner.AccountID)
+
+	user.Username = "renamed"
+	user.RoleID = models.AdminRole
+	_, err := store.UpdateUser(ctx, *user)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	updated, err := store.GetUserById(ctx, owner.AccountID, user.ID)
+	if err != nil || *updated != *user {
+		t.Errorf("Expected updated user to be %+v but was %+v, err: %v", user, updated, err)
+	}
+
+	user.Username = owner.Username
+	_, err = store.UpdateUser(ctx, *user)
+	expectError(t, err, models.ErrUserAlreadyExists)
+}
+
+func testDeleteUser(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	user := newUser(t, store, owner.AccountID)
+	other := newAccount(t, store)
+
+	err := store.DeleteUser(ctx, other.AccountID, user.ID)
+	expectError(t, err, models.ErrUserNotFound)
+
+	err = store.DeleteUser(ctx, owner.AccountID, user.ID)
+	if err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	_, err = store.GetUserById(ctx, owner.AccountID, user.ID)
+	expectError(t, err, models.ErrUserNotFound)
+	err = store.DeleteUser(ctx, owner.AccountID, user.ID)
+	expectError(t, err, models.ErrUserNotFound)
+}
+
+func testGetUsersPagination(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	for i := 0; i < 4; i++ {
+		newUser(t, store, owner.AccountID)
+	}
+	newUser(t, store, newAccount(t, store).AccountID)
+
+	testCases := []struct {
+		page, perPage, expectedItems int
+	}{
+		{page: 0, perPage: 2, expectedItems: 2},
+		{page: 2, perPage: 2, expectedItems: 1},
+		{page: 3, perPage: 2, expectedItems: 0},
+		{page: 0, perPage: 10, expectedItems: 5},
+	}
+	for _, tc := range testCases {
+		users, err := store.GetUsers(ctx, owner.AccountID, models.ListParams{Page: tc.page, PerPage: tc.perPage})
+		if err != nil {
+			t.Fatalf("GetUsers failed: %v", err)
+		}
+		if users.Total != 5 || len(users.Items) != tc.expectedItems {
+			t.Errorf("Expected page %d of %d to have %d of 5 users but had %d of %d",
+				tc.page, tc.perPage, tc.expectedItems, len(users.Items), users.Total)
+		}
+	}
+}
+
+func testGetUsersSort(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	for _, name := range []string{"bob", "carol", "alice"} {
+		_, err := store.SaveUser(ctx, owner.AccountID, name, testPassword, models.UserManagerRole)
+		if err != nil {
+			t.Fatalf("SaveUser failed: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		sort     string
+		expected []string
+	}{
+		{sort: "", expected: []string{"alice", "bob", "carol", owner.Username}},
+		{sort: "-role_id,-username", expected: []string{owner.Username, "carol", "bob", "alice"}},
+	}
+	for _, tc := range testCases {
+		users, err := store.GetUsers(ctx, owner.AccountID, models.ListParams{PerPage: 10, Sort: tc.sort})
+		if err != nil {
+			t.Fatalf("GetUsers failed: %v", err)
+		}
+		if len(users.Items) != len(tc.expected) {
+			t.Fatalf("Expected %d users but was %d", len(tc.expected), len(users.Items))
+		}
+		for i, u := range users.Items {
+			if u.Username != tc.expected[i] {
+				t.Errorf("Expected user %d sorted by `%s` to be %s but was %s", i, tc.sort, tc.expected[i], u.Username)
+			}
+		}
+	}
+
+	_, err := store.GetUsers(ctx, owner.AccountID, models.ListParams{PerPage: 10, Sort: "password"})
+	expectBadRequest(t, err, models.ErrInvalidSort)
+}
+
+func testGetUsersFilter(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	user := newUser(t, store, owner.AccountID)
+
+	users, err := store.GetUsers(ctx, owner.AccountID, models.ListParams{PerPage: 10, Filter: "role_id eq 0"})
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	if users.Total != 1 || len(users.Items) != 1 || users.Items[0] != *user {
+		t.Errorf("Expected filtered users to contain only %+v but was %+v", user, users)
+	}
+}
+
+func testMealNotFound(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	meal := saveMeal(t, store, owner.ID, "2020-01-01", "10:00:00", 100)
+	other := newAccount(t, store)
+
+	_, err := store.GetMeal(ctx, owner.ID, "unknown")
+	expectError(t, err, models.ErrMealNotFound)
+	_, err = store.GetMeal(ctx, other.ID, meal.ID)
+	expectError(t, err, models.ErrMealNotFound)
+	_, err = store.UpdateMeal(ctx, other.ID, *meal)
+	expectError(t, err, models.ErrMealNotFound)
+	err = store.DeleteMeal(ctx, other.ID, meal.ID)
+	expectError(t, err, models.ErrMealNotFound)
+}
+
+func testSaveMeal(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	meal := models.Meal{Date: "2020-01-01", Time: "10:11:12", Name: "chicken", Calories: 100}
+	saved, err := store.SaveMeal(ctx, owner.ID, meal)
+	if err != nil {
+		t.Fatalf("SaveMeal failed: %v", err)
+	}
+	if saved.ID == "" {
+		t.Errorf("Expected saved meal to have an ID")
+	}
+
+	meal.ID = saved.ID
+	fetched, err := store.GetMeal(ctx, owner.ID, saved.ID)
+	if err != nil || *fetched != meal {
+		t.Errorf("Expected GetMeal to return %+v but was %+v, err: %v", meal, fetched, err)
+	}
+}
+
+func testGetMealsPagination(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	for i := 0; i < 5; i++ {
+		saveMeal(t, store, owner.ID, "2020-01-01", "10:00:00", 100)
+	}
+	saveMeal(t, store, newAccount(t, store).ID, "2020-01-01", "10:00:00", 100)
+
+	testCases := []struct {
+		page, perPage, expectedItems int
+	}{
+		{page: 0, perPage: 2, expectedItems: 2},
+		{page: 2, perPage: 2, expectedItems: 1},
+		{page: 3, perPage: 2, expectedItems: 0},
+		{page: 0, perPage: 10, expectedItems: 5},
+	}
+	for _, tc := range testCases {
+		meals, err := store.GetMeals(ctx, owner.ID, models.ListParams{Page: tc.page, PerPage: tc.perPage})
+		if err != nil {
+			t.Fatalf("GetMeals failed: %v", err)
+		}
+		if meals.Total != 5 || len(meals.Items) != tc.expectedItems {
+			t.Errorf("Expected page %d of %d to have %d of 5 meals but had %d of %d",
+				tc.page, tc.perPage, tc.expectedItems, len(meals.Items), meals.Total)
+		}
+	}
+}
+
+func testGetMealsOrder(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	saveMeal(t, store, owner.ID, "2020-01-02", "08:00:00", 100)
+	saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 100)
+	saveMeal(t, store, owner.ID, "2020-01-01", "20:00:00", 100)
+
+	meals, err := store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 10})
+	if err != nil {
+		t.Fatalf("GetMeals failed: %v", err)
+	}
+	expected := []string{"2020-01-01 20:00:00", "2020-01-01 08:00:00", "2020-01-02 08:00:00"}
+	for i, m := range meals.Items {
+		if m.Date+" "+m.Time != expected[i] {
+			t.Errorf("Expected meal %d to be eaten at %s but was %s %s", i, expected[i], m.Date, m.Time)
+		}
+	}
+}
+
+func testGetMealsSort(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 20)
+	saveMeal(t, store, owner.ID, "2020-01-02", "08:00:00", 30)
+	saveMeal(t, store, owner.ID, "2020-01-01", "12:00:00", 10)
+	saveMeal(t, store, owner.ID, "2020-01-02", "12:00:00", 25)
+
+	testCases := []struct {
+		sort     string
+		expected []string
+	}{
+		{
+			sort:     "-date,calories",
+			expected: []string{"2020-01-02 12:00:00", "2020-01-02 08:00:00", "2020-01-01 12:00:00", "2020-01-01 08:00:00"},
+		},
+		{
+			sort:     "-time, calories",
+			expected: []string{"2020-01-01 12:00:00", "2020-01-02 12:00:00", "2020-01-01 08:00:00", "2020-01-02 08:00:00"},
+		},
+	}
+	for _, tc := range testCases {
+		meals, err := store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 10, Sort: tc.sort})
+		if err != nil {
+			t.Fatalf("GetMeals failed: %v", err)
+		}
+		if len(meals.Items) != len(tc.expected) {
+			t.Fatalf("Expected %d meals but was %d", len(tc.expected), len(meals.Items))
+		}
+		for i, m := range meals.Items {
+			if m.Date+" "+m.Time != tc.expected[i] {
+				t.Errorf("Expected meal %d sorted by %s to be eaten at %s but was %s %s",
+					i, tc.sort, tc.expected[i], m.Date, m.Time)
+			}
+		}
+	}
+
+	for _, sort := range []string{"calories_deficit", "unknown", "date,,time", "date,-date", "date; DROP TABLE users_meals"} {
+		_, err := store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 10, Sort: sort})
+		expectBadRequest(t, err, models.ErrInvalidSort)
+	}
+}
+
+func testGetMealsCursor(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	saveMeal(t, store, owner.ID, "2020-01-02", "08:00:00", 10)
+	saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 10)
+	saveMeal(t, store, owner.ID, "2020-01-01", "20:00:00", 10)
+	saveMeal(t, store, owner.ID, "2020-01-01", "20:00:00", 10)
+	saveMeal(t, store, owner.ID, "2020-01-03", "12:00:00", 10)
+
+	params := models.ListParams{PerPage: 2}
+	var seen []models.Meal
+	for i := 0; ; i++ {
+		meals, err := store.GetMeals(ctx, owner.ID, params)
+		if err != nil {
+			t.Fatalf("GetMeals failed: %v", err)
+		}
+		seen = append(seen, meals.Items...)
+		if i == 0 {
+			// meals added before the cursor do not shift the following pages
+			saveMeal(t, store, owner.ID, "2019-12-31", "12:00:00", 10)
+		}
+		if meals.NextCursor == "" {
+			break
+		}
+		if i > 5 {
+			t.Fatalf("Expected cursor pagination to end")
+		}
+		params.Cursor = meals.NextCursor
+	}
+
+	expected := []string{"2020-01-01 20:00:00", "2020-01-01 20:00:00", "2020-01-01 08:00:00", "2020-01-02 08:00:00", "2020-01-03 12:00:00"}
+	if len(seen) != len(expected) {
+		t.Fatalf("Expected %d meals but was %d", len(expected), len(seen))
+	}
+	for i, m := range seen {
+		if m.Date+" "+m.Time != expected[i] {
+			t.Errorf("Expected meal %d to be eaten at %s but was %s %s", i, expected[i], m.Date, m.Time)
+		}
+	}
+	if seen[0].ID == seen[1].ID {
+		t.Errorf("Expected meals eaten at the same time to be returned once")
+	}
+
+	meals, err := store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 2, Sort: "calories"})
+	if err != nil {
+		t.Fatalf("GetMeals failed: %v", err)
+	}
+	if meals.NextCursor != "" {
+		t.Errorf("Expected no cursor for sorted meals but was %s", meals.NextCursor)
+	}
+
+	_, err = store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 2, Cursor: "not a cursor"})
+	expectError(t, err, models.ErrInvalidCursor)
+	_, err = store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 2, Cursor: params.Cursor, Sort: "-date"})
+	expectError(t, err, models.ErrCursorWithSort)
+}
+
+func testGetMealsFilter(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 5)
+	saveMeal(t, store, owner.ID, "2020-01-01", "12:00:00", 15)
+	saveMeal(t, store, owner.ID, "2020-01-01", "18:00:00", 25)
+	saveMeal(t, store, owner.ID, "2020-01-02", "08:00:00", 25)
+
+	meals, err := store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 10, Filter: "(date eq '2020-01-01') AND ((calories gt 20) OR (calories lt 10))"})
+	if err != nil {
+		t.Fatalf("GetMeals failed: %v", err)
+	}
+	if meals.Total != 2 || len(meals.Items) != 2 {
+		t.Errorf("Expected 2 meals to match the filter but was %d", meals.Total)
+	}
+
+	meals, err = store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 10, Filter: "calories ne 25 and not (time ge '12:00:00')"})
+	if err != nil {
+		t.Fatalf("GetMeals failed: %v", err)
+	}
+	if meals.Total != 1 || len(meals.Items) != 1 || meals.Items[0].Calories != 5 {
+		t.Errorf("Expected only 5 calories meal to match the filter but was %+v", meals.Items)
+	}
+
+	meals, err = store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 10, Filter: "name eq 'date eq ''2020-01-01'' OR 1=1'"})
+	if err != nil {
+		t.Fatalf("GetMeals failed: %v", err)
+	}
+	if meals.Total != 0 {
+		t.Errorf("Expected no meals to match the filter but was %d", meals.Total)
+	}
+
+	for _, f := range []string{
+		"calories gt 1; DROP TABLE users_meals",
+		"unknown eq 1",
+		"user_id eq '1'",
+		"calories eq '1'",
+		"date gt 20",
+		"(calories gt 1",
+	} {
+		_, err = store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 10, Filter: f})
+		expectBadRequest(t, err, models.ErrInvalidFilter)
+	}
+}
+
+func testCaloriesDeficitAfterSaveMeal(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	updateSettings(t, store, owner.ID, 100)
+
+	meal := saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 60)
+	if !meal.CaloriesDeficit {
+		t.Errorf("Expected saved meal to be in calories deficit")
+	}
+	meal = saveMeal(t, store, owner.ID, "2020-01-01", "12:00:00", 60)
+	if meal.CaloriesDeficit {
+		t.Errorf("Expected saved meal not to be in calories deficit")
+	}
+	expectDeficit(t, store, owner.ID, meal.ID, false)
+}
+
+func testCaloriesDeficitAfterUpdateMeal(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	updateSettings(t, store, owner.ID, 100)
+	first := saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 60)
+	second := saveMeal(t, store, owner.ID, "2020-01-01", "12:00:00", 60)
+
+	second.Calories = 20
+	updated := updateMeal(t, store, owner.ID, *second, second.Date)
+	if !updated.CaloriesDeficit {
+		t.Errorf("Expected updated meal to be in calories deficit")
+	}
+	expectDeficit(t, store, owner.ID, first.ID, true)
+}
+
+func testCaloriesDeficitAfterMealDateMove(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	updateSettings(t, store, owner.ID, 100)
+	first := saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 60)
+	second := saveMeal(t, store, owner.ID, "2020-01-01", "12:00:00", 60)
+	third := saveMeal(t, store, owner.ID, "2020-01-02", "12:00:00", 60)
+
+	second.Date = "2020-01-02"
+	updated := updateMeal(t, store, owner.ID, *second, first.Date)
+	if updated.CaloriesDeficit {
+		t.Errorf("Expected moved meal not to be in calories deficit")
+	}
+	expectDeficit(t, store, owner.ID, first.ID, true)
+	expectDeficit(t, store, owner.ID, third.ID, false)
+}
+
+func testCaloriesDeficitAfterDeleteMeal(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	updateSettings(t, store, owner.ID, 100)
+	first := saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 60)
+	second := saveMeal(t, store, owner.ID, "2020-01-01", "12:00:00", 60)
+
+	deleteMeal(t, store, owner.ID, *second)
+	_, err := store.GetMeal(ctx, owner.ID, second.ID)
+	expectError(t, err, models.ErrMealNotFound)
+	expectDeficit(t, store, owner.ID, first.ID, true)
+}
+
+func testCaloriesDeficitAfterUpdateSettings(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	first := saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 60)
+	second := saveMeal(t, store, owner.ID, "2020-01-02", "08:00:00", 120)
+	expectDeficit(t, store, owner.ID, first.ID, false)
+
+	updateSettings(t, store, owner.ID, 100)
+	expectDeficit(t, store, owner.ID, first.ID, true)
+	expectDeficit(t, store, owner.ID, second.ID, false)
+
+	updateSettings(t, store, owner.ID, 50)
+	expectDeficit(t, store, owner.ID, first.ID, false)
+}
+
+func testSettings(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	settings, err := store.GetSettings(ctx, owner.ID)
+	if err != nil || settings.ExpectedDailyCalories != 0 {
+		t.Errorf("Expected default settings but was %+v, err: %v", settings, err)
+	}
+
+	updateSettings(t, store, owner.ID, 2000)
+	updateSettings(t, store, owner.ID, 2500)
+	settings, err = store.GetSettings(ctx, owner.ID)
+	if err != nil || settings.ExpectedDailyCalories != 2500 {
+		t.Errorf("Expected 2500 expected daily calories but was %+v, err: %v", settings, err)
+	}
+}
+
+func testUpdateDailyTotal(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	updateSettings(t, store, owner.ID, 100)
+	meal := saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 60)
+
+	caloriesDeficit, err := store.UpdateDailyTotal(ctx, owner.ID, "2020-01-01")
+	if err != nil || !caloriesDeficit {
+		t.Errorf("Expected daily total to be in calories deficit, err: %v", err)
+	}
+	caloriesDeficit, err = store.UpdateDailyTotal(ctx, owner.ID, "2020-01-02")
+	if err != nil || !caloriesDeficit {
+		t.Errorf("Expected day without meals to be in calories deficit, err: %v", err)
+	}
+	expectDeficit(t, store, owner.ID, meal.ID, true)
+}
+
+func testGetDailyTotals(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	other := newUser(t, store, owner.AccountID)
+	updateSettings(t, store, owner.ID, 100)
+	saveMeal(t, store, owner.ID, "2020-01-03", "08:00:00", 120)
+	saveMeal(t, store, owner.ID, "2020-01-01", "08:00:00", 60)
+	saveMeal(t, store, owner.ID, "2020-01-01", "12:00:00", 30)
+	saveMeal(t, store, owner.ID, "2020-01-02", "08:00:00", 100)
+	saveMeal(t, store, other.ID, "2020-01-01", "08:00:00", 500)
+
+	totals, err := store.GetDailyTotals(ctx, owner.ID, "", "")
+	expected := []models.DailyTotal{
+		{Date: "2020-01-01", TotalCalories: 90, CaloriesDeficit: true},
+		{Date: "2020-01-02", TotalCalories: 100},
+		{Date: "2020-01-03", TotalCalories: 120},
+	}
+	if err != nil || !reflect.DeepEqual(totals, expected) {
+		t.Errorf("Expected %+v but was %+v, err: %v", expected, totals, err)
+	}
+
+	totals, err = store.GetDailyTotals(ctx, owner.ID, "2020-01-02", "2020-01-02")
+	if err != nil || !reflect.DeepEqual(totals, expected[1:2]) {
+		t.Errorf("Expected %+v but was %+v, err: %v", expected[1:2], totals, err)
+	}
+	totals, err = store.GetDailyTotals(ctx, owner.ID, "2020-01-02", "")
+	if err != nil || !reflect.DeepEqual(totals, expected[1:]) {
+		t.Errorf("Expected %+v but was %+v, err: %v", expected[1:], totals, err)
+	}
+	totals, err = store.GetDailyTotals(ctx, owner.ID, "", "2019-12-31")
+	if err != nil || len(totals) != 0 || totals == nil {
+		t.Errorf("Expected no totals but was %+v, err: %v", totals, err)
+	}
+}
+
+func testUnitOfWorkCommit(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	var meal *models.Meal
+	err := store.Do(ctx, func(ctx context.Context, repos models.Repositories) error {
+		_, err := repos.Settings.UpdateSettings(ctx, owner.ID, models.Settings{ExpectedDailyCalories: 100})
+		if err != nil {
+			return err
+		}
+		meal, err = repos.Meals.SaveMeal(ctx, owner.ID, models.Meal{Date: "2020-01-01", Time: "08:00:00", Name: "meal", Calories: 60})
+		if err != nil {
+			return err
+		}
+		_, err = repos.DailyTotals.UpdateDailyTotal(ctx, owner.ID, meal.Date)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+
+	expectDeficit(t, store, owner.ID, meal.ID, true)
+}
+
+func testUnitOfWorkRollback(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	errRollback := errors.New("rollback")
+	err := store.Do(ctx, func(ctx context.Context, repos models.Repositories) error {
+		_, err := repos.Meals.SaveMeal(ctx, owner.ID, models.Meal{Date: "2020-01-01", Time: "08:00:00", Name: "meal", Calories: 60})
+		if err != nil {
+			return err
+		}
+		_, err = repos.Users.SaveUser(ctx, owner.AccountID, "rollback", testPassword, models.UserRole)
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	expectError(t, err, errRollback)
+
+	meals, err := store.GetMeals(ctx, owner.ID, models.ListParams{PerPage: 10})
+	if err != nil || meals.Total != 0 {
+		t.Errorf("Expected meals to be rolled back but was %+v, err: %v", meals, err)
+	}
+	_, err = store.GetUser(ctx, owner.AccountID, "rollback")
+	expectError(t, err, models.ErrUserNotFound)
+}
@@ -0,0 +1,70 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"reflect"
+	"testing"
+)
+
+func testTeams(t *testing.T, store models.UserDatastore) {
+	owner := newAccount(t, store)
+	manager, err := store.SaveUser(ctx, owner.AccountID, uniqueName("manager"), testPassword, models.UserManagerRole)
+	if err != nil {
+		t.Fatalf("SaveUser failed: %v", err)
+	}
+	second, err := store.SaveUser(ctx, owner.AccountID, "b"+uniqueName("user"), testPassword, models.UserRole)
+	if err != nil {
+		t.Fatalf("SaveUser failed: %v", err)
+	}
+	first, err := store.SaveUser(ctx, owner.AccountID, "a"+uniqueName("user"), testPassword, models.UserRole)
+	if err != nil {
+		t.Fatalf("SaveUser failed: %v", err)
+	}
+
+	for _, user := range []*models.User{second, first, second} {
+		if err := store.AddTeamMember(ctx, owner.AccountID, manager.ID, user.ID); err != nil {
+			t.Fatalf("AddTeamMember failed: %v", err)
+		}
+	}
+	if err := store.AddTeamMember(ctx, owner.AccountID, owner.ID, first.ID); err != nil {
+		t.Fatalf("AddTeamMember failed: %v", err)
+	}
+
+	members, err := store.GetTeamMembers(ctx, owner.AccountID, manager.ID)
+	if err != nil {
+		t.Fatalf("GetTeamMembers failed: %v", err)
+	}
+	if !reflect.DeepEqual(members, []models.User{*first, *second}) {
+		t.Errorf("Expected members %+v but was %+v", []models.User{*first, *second}, members)
+	}
+	managerIDs, err := store.GetTeamManagers(ctx, owner.AccountID, first.ID)
+	if err != nil {
+		t.Fatalf("GetTeamManagers failed: %v", err)
+	}
+	if len(managerIDs) != 2 {
+		t.Errorf("Expected 2 managers but was %v", managerIDs)
+	}
+
+	other := newAccount(t, store)
+	err = store.RemoveTeamMember(ctx, other.AccountID, manager.ID, first.ID)
+	expectError(t, err, models.ErrTeamMemberNotFound)
+	if err := store.RemoveTeamMember(ctx, owner.AccountID, manager.ID, first.ID); err != nil {
+		t.Fatalf("RemoveTeamMember failed: %v", err)
+	}
+	err = store.RemoveTeamMember(ctx, owner.AccountID, manager.ID, first.ID)
+	expectError(t, err, models.ErrTeamMemberNotFound)
+	managerIDs, err = store.GetTeamManagers(ctx, owner.AccountID, first.ID)
+	if err != nil {
+		t.Fatalf("GetTeamManagers failed: %v", err)
+	}
+	if !reflect.DeepEqual(managerIDs, []string{owner.ID}) {
+		t.Errorf("Expected managers %v but was %v", []string{owner.ID}, managerIDs)
+	}
+	members, err = store.GetTeamMembers(ctx, other.AccountID, manager.ID)
+	if err != nil {
+		t.Fatalf("GetTeamMembers failed: %v", err)
+	}
+	if len(members) != 0 {
+		t.Errorf("Expected no members in other account but was %+v", members)
+	}
+}
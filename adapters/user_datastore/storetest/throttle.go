@@ -0,0 +1,46 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func testLoginFailures(t *testing.T, store models.UserDatastore) {
+	key := "user:" + uuid.New().String()
+	start := time.Now().Truncate(time.Second)
+
+	expectFailures := func(expected models.LoginFailures) {
+		t.Helper()
+		failures, err := store.GetLoginFailures(ctx, key)
+		if err != nil {
+			t.Fatalf("GetLoginFailures failed: %v", err)
+		}
+		if failures.Failures != expected.Failures || !failures.LastFailure.Equal(expected.LastFailure) {
+			t.Errorf("Expected failures %+v but was %+v", expected, failures)
+		}
+	}
+	addFailure := func(at time.Time) {
+		t.Helper()
+		err := store.AddLoginFailure(ctx, key, at, at.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("AddLoginFailure failed: %v", err)
+		}
+	}
+	expectFailures(models.LoginFailures{})
+
+	addFailure(start)
+	addFailure(start.Add(time.Minute))
+	expectFailures(models.LoginFailures{Failures: 2, LastFailure: start.Add(time.Minute)})
+
+	// failures older than an hour are forgotten
+	addFailure(start.Add(2 * time.Hour))
+	expectFailures(models.LoginFailures{Failures: 1, LastFailure: start.Add(2 * time.Hour)})
+
+	err := store.ResetLoginFailures(ctx, key)
+	if err != nil {
+		t.Fatalf("ResetLoginFailures failed: %v", err)
+	}
+	expectFailures(models.LoginFailures{})
+}
@@ -0,0 +1,110 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func newRefreshToken(t *testing.T, store models.UserDatastore, familyID string) models.RefreshToken {
+	t.Helper()
+	token := models.RefreshToken{
+		TokenHash: uuid.New().String() + uuid.New().String()[:28],
+		FamilyID:  familyID,
+		UserID:    uuid.New().String(),
+		AccountID: uuid.New().String(),
+		ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	err := store.SaveRefreshToken(ctx, token)
+	if err != nil {
+		t.Fatalf("SaveRefreshToken failed: %v", err)
+	}
+	return token
+}
+
+func testRefreshTokenRotation(t *testing.T, store models.UserDatastore) {
+	token := newRefreshToken(t, store, uuid.New().String())
+
+	used, err := store.UseRefreshToken(ctx, token.TokenHash)
+	if err != nil {
+		t.Fatalf("UseRefreshToken failed: %v", err)
+	}
+	if used.Used || used.Revoked || !used.ExpiresAt.Equal(token.ExpiresAt) || used.FamilyID != token.FamilyID ||
+		used.UserID != token.UserID || used.AccountID != token.AccountID {
+		t.Errorf("Expected first use to return %+v but was %+v", token, used)
+	}
+
+	reused, err := store.UseRefreshToken(ctx, token.TokenHash)
+	if err != nil {
+		t.Fatalf("UseRefreshToken failed: %v", err)
+	}
+	if !reused.Used {
+		t.Errorf("Expected second use to return used token")
+	}
+
+	_, err = store.UseRefreshToken(ctx, "unknown")
+	expectError(t, err, models.ErrRefreshTokenNotFound)
+}
+
+func testRefreshTokenFamilyRevocation(t *testing.T, store models.UserDatastore) {
+	familyID := uuid.New().String()
+	first := newRefreshToken(t, store, familyID)
+	second := newRefreshToken(t, store, familyID)
+	other := newRefreshToken(t, store, uuid.New().String())
+
+	err := store.RevokeRefreshTokenFamily(ctx, familyID)
+	if err != nil {
+		t.Fatalf("RevokeRefreshTokenFamily failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		token   models.RefreshToken
+		revoked bool
+	}{
+		{token: first, revoked: true},
+		{token: second, revoked: true},
+		{token: other, revoked: false},
+	} {
+		token, err := store.UseRefreshToken(ctx, tc.token.TokenHash)
+		if err != nil {
+			t.Fatalf("UseRefreshToken failed: %v", err)
+		}
+		if token.Revoked != tc.revoked {
+			t.Errorf("Expected token of family %s to be revoked %t but was %t", token.FamilyID, tc.revoked, token.Revoked)
+		}
+	}
+}
+
+func testAccessTokenRevocation(t *testing.T, store models.UserDatastore) {
+	family := newRefreshToken(t, store, uuid.New().String())
+	jti := uuid.New().String()
+
+	expectRevoked := func(jti, familyID string, expected bool) {
+		t.Helper()
+		revoked, err := store.IsAccessTokenRevoked(ctx, jti, familyID)
+		if err != nil {
+			t.Fatalf("IsAccessTokenRevoked failed: %v", err)
+		}
+		if revoked != expected {
+			t.Errorf("Expected token %s of family %s to be revoked %t but was %t", jti, familyID, expected, revoked)
+		}
+	}
+	expectRevoked(jti, family.FamilyID, false)
+
+	for i := 0; i < 2; i++ {
+		err := store.RevokeAccessToken(ctx, jti, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("RevokeAccessToken failed: %v", err)
+		}
+	}
+	expectRevoked(jti, family.FamilyID, true)
+	expectRevoked(uuid.New().String(), family.FamilyID, false)
+
+	err := store.RevokeUserTokenFamilies(ctx, family.AccountID, family.UserID)
+	if err != nil {
+		t.Fatalf("RevokeUserTokenFamilies failed: %v", err)
+	}
+	expectRevoked(uuid.New().String(), family.FamilyID, true)
+	expectRevoked(uuid.New().String(), uuid.New().String(), false)
+}
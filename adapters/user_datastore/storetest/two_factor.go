@@ -0,0 +1,135 @@
+package storetest
+
+import (
+	"calories-counter/models"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+)
+
+func testTOTPEnrollment(t *testing.T, store models.UserDatastore) {
+	totp := models.TOTP{UserID: uuid.New().String(), AccountID: uuid.New().String(), Secret: "PENDING"}
+
+	_, err := store.GetTOTP(ctx, totp.AccountID, totp.UserID)
+	expectError(t, err, models.ErrTOTPNotFound)
+	err = store.EnableTOTP(ctx, totp.AccountID, totp.UserID, nil)
+	expectError(t, err, models.ErrTOTPNotFound)
+
+	err = store.SaveTOTP(ctx, totp)
+	if err != nil {
+		t.Fatalf("SaveTOTP failed: %v", err)
+	}
+	// restarted enrollment replaces pending secret
+	totp.Secret = "SECRET"
+	err = store.SaveTOTP(ctx, totp)
+	if err != nil {
+		t.Fatalf("SaveTOTP failed: %v", err)
+	}
+
+	err = store.EnableTOTP(ctx, totp.AccountID, totp.UserID, []string{"code1", "code2"})
+	if err != nil {
+		t.Fatalf("EnableTOTP failed: %v", err)
+	}
+	stored, err := store.GetTOTP(ctx, totp.AccountID, totp.UserID)
+	if err != nil {
+		t.Fatalf("GetTOTP failed: %v", err)
+	}
+	totp.Enabled = true
+	if !reflect.DeepEqual(*stored, totp) {
+		t.Errorf("Expected TOTP %+v but was %+v", totp, *stored)
+	}
+
+	err = store.SaveTOTP(ctx, totp)
+	expectError(t, err, models.ErrTOTPAlreadyEnabled)
+	_, err = store.GetTOTP(ctx, uuid.New().String(), totp.UserID)
+	expectError(t, err, models.ErrTOTPNotFound)
+
+	err = store.DeleteTOTP(ctx, totp.AccountID, totp.UserID)
+	if err != nil {
+		t.Fatalf("DeleteTOTP failed: %v", err)
+	}
+	_, err = store.GetTOTP(ctx, totp.AccountID, totp.UserID)
+	expectError(t, err, models.ErrTOTPNotFound)
+	if ok, _ := store.UseRecoveryCode(ctx, totp.AccountID, totp.UserID, "code1"); ok {
+		t.Errorf("Expected recovery codes to be deleted with TOTP")
+	}
+	err = store.DeleteTOTP(ctx, totp.AccountID, totp.UserID)
+	expectError(t, err, models.ErrTOTPNotFound)
+}
+
+func testTOTPReplay(t *testing.T, store models.UserDatastore) {
+	totp := models.TOTP{UserID: uuid.New().String(), AccountID: uuid.New().String(), Secret: "SECRET"}
+	err := store.SaveTOTP(ctx, totp)
+	if err != nil {
+		t.Fatalf("SaveTOTP failed: %v", err)
+	}
+	err = store.EnableTOTP(ctx, totp.AccountID, totp.UserID, []string{uuid.New().String(), "code"})
+	if err != nil {
+		t.Fatalf("EnableTOTP failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		step     int64
+		expected bool
+	}{
+		{step: 100, expected: true},
+		{step: 100, expected: false},
+		{step: 99, expected: false},
+		{step: 101, expected: true},
+	} {
+		ok, err := store.UseTOTPStep(ctx, totp.AccountID, totp.UserID, tc.step)
+		if err != nil {
+			t.Fatalf("UseTOTPStep failed: %v", err)
+		}
+		if ok != tc.expected {
+			t.Errorf("Expected step %d to be accepted %t but was %t", tc.step, tc.expected, ok)
+		}
+	}
+
+	for _, tc := range []struct {
+		accountID string
+		code      string
+		expected  bool
+	}{
+		{accountID: uuid.New().String(), code: "code", expected: false},
+		{accountID: totp.AccountID, code: "unknown", expected: false},
+		{accountID: totp.AccountID, code: "code", expected: true},
+		{accountID: totp.AccountID, code: "code", expected: false},
+	} {
+		ok, err := store.UseRecoveryCode(ctx, tc.accountID, totp.UserID, tc.code)
+		if err != nil {
+			t.Fatalf("UseRecoveryCode failed: %v", err)
+		}
+		if ok != tc.expected {
+			t.Errorf("Expected recovery code %s to be accepted %t but was %t", tc.code, tc.expected, ok)
+		}
+	}
+}
+
+func testTwoFactorRoles(t *testing.T, store models.UserDatastore) {
+	accountID := uuid.New().String()
+
+	expectRoles := func(expected []int) {
+		t.Helper()
+		roleIDs, err := store.GetTwoFactorRoles(ctx, accountID)
+		if err != nil {
+			t.Fatalf("GetTwoFactorRoles failed: %v", err)
+		}
+		if !reflect.DeepEqual(roleIDs, expected) {
+			t.Errorf("Expected roles %v but was %v", expected, roleIDs)
+		}
+	}
+	expectRoles([]int{})
+
+	err := store.SetTwoFactorRoles(ctx, accountID, []int{models.OwnerRole, models.AdminRole})
+	if err != nil {
+		t.Fatalf("SetTwoFactorRoles failed: %v", err)
+	}
+	expectRoles([]int{models.AdminRole, models.OwnerRole})
+
+	err = store.SetTwoFactorRoles(ctx, accountID, []int{models.UserManagerRole})
+	if err != nil {
+		t.Fatalf("SetTwoFactorRoles failed: %v", err)
+	}
+	expectRoles([]int{models.UserManagerRole})
+}
@@ -0,0 +1,64 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"sort"
+)
+
+type memoryTeamMember struct {
+	accountID string
+	managerID string
+	userID    string
+}
+
+func (d *MemoryStore) AddTeamMember(ctx context.Context, accountID, managerID, userID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.teams[memoryTeamMember{accountID: accountID, managerID: managerID, userID: userID}] = true
+	return nil
+}
+
+func (d *MemoryStore) RemoveTeamMember(ctx context.Context, accountID, managerID, userID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	member := memoryTeamMember{accountID: accountID, managerID: managerID, userID: userID}
+	if !d.teams[member] {
+		return models.ErrTeamMemberNotFound
+	}
+	delete(d.teams, member)
+	return nil
+}
+
+func (d *MemoryStore) GetTeamMembers(ctx context.Context, accountID, managerID string) ([]models.User, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	users := make([]models.User, 0)
+	for member := range d.teams {
+		if member.accountID != accountID || member.managerID != managerID {
+			continue
+		}
+		if u, ok := d.users[member.userID]; ok && u.user.AccountID == accountID {
+			users = append(users, u.user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (d *MemoryStore) GetTeamManagers(ctx context.Context, accountID, userID string) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	managerIDs := make([]string, 0)
+	for member := range d.teams {
+		if member.accountID == accountID && member.userID == userID {
+			managerIDs = append(managerIDs, member.managerID)
+		}
+	}
+	sort.Strings(managerIDs)
+	return managerIDs, nil
+}
@@ -0,0 +1,55 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"github.com/jmoiron/sqlx"
+)
+
+func (d *sqlStore) AddTeamMember(ctx context.Context, accountID, managerID, userID string) error {
+	query := d.ext().Rebind(`INSERT INTO teams (account_id, manager_id, user_id) VALUES (?, ?, ?)`)
+	_, err := d.ext().ExecContext(ctx, query, accountID, managerID, userID)
+	if err != nil && d.dialect.isDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (d *sqlStore) RemoveTeamMember(ctx context.Context, accountID, managerID, userID string) error {
+	query := d.ext().Rebind(`DELETE FROM teams WHERE account_id=? AND manager_id=? AND user_id=?`)
+	res, err := d.ext().ExecContext(ctx, query, accountID, managerID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrTeamMemberNotFound
+	}
+	return nil
+}
+
+func (d *sqlStore) GetTeamMembers(ctx context.Context, accountID, managerID string) ([]models.User, error) {
+	query := d.ext().Rebind(`SELECT u.id, u.account_id, u.username, u.role_id
+								FROM teams t JOIN users u ON u.account_id = t.account_id AND u.id = t.user_id
+								WHERE t.account_id=? AND t.manager_id=?
+								ORDER BY u.username`)
+	users := make([]models.User, 0)
+	err := sqlx.SelectContext(ctx, d.ext(), &users, query, accountID, managerID)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (d *sqlStore) GetTeamManagers(ctx context.Context, accountID, userID string) ([]string, error) {
+	query := d.ext().Rebind(`SELECT manager_id FROM teams WHERE account_id=? AND user_id=? ORDER BY manager_id`)
+	managerIDs := make([]string, 0)
+	err := sqlx.SelectContext(ctx, d.ext(), &managerIDs, query, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return managerIDs, nil
+}
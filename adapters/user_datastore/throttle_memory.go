@@ -0,0 +1,36 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"time"
+)
+
+func (d *MemoryStore) GetLoginFailures(ctx context.Context, key string) (models.LoginFailures, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.loginFailures[key], nil
+}
+
+func (d *MemoryStore) AddLoginFailure(ctx context.Context, key string, at, since time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	failures := d.loginFailures[key]
+	if failures.LastFailure.Before(since) {
+		failures.Failures = 0
+	}
+	failures.Failures++
+	failures.LastFailure = at
+	d.loginFailures[key] = failures
+	return nil
+}
+
+func (d *MemoryStore) ResetLoginFailures(ctx context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.loginFailures, key)
+	return nil
+}
@@ -0,0 +1,56 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (d *sqlStore) GetLoginFailures(ctx context.Context, key string) (models.LoginFailures, error) {
+	query := d.ext().Rebind(`SELECT failures, last_failure FROM login_failures WHERE throttle_key=?`)
+	var failures models.LoginFailures
+	var lastFailure int64
+	err := d.ext().QueryRowxContext(ctx, query, key).Scan(&failures.Failures, &lastFailure)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.LoginFailures{}, nil
+		}
+		return models.LoginFailures{}, err
+	}
+	failures.LastFailure = time.Unix(lastFailure, 0)
+
+	return failures, nil
+}
+
+func (d *sqlStore) AddLoginFailure(ctx context.Context, key string, at, since time.Time) error {
+	// failures are incremented by the database, so concurrent attempts are all counted
+	update := d.ext().Rebind(`UPDATE login_failures
+								SET failures=CASE WHEN last_failure<? THEN 1 ELSE failures+1 END, last_failure=?
+								WHERE throttle_key=?`)
+	res, err := d.ext().ExecContext(ctx, update, since.Unix(), at.Unix(), key)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	insert := d.ext().Rebind(`INSERT INTO login_failures (throttle_key, failures, last_failure) VALUES (?, ?, ?)`)
+	_, err = d.ext().ExecContext(ctx, insert, key, 1, at.Unix())
+	if err != nil && d.dialect.isDuplicateKeyError(err) {
+		// concurrent attempt inserted the key first
+		_, err = d.ext().ExecContext(ctx, update, since.Unix(), at.Unix(), key)
+	}
+	return err
+}
+
+func (d *sqlStore) ResetLoginFailures(ctx context.Context, key string) error {
+	query := d.ext().Rebind(`DELETE FROM login_failures WHERE throttle_key=?`)
+	_, err := d.ext().ExecContext(ctx, query, key)
+	return err
+}
@@ -0,0 +1,85 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"time"
+)
+
+func (d *MemoryStore) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.refreshTokens[token.TokenHash] = token
+	return nil
+}
+
+func (d *MemoryStore) UseRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	token, ok := d.refreshTokens[tokenHash]
+	if !ok {
+		return nil, models.ErrRefreshTokenNotFound
+	}
+	used := token
+	used.Used = true
+	d.refreshTokens[tokenHash] = used
+
+	return &token, nil
+}
+
+func (d *MemoryStore) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for hash, token := range d.refreshTokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			d.refreshTokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func (d *MemoryStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range d.revokedTokens {
+		if exp.Before(now) {
+			delete(d.revokedTokens, id)
+		}
+	}
+	d.revokedTokens[jti] = expiresAt
+	return nil
+}
+
+func (d *MemoryStore) RevokeUserTokenFamilies(ctx context.Context, accountID, userID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for hash, token := range d.refreshTokens {
+		if token.AccountID == accountID && token.UserID == userID {
+			token.Revoked = true
+			d.refreshTokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func (d *MemoryStore) IsAccessTokenRevoked(ctx context.Context, jti, familyID string) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if _, ok := d.revokedTokens[jti]; ok {
+		return true, nil
+	}
+	for _, token := range d.refreshTokens {
+		if token.FamilyID == familyID && token.Revoked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
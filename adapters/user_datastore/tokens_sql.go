@@ -0,0 +1,86 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (d *sqlStore) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	query := d.ext().Rebind(`INSERT INTO refresh_tokens (token_hash, family_id, user_id, account_id, expires_at, used, revoked)
+								VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	_, err := d.ext().ExecContext(ctx, query, token.TokenHash, token.FamilyID, token.UserID, token.AccountID,
+		token.ExpiresAt.Unix(), token.Used, token.Revoked)
+	return err
+}
+
+func (d *sqlStore) UseRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	// only one of concurrent calls marks the token as used, the others see it used already
+	query := d.ext().Rebind(`UPDATE refresh_tokens SET used=? WHERE token_hash=? AND used=?`)
+	res, err := d.ext().ExecContext(ctx, query, true, tokenHash, false)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	query = d.ext().Rebind(`SELECT token_hash, family_id, user_id, account_id, expires_at, revoked
+								FROM refresh_tokens WHERE token_hash=?`)
+	row := d.ext().QueryRowxContext(ctx, query, tokenHash)
+	var token models.RefreshToken
+	var expiresAt int64
+	err = row.Scan(&token.TokenHash, &token.FamilyID, &token.UserID, &token.AccountID, &expiresAt, &token.Revoked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+	token.ExpiresAt = time.Unix(expiresAt, 0)
+	token.Used = n == 0
+
+	return &token, nil
+}
+
+func (d *sqlStore) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := d.ext().Rebind(`UPDATE refresh_tokens SET revoked=? WHERE family_id=?`)
+	_, err := d.ext().ExecContext(ctx, query, true, familyID)
+	return err
+}
+
+func (d *sqlStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	// revoked tokens are needed only until they expire
+	query := d.ext().Rebind(`DELETE FROM revoked_tokens WHERE expires_at < ?`)
+	_, err := d.ext().ExecContext(ctx, query, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	query = d.ext().Rebind(`INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?)`)
+	_, err = d.ext().ExecContext(ctx, query, jti, expiresAt.Unix())
+	// token revoked twice stays revoked
+	if err != nil && !d.dialect.isDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+func (d *sqlStore) RevokeUserTokenFamilies(ctx context.Context, accountID, userID string) error {
+	query := d.ext().Rebind(`UPDATE refresh_tokens SET revoked=? WHERE account_id=? AND user_id=?`)
+	_, err := d.ext().ExecContext(ctx, query, true, accountID, userID)
+	return err
+}
+
+func (d *sqlStore) IsAccessTokenRevoked(ctx context.Context, jti, familyID string) (bool, error) {
+	query := d.ext().Rebind(`SELECT (SELECT COUNT(*) FROM revoked_tokens WHERE jti=?) +
+								(SELECT COUNT(*) FROM refresh_tokens WHERE family_id=? AND revoked=?)`)
+	var n int
+	err := d.ext().QueryRowxContext(ctx, query, jti, familyID, true).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
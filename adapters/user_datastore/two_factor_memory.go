@@ -0,0 +1,120 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"sort"
+)
+
+type memoryRecoveryCode struct {
+	userID    string
+	accountID string
+	used      bool
+}
+
+func (d *MemoryStore) SaveTOTP(ctx context.Context, totp models.TOTP) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if stored, ok := d.totp[totp.UserID]; ok && stored.AccountID == totp.AccountID && stored.Enabled {
+		return models.ErrTOTPAlreadyEnabled
+	}
+	totp.Enabled = false
+	totp.LastStep = 0
+	d.totp[totp.UserID] = totp
+	return nil
+}
+
+func (d *MemoryStore) GetTOTP(ctx context.Context, accountID, userID string) (*models.TOTP, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	totp, ok := d.totp[userID]
+	if !ok || totp.AccountID != accountID {
+		return nil, models.ErrTOTPNotFound
+	}
+	return &totp, nil
+}
+
+func (d *MemoryStore) EnableTOTP(ctx context.Context, accountID, userID string, recoveryCodeHashes []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	totp, ok := d.totp[userID]
+	if !ok || totp.AccountID != accountID {
+		return models.ErrTOTPNotFound
+	}
+	totp.Enabled = true
+	d.totp[userID] = totp
+
+	d.deleteRecoveryCodes(accountID, userID)
+	for _, hash := range recoveryCodeHashes {
+		d.recoveryCodes[hash] = memoryRecoveryCode{userID: userID, accountID: accountID}
+	}
+	return nil
+}
+
+func (d *MemoryStore) UseTOTPStep(ctx context.Context, accountID, userID string, step int64) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	totp, ok := d.totp[userID]
+	if !ok || totp.AccountID != accountID || totp.LastStep >= step {
+		return false, nil
+	}
+	totp.LastStep = step
+	d.totp[userID] = totp
+	return true, nil
+}
+
+func (d *MemoryStore) UseRecoveryCode(ctx context.Context, accountID, userID, codeHash string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	code, ok := d.recoveryCodes[codeHash]
+	if !ok || code.accountID != accountID || code.userID != userID || code.used {
+		return false, nil
+	}
+	code.used = true
+	d.recoveryCodes[codeHash] = code
+	return true, nil
+}
+
+func (d *MemoryStore) DeleteTOTP(ctx context.Context, accountID, userID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	totp, ok := d.totp[userID]
+	if !ok || totp.AccountID != accountID {
+		return models.ErrTOTPNotFound
+	}
+	delete(d.totp, userID)
+	d.deleteRecoveryCodes(accountID, userID)
+	return nil
+}
+
+// deleteRecoveryCodes has to be called with d.mu held
+func (d *MemoryStore) deleteRecoveryCodes(accountID, userID string) {
+	for hash, code := range d.recoveryCodes {
+		if code.accountID == accountID && code.userID == userID {
+			delete(d.recoveryCodes, hash)
+		}
+	}
+}
+
+func (d *MemoryStore) GetTwoFactorRoles(ctx context.Context, accountID string) ([]int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	roleIDs := append([]int{}, d.twoFactorRoles[accountID]...)
+	sort.Ints(roleIDs)
+	return roleIDs, nil
+}
+
+func (d *MemoryStore) SetTwoFactorRoles(ctx context.Context, accountID string, roleIDs []int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.twoFactorRoles[accountID] = append([]int{}, roleIDs...)
+	return nil
+}
@@ -0,0 +1,156 @@
+package user_datastore
+
+import (
+	"calories-counter/models"
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+func (d *sqlStore) SaveTOTP(ctx context.Context, totp models.TOTP) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`SELECT enabled FROM users_totp WHERE account_id=? AND user_id=?`)
+		var enabled bool
+		err := tx.QueryRowxContext(ctx, query, totp.AccountID, totp.UserID).Scan(&enabled)
+		switch {
+		case err == sql.ErrNoRows:
+			query = tx.Rebind(`INSERT INTO users_totp (user_id, account_id, secret, enabled, last_step) VALUES (?, ?, ?, ?, ?)`)
+			_, err = tx.ExecContext(ctx, query, totp.UserID, totp.AccountID, totp.Secret, false, 0)
+			return err
+		case err != nil:
+			return err
+		case enabled:
+			return models.ErrTOTPAlreadyEnabled
+		}
+
+		query = tx.Rebind(`UPDATE users_totp SET secret=?, last_step=? WHERE account_id=? AND user_id=?`)
+		_, err = tx.ExecContext(ctx, query, totp.Secret, 0, totp.AccountID, totp.UserID)
+		return err
+	})
+}
+
+func (d *sqlStore) GetTOTP(ctx context.Context, accountID, userID string) (*models.TOTP, error) {
+	query := d.ext().Rebind(`SELECT user_id, account_id, secret, enabled, last_step FROM users_totp
+								WHERE account_id=? AND user_id=?`)
+	row := d.ext().QueryRowxContext(ctx, query, accountID, userID)
+
+	var totp models.TOTP
+	err := row.Scan(&totp.UserID, &totp.AccountID, &totp.Secret, &totp.Enabled, &totp.LastStep)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrTOTPNotFound
+		}
+		return nil, err
+	}
+
+	return &totp, nil
+}
+
+func (d *sqlStore) EnableTOTP(ctx context.Context, accountID, userID string, recoveryCodeHashes []string) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`UPDATE users_totp SET enabled=? WHERE account_id=? AND user_id=?`)
+		res, err := tx.ExecContext(ctx, query, true, accountID, userID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrTOTPNotFound
+		}
+
+		query = tx.Rebind(`DELETE FROM recovery_codes WHERE account_id=? AND user_id=?`)
+		_, err = tx.ExecContext(ctx, query, accountID, userID)
+		if err != nil {
+			return err
+		}
+		query = tx.Rebind(`INSERT INTO recovery_codes (code_hash, user_id, account_id, used) VALUES (?, ?, ?, ?)`)
+		for _, hash := range recoveryCodeHashes {
+			_, err = tx.ExecContext(ctx, query, hash, userID, accountID, false)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *sqlStore) UseTOTPStep(ctx context.Context, accountID, userID string, step int64) (bool, error) {
+	// steps only grow, so a code can't be replayed even by concurrent requests
+	query := d.ext().Rebind(`UPDATE users_totp SET last_step=? WHERE account_id=? AND user_id=? AND last_step<?`)
+	res, err := d.ext().ExecContext(ctx, query, step, accountID, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (d *sqlStore) UseRecoveryCode(ctx context.Context, accountID, userID, codeHash string) (bool, error) {
+	query := d.ext().Rebind(`UPDATE recovery_codes SET used=?
+								WHERE code_hash=? AND account_id=? AND user_id=? AND used=?`)
+	res, err := d.ext().ExecContext(ctx, query, true, codeHash, accountID, userID, false)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (d *sqlStore) DeleteTOTP(ctx context.Context, accountID, userID string) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`DELETE FROM users_totp WHERE account_id=? AND user_id=?`)
+		res, err := tx.ExecContext(ctx, query, accountID, userID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrTOTPNotFound
+		}
+
+		query = tx.Rebind(`DELETE FROM recovery_codes WHERE account_id=? AND user_id=?`)
+		_, err = tx.ExecContext(ctx, query, accountID, userID)
+		return err
+	})
+}
+
+func (d *sqlStore) GetTwoFactorRoles(ctx context.Context, accountID string) ([]int, error) {
+	query := d.ext().Rebind(`SELECT role_id FROM two_factor_roles WHERE account_id=? ORDER BY role_id`)
+	roleIDs := []int{}
+	err := sqlx.SelectContext(ctx, d.ext(), &roleIDs, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return roleIDs, nil
+}
+
+func (d *sqlStore) SetTwoFactorRoles(ctx context.Context, accountID string, roleIDs []int) error {
+	return d.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`DELETE FROM two_factor_roles WHERE account_id=?`)
+		_, err := tx.ExecContext(ctx, query, accountID)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`INSERT INTO two_factor_roles (account_id, role_id) VALUES (?, ?)`)
+		for _, roleID := range roleIDs {
+			_, err = tx.ExecContext(ctx, query, accountID, roleID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
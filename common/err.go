@@ -8,3 +8,8 @@ type ApiErr struct {
 func (e ApiErr) Error() string {
 	return e.Err.Error()
 }
+
+// Unwrap lets errors.Is match sentinel errors wrapped by Err
+func (e ApiErr) Unwrap() error {
+	return e.Err
+}
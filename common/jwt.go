@@ -2,8 +2,11 @@ package common
 
 import "github.com/dgrijalva/jwt-go"
 
+// JWTClaims are claims of access tokens, StandardClaims.Id is the `jti` under which the token can be revoked
 type JWTClaims struct {
 	UUID      string
 	AccountID string
+	// FamilyID is the refresh token family of the sign in which issued the token
+	FamilyID string
 	jwt.StandardClaims
 }
@@ -0,0 +1,76 @@
+package filter
+
+import (
+	"strings"
+)
+
+// Eval reports whether record matches expr, record maps field names to string, int, float64 or bool values.
+// Nil expr matches every record.
+func Eval(expr Expr, record map[string]interface{}) bool {
+	switch e := expr.(type) {
+	case nil:
+		return true
+	case *And:
+		return Eval(e.Left, record) && Eval(e.Right, record)
+	case *Or:
+		return Eval(e.Left, record) || Eval(e.Right, record)
+	case *Not:
+		return !Eval(e.Expr, record)
+	case *Comparison:
+		return compare(e, record[e.Field])
+	}
+	return false
+}
+
+func compare(c *Comparison, value interface{}) bool {
+	var cmp int
+	switch c.Type {
+	case Number:
+		var n float64
+		switch v := value.(type) {
+		case int:
+			n = float64(v)
+		case float64:
+			n = v
+		default:
+			return false
+		}
+		want := c.Value.(float64)
+		switch {
+		case n < want:
+			cmp = -1
+		case n > want:
+			cmp = 1
+		}
+	case Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return false
+		}
+		if b != c.Value.(bool) {
+			cmp = 1
+		}
+	default:
+		// dates and times are compared as strings, their formats keep lexical and chronological order the same
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(s, c.Value.(string))
+	}
+
+	switch c.Operator {
+	case Eq:
+		return cmp == 0
+	case Ne:
+		return cmp != 0
+	case Gt:
+		return cmp > 0
+	case Ge:
+		return cmp >= 0
+	case Lt:
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
@@ -0,0 +1,303 @@
+// Package filter parses filter expressions of list endpoints, e.g.
+// `(date eq '2020-01-01') AND ((calories gt 20) OR (calories lt 10))`.
+//
+// Expression is a comparison `<field> <operator> <value>` where operator is one of eq, ne, gt, ge, lt, le,
+// comparisons can be combined with AND, OR, NOT and parentheses. Values are numbers, true/false
+// or strings in single quotes. Fields are checked against a whitelist of the listed resource.
+//
+// Lists are ordered by sort parameter, see ParseSort.
+package filter
+
+import (
+	"calories-counter/common"
+	"calories-counter/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FieldType int
+
+const (
+	String FieldType = iota
+	Number
+	Bool
+	// Date is a string in YYYY-MM-DD format
+	Date
+	// Time is a string in HH:MM:SS format
+	Time
+)
+
+// Fields is a whitelist of fields which can be used in filters of a resource
+type Fields map[string]FieldType
+
+var (
+	MealFields = Fields{
+		"id":               String,
+		"date":             Date,
+		"time":             Time,
+		"name":             String,
+		"calories":         Number,
+		"calories_deficit": Bool,
+	}
+
+	UserFields = Fields{
+		"id":       String,
+		"username": String,
+		"role_id":  Number,
+	}
+)
+
+type Operator string
+
+const (
+	Eq Operator = "eq"
+	Ne Operator = "ne"
+	Gt Operator = "gt"
+	Ge Operator = "ge"
+	Lt Operator = "lt"
+	Le Operator = "le"
+)
+
+const (
+	// MaxLength limits length of filters in bytes
+	MaxLength = 4096
+	// MaxDepth limits nesting of parentheses and NOT, the parser recurses into them
+	MaxDepth = 32
+)
+
+// Expr is a node of parsed filter: *And, *Or, *Not or *Comparison
+type Expr interface {
+	expr()
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+type Comparison struct {
+	Field    string
+	Type     FieldType
+	Operator Operator
+	// Value is string, float64 or bool depending on Type
+	Value interface{}
+}
+
+func (*And) expr()        {}
+func (*Or) expr()         {}
+func (*Not) expr()        {}
+func (*Comparison) expr() {}
+
+// errorAt returns models.ErrInvalidFilter pointing to the position in the filter
+func errorAt(pos int, format string, args ...interface{}) error {
+	return positionedError(models.ErrInvalidFilter, pos, format, args...)
+}
+
+// positionedError wraps bad request sentinel err with the position in the parsed parameter
+func positionedError(err common.ApiErr, pos int, format string, args ...interface{}) error {
+	return common.ApiErr{
+		Code: err.Code,
+		Err:  fmt.Errorf("%w at position %d: %s", err, pos, fmt.Sprintf(format, args...)),
+	}
+}
+
+// Parse parses filter and checks it against fields, empty filter returns nil Expr which matches everything
+func Parse(filter string, fields Fields) (Expr, error) {
+	if len(filter) > MaxLength {
+		return nil, errorAt(MaxLength+1, "filter is longer than %d characters", MaxLength)
+	}
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind == tokenEOF {
+		return nil, nil
+	}
+
+	p := parser{tokens: tokens, fields: fields}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errorAt(t.pos, "unexpected %s, expected AND or OR", t)
+	}
+
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	fields Fields
+	// depth is the number of parentheses and NOT the parser is in
+	depth int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// nest enters parentheses or NOT at t, callers decrement p.depth when they leave it
+func (p *parser) nest(t token) error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return errorAt(t.pos, "filter is nested deeper than %d levels", MaxDepth)
+	}
+	return nil
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.isKeyword("not") {
+		if err := p.nest(p.next()); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		p.depth--
+		return &Not{Expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenLParen:
+		if err := p.nest(t); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, errorAt(closing.pos, "unexpected %s, expected `)`", closing)
+		}
+		p.depth--
+		return expr, nil
+	case t.kind != tokenIdent:
+		return nil, errorAt(t.pos, "unexpected %s, expected field name or `(`", t)
+	}
+
+	fieldType, ok := p.fields[strings.ToLower(t.text)]
+	if !ok {
+		return nil, errorAt(t.pos, "unknown field %s", t)
+	}
+	cmp := &Comparison{Field: strings.ToLower(t.text), Type: fieldType}
+
+	op := p.next()
+	if op.kind != tokenIdent {
+		return nil, errorAt(op.pos, "unexpected %s, expected operator", op)
+	}
+	cmp.Operator = Operator(strings.ToLower(op.text))
+	switch cmp.Operator {
+	case Eq, Ne:
+	case Gt, Ge, Lt, Le:
+		if fieldType == Bool {
+			return nil, errorAt(op.pos, "operator %s can not be used with field %s", op, t)
+		}
+	default:
+		return nil, errorAt(op.pos, "unknown operator %s", op)
+	}
+
+	value, err := p.parseValue(t, fieldType)
+	if err != nil {
+		return nil, err
+	}
+	cmp.Value = value
+	return cmp, nil
+}
+
+func (p *parser) parseValue(field token, fieldType FieldType) (interface{}, error) {
+	t := p.next()
+	switch fieldType {
+	case Number:
+		if t.kind == tokenNumber {
+			if n, err := strconv.ParseFloat(t.text, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, errorAt(t.pos, "unexpected %s, field %s requires a number", t, field)
+	case Bool:
+		if t.kind == tokenIdent && (strings.EqualFold(t.text, "true") || strings.EqualFold(t.text, "false")) {
+			return strings.EqualFold(t.text, "true"), nil
+		}
+		return nil, errorAt(t.pos, "unexpected %s, field %s requires true or false", t, field)
+	case Date:
+		if t.kind == tokenString {
+			if _, err := time.Parse("2006-01-02", t.text); err == nil {
+				return t.text, nil
+			}
+		}
+		return nil, errorAt(t.pos, "unexpected %s, field %s requires a date in 'YYYY-MM-DD' format", t, field)
+	case Time:
+		if t.kind == tokenString {
+			if _, err := time.Parse("15:04:05", t.text); err == nil {
+				return t.text, nil
+			}
+		}
+		return nil, errorAt(t.pos, "unexpected %s, field %s requires a time in 'HH:MM:SS' format", t, field)
+	default:
+		if t.kind == tokenString {
+			return t.text, nil
+		}
+		return nil, errorAt(t.pos, "unexpected %s, field %s requires a string in single quotes", t, field)
+	}
+}
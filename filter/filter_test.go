@@ -0,0 +1,228 @@
+package filter
+
+import (
+	"calories-counter/common"
+	"calories-counter/models"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testColumns = map[string]string{
+	"id":               "m.id",
+	"date":             "m.date",
+	"time":             "m.time",
+	"name":             "m.name",
+	"calories":         "m.calories",
+	"calories_deficit": "c.calories_deficit",
+}
+
+func TestParseSQL(t *testing.T) {
+	testCases := []struct {
+		name         string
+		filter       string
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:   "Empty",
+			filter: "  ",
+		},
+		{
+			name:         "Comparison",
+			filter:       "calories gt 20",
+			expectedSQL:  "(m.calories > ?)",
+			expectedArgs: []interface{}{int64(20)},
+		},
+		{
+			name:         "NotEqual",
+			filter:       "name ne 'soup'",
+			expectedSQL:  "(m.name <> ?)",
+			expectedArgs: []interface{}{"soup"},
+		},
+		{
+			name:         "Precedence",
+			filter:       "date eq '2020-01-01' AND calories gt 20 OR calories lt 10.5",
+			expectedSQL:  "(((m.date = ?) AND (m.calories > ?)) OR (m.calories < ?))",
+			expectedArgs: []interface{}{"2020-01-01", int64(20), 10.5},
+		},
+		{
+			name:         "Parentheses",
+			filter:       "(date eq '2020-01-01') and ((calories ge 20) or (calories le 10))",
+			expectedSQL:  "((m.date = ?) AND ((m.calories >= ?) OR (m.calories <= ?)))",
+			expectedArgs: []interface{}{"2020-01-01", int64(20), int64(10)},
+		},
+		{
+			name:         "Not",
+			filter:       "NOT calories_deficit eq true",
+			expectedSQL:  "NOT (c.calories_deficit = ?)",
+			expectedArgs: []interface{}{true},
+		},
+		{
+			name:         "KeywordsInString",
+			filter:       "name eq 'date eq ''x'' OR 1=1; --'",
+			expectedSQL:  "(m.name = ?)",
+			expectedArgs: []interface{}{"date eq 'x' OR 1=1; --"},
+		},
+		{
+			name:         "Time",
+			filter:       "TIME lt '12:00:00'",
+			expectedSQL:  "(m.time < ?)",
+			expectedArgs: []interface{}{"12:00:00"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := Parse(tc.filter, MealFields)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			sql, args := SQL(expr, testColumns)
+			if sql != tc.expectedSQL {
+				t.Errorf("Expected SQL `%s` but was `%s`", tc.expectedSQL, sql)
+			}
+			if !reflect.DeepEqual(args, tc.expectedArgs) {
+				t.Errorf("Expected args %v but was %v", tc.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []struct {
+		name          string
+		filter        string
+		expectedError string
+	}{
+		{
+			name:          "UnknownField",
+			filter:        "calories gt 1 and user_id eq '1'",
+			expectedError: "invalid filter at position 19: unknown field `user_id`",
+		},
+		{
+			name:          "UnknownOperator",
+			filter:        "calories like 1",
+			expectedError: "invalid filter at position 10: unknown operator `like`",
+		},
+		{
+			name:          "UnexpectedCharacter",
+			filter:        "calories gt 1; DROP TABLE users_meals",
+			expectedError: "invalid filter at position 14: unexpected character ';'",
+		},
+		{
+			name:          "UnterminatedString",
+			filter:        "name eq 'soup",
+			expectedError: "invalid filter at position 9: unterminated string",
+		},
+		{
+			name:          "MissingParenthesis",
+			filter:        "(calories gt 1",
+			expectedError: "invalid filter at position 15: unexpected end of filter, expected `)`",
+		},
+		{
+			name:          "MissingOperand",
+			filter:        "calories gt 1 or",
+			expectedError: "invalid filter at position 17: unexpected end of filter, expected field name or `(`",
+		},
+		{
+			name:          "TrailingTokens",
+			filter:        "calories gt 1 calories",
+			expectedError: "invalid filter at position 15: unexpected `calories`, expected AND or OR",
+		},
+		{
+			name:          "FieldOnRightSide",
+			filter:        "calories gt calories",
+			expectedError: "invalid filter at position 13: unexpected `calories`, field `calories` requires a number",
+		},
+		{
+			name:          "InvalidDate",
+			filter:        "date eq '2020-13-01'",
+			expectedError: "invalid filter at position 9: unexpected '2020-13-01', field `date` requires a date in 'YYYY-MM-DD' format",
+		},
+		{
+			name:          "BoolOrdering",
+			filter:        "calories_deficit gt false",
+			expectedError: "invalid filter at position 18: operator `gt` can not be used with field `calories_deficit`",
+		},
+		{
+			name:          "NestedTooDeeply",
+			filter:        strings.Repeat("(", MaxDepth) + "NOT calories gt 1" + strings.Repeat(")", MaxDepth),
+			expectedError: "invalid filter at position 33: filter is nested deeper than 32 levels",
+		},
+		{
+			name:          "TooLong",
+			filter:        strings.Repeat("(", 1040000),
+			expectedError: "invalid filter at position 4097: filter is longer than 4096 characters",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.filter, MealFields)
+			apiErr, ok := err.(common.ApiErr)
+			if !ok || apiErr.Code != 400 || !errors.Is(err, models.ErrInvalidFilter) {
+				t.Fatalf("Expected bad request error wrapping `%v` but was `%v`", models.ErrInvalidFilter, err)
+			}
+			if apiErr.Error() != tc.expectedError {
+				t.Errorf("Expected error `%s` but was `%s`", tc.expectedError, apiErr.Error())
+			}
+		})
+	}
+}
+
+func TestEval(t *testing.T) {
+	record := map[string]interface{}{
+		"date":             "2020-01-01",
+		"time":             "12:00:00",
+		"name":             "soup",
+		"calories":         20,
+		"calories_deficit": true,
+	}
+
+	testCases := []struct {
+		filter   string
+		expected bool
+	}{
+		{"", true},
+		{"calories eq 20", true},
+		{"calories ne 20", false},
+		{"calories gt 19.5 and calories lt 21", true},
+		{"calories ge 21 or name eq 'soup'", true},
+		{"not (date le '2020-01-01')", false},
+		{"time gt '09:00:00' and calories_deficit eq true", true},
+		{"calories_deficit ne true", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.filter, func(t *testing.T) {
+			expr, err := Parse(tc.filter, MealFields)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if res := Eval(expr, record); res != tc.expected {
+				t.Errorf("Expected %v but was %v", tc.expected, res)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	sort, err := ParseSort("-date, Calories", MealSortFields)
+	if err != nil {
+		t.Fatalf("ParseSort failed: %v", err)
+	}
+	expected := Sort{{Field: "date", Desc: true}, {Field: "calories"}}
+	if !reflect.DeepEqual(sort, expected) {
+		t.Errorf("Expected sort %v but was %v", expected, sort)
+	}
+	if sql := sort.Then(SortField{Field: "id"}).SQL(testColumns); sql != "m.date DESC, m.calories, m.id" {
+		t.Errorf("Expected ORDER BY `m.date DESC, m.calories, m.id` but was `%s`", sql)
+	}
+
+	_, err = ParseSort("date,-password", MealSortFields)
+	if err == nil || err.Error() != "invalid sort at position 6: unknown field `password`" || !errors.Is(err, models.ErrInvalidSort) {
+		t.Errorf("Expected unknown field error but was `%v`", err)
+	}
+}
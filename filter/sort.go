@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"calories-counter/models"
+	"strings"
+)
+
+// SortField orders list by Field, ascending unless Desc is set
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Sort is a list of fields to order by, first field has the highest priority
+type Sort []SortField
+
+var (
+	MealSortFields = Fields{
+		"id":       String,
+		"date":     Date,
+		"time":     Time,
+		"name":     String,
+		"calories": Number,
+	}
+
+	UserSortFields = Fields{
+		"id":       String,
+		"username": String,
+		"role_id":  Number,
+	}
+)
+
+// ParseSort parses comma separated list of fields, e.g. `-date,calories`, field prefixed with `-` is sorted in
+// descending order. Fields are checked against fields whitelist, empty sort returns nil Sort.
+func ParseSort(sort string, fields Fields) (Sort, error) {
+	if strings.TrimSpace(sort) == "" {
+		return nil, nil
+	}
+
+	var res Sort
+	seen := make(map[string]bool)
+	pos := 1
+	for _, part := range strings.Split(sort, ",") {
+		name := strings.TrimSpace(part)
+		field := SortField{}
+		if strings.HasPrefix(name, "-") {
+			field.Desc = true
+			name = strings.TrimSpace(name[1:])
+		} else if strings.HasPrefix(name, "+") {
+			name = strings.TrimSpace(name[1:])
+		}
+		field.Field = strings.ToLower(name)
+
+		switch {
+		case name == "":
+			return nil, sortErrorAt(pos, "missing field name")
+		case seen[field.Field]:
+			return nil, sortErrorAt(pos, "field `%s` is used more than once", name)
+		}
+		if _, ok := fields[field.Field]; !ok {
+			return nil, sortErrorAt(pos, "unknown field `%s`", name)
+		}
+
+		seen[field.Field] = true
+		res = append(res, field)
+		pos += len([]rune(part)) + 1
+	}
+
+	return res, nil
+}
+
+// sortErrorAt returns models.ErrInvalidSort pointing to the position in the sort
+func sortErrorAt(pos int, format string, args ...interface{}) error {
+	return positionedError(models.ErrInvalidSort, pos, format, args...)
+}
+
+// Then returns s followed by fields of other which are not in s yet
+func (s Sort) Then(other ...SortField) Sort {
+	res := append(Sort{}, s...)
+	for _, o := range other {
+		found := false
+		for _, f := range s {
+			if f.Field == o.Field {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// SQL returns ORDER BY list of s, columns maps field names to column expressions
+func (s Sort) SQL(columns map[string]string) string {
+	parts := make([]string, 0, len(s))
+	for _, f := range s {
+		if f.Desc {
+			parts = append(parts, columns[f.Field]+" DESC")
+		} else {
+			parts = append(parts, columns[f.Field])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Less reports whether record a is ordered before record b, records are in the same format as in Eval
+func (s Sort) Less(a, b map[string]interface{}) bool {
+	for _, f := range s {
+		cmp := compareValues(a[f.Field], b[f.Field])
+		if cmp == 0 {
+			continue
+		}
+		if f.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	}
+	return false
+}
+
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case int:
+		bv, _ := b.(int)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	case string:
+		bv, _ := b.(string)
+		return strings.Compare(av, bv)
+	}
+	return 0
+}
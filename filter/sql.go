@@ -0,0 +1,59 @@
+package filter
+
+import (
+	"math"
+	"strings"
+)
+
+var sqlOperators = map[Operator]string{
+	Eq: "=",
+	Ne: "<>",
+	Gt: ">",
+	Ge: ">=",
+	Lt: "<",
+	Le: "<=",
+}
+
+// SQL compiles expr into a condition with `?` placeholders and its arguments,
+// columns maps field names to column expressions. Nil expr returns empty condition.
+func SQL(expr Expr, columns map[string]string) (string, []interface{}) {
+	var sb strings.Builder
+	var args []interface{}
+	writeSQL(&sb, &args, expr, columns)
+	return sb.String(), args
+}
+
+func writeSQL(sb *strings.Builder, args *[]interface{}, expr Expr, columns map[string]string) {
+	switch e := expr.(type) {
+	case *And:
+		sb.WriteString("(")
+		writeSQL(sb, args, e.Left, columns)
+		sb.WriteString(" AND ")
+		writeSQL(sb, args, e.Right, columns)
+		sb.WriteString(")")
+	case *Or:
+		sb.WriteString("(")
+		writeSQL(sb, args, e.Left, columns)
+		sb.WriteString(" OR ")
+		writeSQL(sb, args, e.Right, columns)
+		sb.WriteString(")")
+	case *Not:
+		sb.WriteString("NOT ")
+		writeSQL(sb, args, e.Expr, columns)
+	case *Comparison:
+		sb.WriteString("(")
+		sb.WriteString(columns[e.Field])
+		sb.WriteString(" ")
+		sb.WriteString(sqlOperators[e.Operator])
+		sb.WriteString(" ?)")
+		*args = append(*args, sqlValue(e.Value))
+	}
+}
+
+// sqlValue passes whole numbers as integers, so they compare exactly with integer columns
+func sqlValue(v interface{}) interface{} {
+	if n, ok := v.(float64); ok && n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+		return int64(n)
+	}
+	return v
+}
@@ -4,27 +4,125 @@ import (
 	"calories-counter/adapters/calories_datastore"
 	"calories-counter/adapters/user_datastore"
 	"calories-counter/server"
+	"calories-counter/signing"
+	"context"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"time"
+)
+
+const (
+	defaultRequestTimeout = 10 * time.Second
+	defaultPurgeInterval  = time.Hour
 )
 
 var (
-	secretKey = os.Getenv("TOKEN_SECRET")
-	dbSource  = os.Getenv("MYSQL_DB_SOURCE")
-	appID     = os.Getenv("API_APP_ID")
-	apiKey    = os.Getenv("API_KEY")
+	// SIGNING_KEYS is a path of keys file, see signing.LoadKeySet
+	signingKeys = os.Getenv("SIGNING_KEYS")
+	dbSource    = getEnv("DB_SOURCE", os.Getenv("MYSQL_DB_SOURCE"))
+	appID       = os.Getenv("API_APP_ID")
+	apiKey      = os.Getenv("API_KEY")
+	// REQUEST_TIMEOUT is a duration, e.g. 5s or 1m
+	requestTimeout = getEnv("REQUEST_TIMEOUT", defaultRequestTimeout.String())
+	// ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL are durations, server defaults are used when not set
+	accessTokenTTL  = getEnv("ACCESS_TOKEN_TTL", server.DefaultAccessTokenTTL.String())
+	refreshTokenTTL = getEnv("REFRESH_TOKEN_TTL", server.DefaultRefreshTokenTTL.String())
+	// PURGE_INTERVAL is a duration, deleted accounts past their grace period are purged that often
+	purgeInterval = getEnv("PURGE_INTERVAL", defaultPurgeInterval.String())
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
+	}
+
 	caloriesDatastore := calories_datastore.NewNutritionixApi(appID, apiKey)
-	userDatastore, err := user_datastore.NewMySQLStore(dbSource)
+	timeout, err := time.ParseDuration(requestTimeout)
+	if err != nil {
+		log.Fatal("invalid REQUEST_TIMEOUT: ", err)
+	}
+	accessTTL, err := time.ParseDuration(accessTokenTTL)
+	if err != nil {
+		log.Fatal("invalid ACCESS_TOKEN_TTL: ", err)
+	}
+	refreshTTL, err := time.ParseDuration(refreshTokenTTL)
+	if err != nil {
+		log.Fatal("invalid REFRESH_TOKEN_TTL: ", err)
+	}
+	purgeEvery, err := time.ParseDuration(purgeInterval)
+	if err != nil {
+		log.Fatal("invalid PURGE_INTERVAL: ", err)
+	} else if purgeEvery <= 0 {
+		log.Fatal("invalid PURGE_INTERVAL: has to be positive")
+	}
+
+	keys, err := loadKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if dbSource == "" {
+		log.Warn("DB_SOURCE is not set, using in-memory datastore")
+	}
+	userDatastore, err := user_datastore.Open(dbSource)
 	if err != nil {
-		log.Error(err)
+		log.Fatal(err)
 	}
 	defer func() { _ = userDatastore.Close() }()
 
+	s, err := server.New(server.Config{
+		Keys:               keys,
+		RequestTimeout:     timeout,
+		AccessTokenTTL:     accessTTL,
+		RefreshTokenTTL:    refreshTTL,
+		UnitOfWork:         userDatastore,
+		Credentials:        userDatastore,
+		Accounts:           userDatastore,
+		RefreshTokens:      userDatastore,
+		Revocations:        userDatastore,
+		TwoFactor:          userDatastore,
+		LoginThrottle:      userDatastore,
+		PasswordResets:     userDatastore,
+		APIKeys:            userDatastore,
+		Roles:              userDatastore,
+		Teams:              userDatastore,
+		OwnershipTransfers: userDatastore,
+		Invitations:        userDatastore,
+		Users:              userDatastore,
+		Meals:              userDatastore,
+		Settings:           userDatastore,
+		DailyTotals:        userDatastore,
+		Calories:           caloriesDatastore,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go s.RunAccountPurge(context.Background(), purgeEvery)
+
 	r := gin.Default()
-	server.SetupRouter(r, secretKey, userDatastore, caloriesDatastore)
+	s.SetupRouter(r)
 	_ = r.Run(":8000")
 }
+
+func loadKeys() (*signing.KeySet, error) {
+	if signingKeys != "" {
+		return signing.LoadKeySet(signingKeys)
+	}
+
+	log.Warn("SIGNING_KEYS is not set, using generated signing key, tokens are invalid after restart")
+	key, err := signing.GenerateKey("generated")
+	if err != nil {
+		return nil, err
+	}
+	return signing.NewKeySet(key)
+}
+
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+const migrateUsage = "usage: calories-counter migrate up|down|version"
+
+// migrate handles `migrate` subcommand: up applies all pending migrations,
+// down reverts the latest one and version prints the current schema version
+func migrate(args []string) {
+	if len(args) != 1 {
+		log.Fatal(migrateUsage)
+	}
+
+	migrator, err := user_datastore.OpenMigrator(dbSource)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = migrator.Close() }()
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down()
+	case "version":
+	default:
+		log.Fatal(migrateUsage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("schema version: %d, latest: %d\n", version, migrator.Latest())
+}
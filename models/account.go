@@ -0,0 +1,32 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Account groups users created by its owner, Name identifies the account at sign in instead of its ID
+type Account struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// DeletedAt is set while deleted account waits to be purged, it keeps its name until then
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"-"`
+}
+
+// AccountRepository resolves accounts, they are created together with their owner by CredentialStore.SaveRootUser
+type AccountRepository interface {
+	GetAccount(ctx context.Context, accountID string) (*Account, error)
+	GetAccountByName(ctx context.Context, name string) (*Account, error)
+	// DeleteAccount marks the account deleted, users of deleted accounts are not found by UserRepository
+	// and CredentialStore until the account is restored. Only hash of the restore token is stored.
+	// Deleting deleted account returns ErrAccountNotFound.
+	DeleteAccount(ctx context.Context, accountID string, deletedAt time.Time, restoreTokenHash string) error
+	// RestoreAccount restores the account deleted since the time with the restore token and forgets the token,
+	// so it can be used only once. It returns ErrAccountNotFound when no such account is deleted.
+	RestoreAccount(ctx context.Context, restoreTokenHash string, deletedSince time.Time) (*Account, error)
+	// GetDeletedAccounts lists accounts deleted before the time, the earliest deleted first
+	GetDeletedAccounts(ctx context.Context, before time.Time) ([]Account, error)
+	// PurgeAccount permanently removes deleted account together with its users and all their data,
+	// it returns ErrAccountNotFound when the account is not deleted
+	PurgeAccount(ctx context.Context, accountID string) error
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// API key scopes, key without scopes has all permissions of its user
+const (
+	ScopeMealsRead   = "meals:read"
+	ScopeMealsWrite  = "meals:write"
+	ScopeUsersManage = "users:manage"
+)
+
+// Scopes lists all valid API key scopes
+var Scopes = []string{ScopeMealsRead, ScopeMealsWrite, ScopeUsersManage}
+
+// APIKey is a long-lived credential of a user for scripts and integrations. Only hash of the key is stored,
+// Prefix is kept to tell keys apart in lists.
+type APIKey struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"-"`
+	AccountID string     `json:"-"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	KeyHash   string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Allows reports whether the key grants scope
+func (k APIKey) Allows(scope string) bool {
+	if len(k.Scopes) == 0 {
+		return true
+	}
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key expired at t
+func (k APIKey) Expired(t time.Time) bool {
+	return k.ExpiresAt != nil && !t.Before(*k.ExpiresAt)
+}
+
+type APIKeyRepository interface {
+	SaveAPIKey(ctx context.Context, key APIKey) error
+	// GetAPIKeys returns keys of the user ordered by creation time
+	GetAPIKeys(ctx context.Context, accountID, userID string) ([]APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	DeleteAPIKey(ctx context.Context, accountID, userID, keyID string) error
+	// DeleteUserAPIKeys deletes all keys of the user, e.g. when its password changes
+	DeleteUserAPIKeys(ctx context.Context, accountID, userID string) error
+}
@@ -1,7 +1,9 @@
 package models
 
+import "context"
+
 //go:generate mockgen -destination=../adapters/calories_datastore/mock.go -package=calories_datastore calories-counter/models CaloriesDatastore
 
 type CaloriesDatastore interface {
-	GetCalories(mealName string) (*int, error)
+	GetCalories(ctx context.Context, mealName string) (*int, error)
 }
@@ -17,26 +17,102 @@ var (
 		Err:  errors.New("user already exist"),
 	}
 
+	ErrRoleAlreadyExists = common.ApiErr{
+		Code: http.StatusConflict,
+		Err:  errors.New("role already exist"),
+	}
+
+	ErrAccountNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("account not found"),
+	}
+
 	ErrUserNotFound = common.ApiErr{
 		Code: http.StatusNotFound,
 		Err:  errors.New("user not found"),
 	}
 
+	ErrRoleNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("role not found"),
+	}
+
+	ErrTeamMemberNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("team member not found"),
+	}
+
+	ErrOwnershipTransferNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("ownership transfer not found"),
+	}
+
+	ErrLastOwner = common.ApiErr{
+		Code: http.StatusConflict,
+		Err:  errors.New("account has to keep at least one owner"),
+	}
+
+	ErrInvitationNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("invitation not found"),
+	}
+
 	ErrMealNotFound = common.ApiErr{
 		Code: http.StatusNotFound,
 		Err:  errors.New("meal not found"),
 	}
 
+	// ErrInvalidFilter and ErrInvalidSort are wrapped by errors pointing to the position of the problem
 	ErrInvalidFilter = common.ApiErr{
 		Code: http.StatusBadRequest,
 		Err:  errors.New("invalid filter"),
 	}
 
+	ErrInvalidSort = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("invalid sort"),
+	}
+
 	ErrInvalidQuery = common.ApiErr{
 		Code: http.StatusBadRequest,
 		Err:  errors.New("invalid query"),
 	}
 
+	ErrInvalidCursor = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("invalid cursor"),
+	}
+
+	ErrCursorWithSort = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("cursor can not be combined with sort"),
+	}
+
+	ErrRefreshTokenNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("refresh token not found"),
+	}
+
+	ErrPasswordResetTokenNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("password reset token not found"),
+	}
+
+	ErrAPIKeyNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("API key not found"),
+	}
+
+	ErrTOTPNotFound = common.ApiErr{
+		Code: http.StatusNotFound,
+		Err:  errors.New("two-factor authentication is not enrolled"),
+	}
+
+	ErrTOTPAlreadyEnabled = common.ApiErr{
+		Code: http.StatusConflict,
+		Err:  errors.New("two-factor authentication is enabled already"),
+	}
+
 	ErrMealCaloriesNotFound = common.ApiErr{
 		Code: http.StatusNotFound,
 		Err:  errors.New("meal calories not found"),
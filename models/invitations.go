@@ -0,0 +1,39 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Invitation lets its invitee create a user with RoleID in the account. Invitation tokens are signed and name
+// the invitation and its TokenID, resending the invitation replaces TokenID so earlier tokens stop working.
+type Invitation struct {
+	ID        string `json:"id"`
+	AccountID string `json:"-"`
+	// Name tells managers whom the invitation is for
+	Name      string    `json:"name"`
+	RoleID    int       `json:"role_id"`
+	InvitedBy string    `json:"invited_by"`
+	TokenID   string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InvitationRepository keeps invitations until they are accepted or revoked
+type InvitationRepository interface {
+	SaveInvitation(ctx context.Context, invitation Invitation) error
+	// GetInvitations returns invitations of the account ordered by creation time, expired ones included
+	GetInvitations(ctx context.Context, accountID string) ([]Invitation, error)
+	GetInvitation(ctx context.Context, accountID, invitationID string) (*Invitation, error)
+	// UpdateInvitation replaces TokenID and ExpiresAt of the invitation
+	UpdateInvitation(ctx context.Context, invitation Invitation) error
+	// DeleteInvitation returns ErrInvitationNotFound when the invitation was deleted already
+	DeleteInvitation(ctx context.Context, accountID, invitationID string) error
+	// UseInvitation deletes the invitation when its token is still tokenID, it returns ErrInvitationNotFound
+	// when the invitation was deleted or resent, so only one of concurrent accepts succeeds and a token
+	// revoked by resending can't be used
+	UseInvitation(ctx context.Context, accountID, invitationID, tokenID string) error
+	// DeleteRoleInvitations revokes invitations of the account with the role, they are revoked when the role
+	// is deleted so its ID, which a new role can get, does not grant the new role
+	DeleteRoleInvitations(ctx context.Context, accountID string, roleID int) error
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// OwnershipTransfer is an offer of account ownership confirmed by an owner, the offered user becomes
+// an owner when it accepts it. The owner who made it becomes an admin unless KeepOwnership is set.
+type OwnershipTransfer struct {
+	AccountID     string    `json:"-"`
+	FromUserID    string    `json:"from_user_id"`
+	ToUserID      string    `json:"to_user_id"`
+	KeepOwnership bool      `json:"keep_ownership"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// OwnershipTransferRepository keeps pending ownership transfers, an account has at most one
+type OwnershipTransferRepository interface {
+	// SaveOwnershipTransfer replaces pending transfer of the account
+	SaveOwnershipTransfer(ctx context.Context, transfer OwnershipTransfer) error
+	GetOwnershipTransfer(ctx context.Context, accountID string) (*OwnershipTransfer, error)
+	DeleteOwnershipTransfer(ctx context.Context, accountID string) error
+	// UseOwnershipTransfer deletes the transfer when it is still pending unchanged, it returns
+	// ErrOwnershipTransferNotFound when the transfer was deleted or replaced, so only one of concurrent
+	// accepts succeeds and a replaced offer can't be accepted
+	UseOwnershipTransfer(ctx context.Context, transfer OwnershipTransfer) error
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// PasswordResetToken is a single use token issued by an admin or a user manager which lets the user
+// set a new password without the old one. Only hash of the token is stored.
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    string
+	AccountID string
+	ExpiresAt time.Time
+}
+
+// PasswordResetRepository keeps password reset tokens, a user has at most one
+type PasswordResetRepository interface {
+	// SavePasswordResetToken replaces previous reset token of the user
+	SavePasswordResetToken(ctx context.Context, token PasswordResetToken) error
+	// UsePasswordResetToken deletes the token and returns it, so it can be used only once
+	UsePasswordResetToken(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+}
@@ -0,0 +1,26 @@
+package models
+
+import "context"
+
+// CustomRoleMinID is the lowest ID of custom roles, lower IDs are reserved for built-in roles
+const CustomRoleMinID = 100
+
+// Role is a named set of permissions, see package policy. Built-in roles are defined by the policy,
+// custom roles are created by account owners and stored by RoleRepository.
+type Role struct {
+	ID          int      `json:"id"`
+	AccountID   string   `json:"-"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleRepository keeps custom roles of accounts, role names are unique in the account
+type RoleRepository interface {
+	// SaveRole creates role with the next free ID of the account, starting at CustomRoleMinID
+	SaveRole(ctx context.Context, role Role) (*Role, error)
+	// GetRoles returns custom roles of the account ordered by ID
+	GetRoles(ctx context.Context, accountID string) ([]Role, error)
+	GetRole(ctx context.Context, accountID string, roleID int) (*Role, error)
+	UpdateRole(ctx context.Context, role Role) (*Role, error)
+	DeleteRole(ctx context.Context, accountID string, roleID int) error
+}
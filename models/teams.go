@@ -0,0 +1,14 @@
+package models
+
+import "context"
+
+// TeamRepository keeps users assigned to teams of managers, managers read meals of their team members
+type TeamRepository interface {
+	// AddTeamMember assigns user to team of manager, assigning a member again is not an error
+	AddTeamMember(ctx context.Context, accountID, managerID, userID string) error
+	RemoveTeamMember(ctx context.Context, accountID, managerID, userID string) error
+	// GetTeamMembers returns members of the manager's team ordered by username
+	GetTeamMembers(ctx context.Context, accountID, managerID string) ([]User, error)
+	// GetTeamManagers returns IDs of managers whose team the user is in
+	GetTeamManagers(ctx context.Context, accountID, userID string) ([]string, error)
+}
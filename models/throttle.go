@@ -0,0 +1,27 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// LoginFailures counts consecutive failed sign ins of a throttle key, e.g. of a username or a client IP
+type LoginFailures struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// UserThrottleKey is the throttle key of failed sign ins of a user, they are deleted together with the user
+func UserThrottleKey(accountID, username string) string {
+	return "user:" + accountID + ":" + username
+}
+
+// LoginThrottleRepository keeps failed sign ins, policy of backoff and lockout is up to the caller
+type LoginThrottleRepository interface {
+	// GetLoginFailures returns zero LoginFailures for key without failures
+	GetLoginFailures(ctx context.Context, key string) (LoginFailures, error)
+	// AddLoginFailure increments failures of key atomically, counting starts again
+	// when the last failure happened before since
+	AddLoginFailure(ctx context.Context, key string, at, since time.Time) error
+	ResetLoginFailures(ctx context.Context, key string) error
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// RefreshToken is a long-lived single use token exchanged for a new access token.
+// Only hash of the token is stored. Tokens issued by rotation share FamilyID with the token issued at sign in.
+type RefreshToken struct {
+	TokenHash string
+	FamilyID  string
+	UserID    string
+	AccountID string
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
+
+// RefreshTokenRepository keeps refresh tokens
+type RefreshTokenRepository interface {
+	SaveRefreshToken(ctx context.Context, token RefreshToken) error
+	// UseRefreshToken marks token as used and returns it as it was before, so token with Used set
+	// means it was presented again
+	UseRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// RevokeRefreshTokenFamily revokes all tokens rotated from the same sign in
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+}
+
+// TokenRevocationStore keeps access tokens revoked before they expire. Access tokens belong to the refresh token
+// family of the sign in, so revoking the family revokes access tokens issued by it as well.
+type TokenRevocationStore interface {
+	// RevokeAccessToken rejects access token with id jti until expiresAt
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeUserTokenFamilies revokes all refresh token families of the user, i.e. signs out all sessions
+	RevokeUserTokenFamilies(ctx context.Context, accountID, userID string) error
+	// IsAccessTokenRevoked reports whether access token jti or its refresh token family is revoked
+	IsAccessTokenRevoked(ctx context.Context, jti, familyID string) (bool, error)
+}
@@ -0,0 +1,33 @@
+package models
+
+import "context"
+
+// TOTP is a TOTP secret of user, it is pending until the user confirms enrollment with a valid code
+type TOTP struct {
+	UserID    string
+	AccountID string
+	Secret    string
+	Enabled   bool
+	// LastStep is the step of the last accepted code, codes can not be used twice
+	LastStep int64
+}
+
+// TwoFactorRepository keeps TOTP secrets, recovery codes and roles which have to use two-factor authentication
+type TwoFactorRepository interface {
+	// SaveTOTP starts enrollment, it replaces pending TOTP of the user
+	// and fails with ErrTOTPAlreadyEnabled when TOTP is enabled already
+	SaveTOTP(ctx context.Context, totp TOTP) error
+	GetTOTP(ctx context.Context, accountID, userID string) (*TOTP, error)
+	// EnableTOTP enables pending TOTP and replaces recovery codes of the user, only hashes of the codes are stored
+	EnableTOTP(ctx context.Context, accountID, userID string, recoveryCodeHashes []string) error
+	// UseTOTPStep records step of an accepted code, it returns false when the step or a later one was used before
+	UseTOTPStep(ctx context.Context, accountID, userID string, step int64) (bool, error)
+	// UseRecoveryCode marks recovery code as used, it returns false when the user has no such unused code
+	UseRecoveryCode(ctx context.Context, accountID, userID, codeHash string) (bool, error)
+	// DeleteTOTP disables two-factor authentication of the user and deletes the recovery codes
+	DeleteTOTP(ctx context.Context, accountID, userID string) error
+
+	// GetTwoFactorRoles returns roles of the account which can not sign in without two-factor authentication
+	GetTwoFactorRoles(ctx context.Context, accountID string) ([]int, error)
+	SetTwoFactorRoles(ctx context.Context, accountID string, roleIDs []int) error
+}
@@ -1,5 +1,7 @@
 package models
 
+import "context"
+
 //go:generate mockgen -destination=../adapters/user_datastore/mock.go -package=user_datastore calories-counter/models UserDatastore
 
 type User struct {
@@ -9,6 +11,13 @@ type User struct {
 	RoleID    int    `json:"role_id" db:"role_id"`
 }
 
+// Password is a stored user password, Hash was produced by Algorithm with Cost, see package password
+type Password struct {
+	Hash      string `db:"password"`
+	Algorithm string `db:"password_algorithm"`
+	Cost      int    `db:"password_cost"`
+}
+
 type UserSlice struct {
 	Items []User `json:"items"`
 	Total int    `json:"total"`
@@ -33,29 +42,119 @@ type Meal struct {
 type MealSlice struct {
 	Items []Meal `json:"items"`
 	Total int    `json:"total"`
+	// NextCursor points after the last item, it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListParams selects page of a list and its order
+type ListParams struct {
+	Page    int
+	PerPage int
+	// Filter is a filter expression, see package filter
+	Filter string
+	// Sort is a comma separated list of fields, field prefixed with `-` is sorted in descending order
+	Sort string
+	// Cursor is MealSlice.NextCursor of the previous page, Page is ignored when it is set.
+	// Cursors are supported only by meals in the default order.
+	Cursor string
+}
+
+// DailyTotal is calories eaten by a user on a day
+type DailyTotal struct {
+	Date            string `json:"date" db:"date"`
+	TotalCalories   int    `json:"total_calories" db:"total_calories"`
+	CaloriesDeficit bool   `json:"calories_deficit" db:"calories_deficit"`
 }
 
 type Settings struct {
 	ExpectedDailyCalories int `json:"expected_daily_calories" db:"expected_daily_calories"`
 }
 
+// CredentialStore keeps users passwords and creates new accounts
+type CredentialStore interface {
+	GetUserPassword(ctx context.Context, accountID, username string) (*Password, error)
+	UpdateUserPassword(ctx context.Context, accountID, userID string, pass Password) error
+	// SaveRootUser creates account named accountName with its owner, account names are unique
+	SaveRootUser(ctx context.Context, accountName, username string, pass Password) (*User, error)
+}
+
+type UserRepository interface {
+	GetUserById(ctx context.Context, accountID, userID string) (*User, error)
+	GetUser(ctx context.Context, accountID, username string) (*User, error)
+	GetUsers(ctx context.Context, accountID string, params ListParams) (UserSlice, error)
+	// CountUsersWithRole returns number of users of the account who have the role
+	CountUsersWithRole(ctx context.Context, accountID string, roleID int) (int, error)
+	SaveUser(ctx context.Context, accountID, username string, pass Password, roleID int) (*User, error)
+	// UpdateUser and DeleteUser return ErrLastOwner when the user is the only owner of its account and would
+	// stop being one, the check and the write are atomic
+	UpdateUser(ctx context.Context, user User) (*User, error)
+	DeleteUser(ctx context.Context, accountID, userID string) error
+}
+
+// MealRepository keeps users meals, writes do not touch daily totals, callers update totals of the affected days
+// with DailyTotalRepository in the same unit of work. CaloriesDeficit of meals returned by writes is not set.
+type MealRepository interface {
+	SaveMeal(ctx context.Context, userID string, meal Meal) (*Meal, error)
+	GetMeals(ctx context.Context, userID string, params ListParams) (MealSlice, error)
+	GetMeal(ctx context.Context, userID, mealID string) (*Meal, error)
+	UpdateMeal(ctx context.Context, userID string, meal Meal) (*Meal, error)
+	DeleteMeal(ctx context.Context, userID string, mealID string) error
+}
+
+type SettingsRepository interface {
+	UpdateSettings(ctx context.Context, userID string, settings Settings) (*Settings, error)
+	GetSettings(ctx context.Context, userID string) (*Settings, error)
+}
+
+// DailyTotalRepository keeps calories eaten by users per day
+type DailyTotalRepository interface {
+	// UpdateDailyTotal recalculates calories eaten by user on date (YYYY-MM-DD),
+	// returns true when they are below users expected daily calories
+	UpdateDailyTotal(ctx context.Context, userID, date string) (bool, error)
+	// GetDailyTotals returns totals of the user's days from date to date (YYYY-MM-DD) ordered by date,
+	// empty from or to leaves the range open, days without meals have no total
+	GetDailyTotals(ctx context.Context, userID, from, to string) ([]DailyTotal, error)
+}
+
+// Repositories are the repositories sharing one unit of work, callers use only the ones they need
+type Repositories struct {
+	Credentials        CredentialStore
+	Revocations        TokenRevocationStore
+	APIKeys            APIKeyRepository
+	Users              UserRepository
+	Meals              MealRepository
+	DailyTotals        DailyTotalRepository
+	Settings           SettingsRepository
+	OwnershipTransfers OwnershipTransferRepository
+	Roles              RoleRepository
+	TwoFactor          TwoFactorRepository
+	Teams              TeamRepository
+	Invitations        InvitationRepository
+}
+
+type UnitOfWork interface {
+	// Do runs fn in a single transaction, writes made through repos are committed when fn returns nil
+	// and rolled back otherwise
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+// UserDatastore is implemented by stores which keep all the data in one database
 type UserDatastore interface {
-	GetUserPassword(accountID, username string) (*string, error)
-	SaveRootUser(username, pass string) (*User, error)
-
-	GetUserById(accountID, userID string) (*User, error)
-	GetUser(accountID, username string) (*User, error)
-	GetUsers(accountID string, page, perPage int, filter string) (UserSlice, error)
-	SaveUser(accountID, username, pass string, roleID int) (*User, error)
-	UpdateUser(user User) (*User, error)
-	DeleteUser(accountID, userID string) error
-
-	SaveMeal(userID string, meal Meal) (*Meal, error)
-	GetMeals(userID string, page, perPage int, filter string) (MealSlice, error)
-	GetMeal(userID, mealID string) (*Meal, error)
-	UpdateMeal(userID string, meal Meal) (*Meal, error)
-	DeleteMeal(userID string, mealID string) error
-
-	UpdateSettings(userID string, settings Settings) (*Settings, error)
-	GetSettings(userID string) (*Settings, error)
+	CredentialStore
+	AccountRepository
+	UserRepository
+	MealRepository
+	SettingsRepository
+	DailyTotalRepository
+	RefreshTokenRepository
+	TokenRevocationStore
+	TwoFactorRepository
+	LoginThrottleRepository
+	PasswordResetRepository
+	APIKeyRepository
+	RoleRepository
+	TeamRepository
+	OwnershipTransferRepository
+	InvitationRepository
+	UnitOfWork
 }
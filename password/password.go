@@ -0,0 +1,56 @@
+// Package password hashes users passwords and verifies them in constant time.
+// Stored passwords record the algorithm and cost they were hashed with, so the cost can be raised
+// and legacy plaintext passwords can be rehashed when their users sign in.
+package password
+
+import (
+	"calories-counter/models"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Plaintext marks passwords stored before hashing was introduced
+	Plaintext = "plaintext"
+	Bcrypt    = "bcrypt"
+)
+
+// Cost is the bcrypt cost of new hashes
+var Cost = bcrypt.DefaultCost
+
+// Hash returns bcrypt hash of plain password
+func Hash(plain string) (models.Password, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plain), Cost)
+	if err != nil {
+		return models.Password{}, err
+	}
+
+	return models.Password{
+		Hash:      string(hash),
+		Algorithm: Bcrypt,
+		Cost:      Cost,
+	}, nil
+}
+
+// Verify reports whether plain matches stored password
+func Verify(stored models.Password, plain string) (bool, error) {
+	switch stored.Algorithm {
+	case Bcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(stored.Hash), []byte(plain))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case Plaintext:
+		return subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(plain)) == 1, nil
+	}
+
+	return false, fmt.Errorf("unknown password algorithm %q", stored.Algorithm)
+}
+
+// NeedsRehash reports whether stored password should be hashed again with the current algorithm and cost
+func NeedsRehash(stored models.Password) bool {
+	return stored.Algorithm != Bcrypt || stored.Cost != Cost
+}
@@ -0,0 +1,56 @@
+package password
+
+import (
+	"calories-counter/models"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestHashVerify(t *testing.T) {
+	hash, err := Hash("Xyz123")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if hash.Algorithm != Bcrypt || hash.Cost != Cost || hash.Hash == "Xyz123" {
+		t.Errorf("Expected bcrypt hash with cost %d but was %+v", Cost, hash)
+	}
+	if NeedsRehash(hash) {
+		t.Errorf("Expected new hash not to need rehash")
+	}
+
+	testCases := []struct {
+		name     string
+		stored   models.Password
+		plain    string
+		expected bool
+	}{
+		{name: "Bcrypt", stored: hash, plain: "Xyz123", expected: true},
+		{name: "BcryptMismatch", stored: hash, plain: "Xyz124", expected: false},
+		{name: "Plaintext", stored: models.Password{Hash: "Xyz123", Algorithm: Plaintext}, plain: "Xyz123", expected: true},
+		{name: "PlaintextMismatch", stored: models.Password{Hash: "Xyz123", Algorithm: Plaintext}, plain: "Xyz12", expected: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, err := Verify(tc.stored, tc.plain)
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if ok != tc.expected {
+				t.Errorf("Expected %v but was %v", tc.expected, ok)
+			}
+		})
+	}
+
+	if _, err := Verify(models.Password{Hash: "x", Algorithm: "md5"}, "x"); err == nil {
+		t.Errorf("Expected unknown algorithm to fail")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	if !NeedsRehash(models.Password{Hash: "Xyz123", Algorithm: Plaintext}) {
+		t.Errorf("Expected plaintext password to need rehash")
+	}
+	if !NeedsRehash(models.Password{Hash: "x", Algorithm: Bcrypt, Cost: bcrypt.MinCost}) {
+		t.Errorf("Expected password with lower cost to need rehash")
+	}
+}
@@ -0,0 +1,193 @@
+// Package policy decides what users can do. Permissions are granted to roles, built-in roles have fixed
+// permissions and accounts can define custom roles with any permission except AccountManage.
+package policy
+
+import (
+	"calories-counter/models"
+	"strings"
+)
+
+// Permissions, `:own` ones apply to the data of the user itself, `:team` ones to standard users assigned
+// to the user and `:any` ones to all users of the account. `:any` does not include `:own`, users who manage
+// meals of others reach their own meals only through the routes of a target user.
+// Standard users are users whose role does not manage users nor the account.
+const (
+	MealsReadOwn       = "meals:read:own"
+	MealsReadTeam      = "meals:read:team"
+	MealsReadAny       = "meals:read:any"
+	MealsWriteOwn      = "meals:write:own"
+	MealsWriteAny      = "meals:write:any"
+	UsersRead          = "users:read"
+	UsersWriteStandard = "users:write:standard"
+	UsersWriteAny      = "users:write:any"
+	AccountManage      = "account:manage"
+)
+
+// Permissions lists all valid permissions
+var Permissions = []string{
+	MealsReadOwn, MealsReadTeam, MealsReadAny, MealsWriteOwn, MealsWriteAny, UsersRead, UsersWriteStandard,
+	UsersWriteAny, AccountManage,
+}
+
+// implied maps permissions to the narrower ones they include
+var implied = map[string][]string{
+	MealsReadAny:  {MealsReadTeam},
+	UsersWriteAny: {UsersWriteStandard},
+}
+
+var builtinRoles = []models.Role{
+	{ID: models.UserRole, Name: "user", Permissions: []string{MealsReadOwn, MealsWriteOwn}},
+	{ID: models.UserManagerRole, Name: "user manager",
+		Permissions: []string{MealsReadTeam, UsersRead, UsersWriteStandard}},
+	{ID: models.AdminRole, Name: "admin", Permissions: []string{MealsReadAny, MealsWriteAny, UsersRead, UsersWriteAny}},
+	{ID: models.OwnerRole, Name: "owner", Permissions: []string{UsersRead, UsersWriteAny, AccountManage}},
+}
+
+// Action is what a user does, it is allowed by the own, team, any or standard variant of a permission
+// depending on the target of the action
+type Action string
+
+const (
+	// ReadMeals and WriteMeals cover meals and settings of the target
+	ReadMeals  Action = "meals:read"
+	WriteMeals Action = "meals:write"
+	ReadUsers  Action = "users:read"
+	// WriteUser creates, updates or deletes the target and manages its credentials
+	WriteUser     Action = "users:write"
+	ManageAccount Action = "account:manage"
+)
+
+// Subject is a user with its resolved role
+type Subject struct {
+	UserID string
+	Role   models.Role
+	// ManagerIDs are users whose team the subject is in, they are needed only when RequiresTeam
+	ManagerIDs []string
+}
+
+// BuiltinRoles returns roles every account has
+func BuiltinRoles() []models.Role {
+	roles := make([]models.Role, len(builtinRoles))
+	for i, role := range builtinRoles {
+		roles[i] = role
+		roles[i].Permissions = append([]string{}, role.Permissions...)
+	}
+	return roles
+}
+
+// BuiltinRole returns built-in role with roleID, ok is false for custom roles
+func BuiltinRole(roleID int) (role models.Role, ok bool) {
+	for _, role := range BuiltinRoles() {
+		if role.ID == roleID {
+			return role, true
+		}
+	}
+	return models.Role{}, false
+}
+
+// ValidPermission reports whether custom roles can be granted permission
+func ValidPermission(permission string) bool {
+	if permission == AccountManage {
+		return false
+	}
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether role grants permission directly or through a broader one
+func Has(role models.Role, permission string) bool {
+	for _, p := range role.Permissions {
+		if p == permission {
+			return true
+		}
+		for _, i := range implied[p] {
+			if i == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Standard reports whether role neither manages users nor the account
+func Standard(role models.Role) bool {
+	for _, p := range role.Permissions {
+		if strings.HasPrefix(p, "users:") || strings.HasPrefix(p, "account:") {
+			return false
+		}
+	}
+	return true
+}
+
+// Can decides whether actor can do action to target. Target is nil for actions which are not done
+// to a particular user, meals and settings without target are the actor's own.
+func Can(actor Subject, action Action, target *Subject) bool {
+	switch action {
+	case ReadMeals, WriteMeals:
+		if target == nil {
+			return Has(actor.Role, string(action)+":own")
+		}
+		if target.UserID == actor.UserID && Has(actor.Role, string(action)+":own") {
+			return true
+		}
+		if Has(actor.Role, string(action)+":any") {
+			return true
+		}
+		return action == ReadMeals && Has(actor.Role, MealsReadTeam) && Standard(target.Role) &&
+			inTeam(actor.UserID, *target)
+	case WriteUser:
+		if target == nil || Has(actor.Role, UsersWriteAny) {
+			return Has(actor.Role, UsersWriteStandard)
+		}
+		// users who manage only standard users could take over users with permissions they do not have
+		return Standard(target.Role) && Has(actor.Role, UsersWriteStandard) && holdsAll(actor.Role, target.Role)
+	case ReadUsers, ManageAccount:
+		return Has(actor.Role, string(action))
+	}
+	return false
+}
+
+// RequiresTeam reports whether Can needs ManagerIDs of the target to decide action of actor
+func RequiresTeam(actor Subject, action Action) bool {
+	return action == ReadMeals && Has(actor.Role, MealsReadTeam) && !Has(actor.Role, MealsReadAny)
+}
+
+func inTeam(managerID string, member Subject) bool {
+	for _, id := range member.ManagerIDs {
+		if id == managerID {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAssign decides whether actor can give role to a user, owner role is never assigned, users who manage
+// only standard users assign only standard roles and nobody grants a permission it does not have except
+// `:own` ones. Account managers define roles with any permission, so they are not limited to their own.
+func CanAssign(actor models.Role, role models.Role) bool {
+	if role.ID == models.OwnerRole || Has(role, AccountManage) {
+		return false
+	}
+	write := UsersWriteAny
+	if Standard(role) {
+		write = UsersWriteStandard
+	}
+	if !Has(actor, write) {
+		return false
+	}
+	return Has(actor, AccountManage) || holdsAll(actor, role)
+}
+
+// holdsAll reports whether actor has every permission of role except `:own` ones
+func holdsAll(actor models.Role, role models.Role) bool {
+	for _, p := range role.Permissions {
+		if !strings.HasSuffix(p, ":own") && !Has(actor, p) {
+			return false
+		}
+	}
+	return true
+}
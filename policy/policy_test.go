@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"calories-counter/models"
+	"testing"
+)
+
+func builtin(t *testing.T, roleID int) models.Role {
+	role, ok := BuiltinRole(roleID)
+	if !ok {
+		t.Fatalf("Expected built-in role %d", roleID)
+	}
+	return role
+}
+
+func TestCan(t *testing.T) {
+	user := Subject{UserID: "user", Role: builtin(t, models.UserRole)}
+	otherUser := Subject{UserID: "other", Role: builtin(t, models.UserRole)}
+	manager := Subject{UserID: "manager", Role: builtin(t, models.UserManagerRole)}
+	otherManager := Subject{UserID: "other manager", Role: builtin(t, models.UserManagerRole)}
+	admin := Subject{UserID: "admin", Role: builtin(t, models.AdminRole)}
+	owner := Subject{UserID: "owner", Role: builtin(t, models.OwnerRole)}
+	teamMember := Subject{UserID: "member", Role: builtin(t, models.UserRole), ManagerIDs: []string{"manager"}}
+	teamManager := Subject{UserID: "team manager", Role: builtin(t, models.UserManagerRole), ManagerIDs: []string{"manager"}}
+	auditor := Subject{UserID: "auditor", Role: models.Role{ID: 100, Name: "auditor",
+		Permissions: []string{MealsReadAny, UsersRead}}}
+	dietitian := Subject{UserID: "dietitian", Role: models.Role{ID: 101, Name: "dietitian",
+		Permissions: []string{MealsReadAny, MealsWriteAny}}}
+	coach := Subject{UserID: "coach", Role: models.Role{ID: 102, Name: "coach",
+		Permissions: []string{MealsReadOwn, MealsWriteOwn, MealsReadTeam}}}
+
+	testCases := []struct {
+		name     string
+		actor    Subject
+		action   Action
+		target   *Subject
+		expected bool
+	}{
+		{name: "UserReadsOwnMeals", actor: user, action: ReadMeals, expected: true},
+		{name: "UserWritesOwnMealsAsTarget", actor: user, action: WriteMeals, target: &user, expected: true},
+		{name: "UserReadsMealsOfOtherUser", actor: user, action: ReadMeals, target: &otherUser},
+		{name: "UserReadsUsers", actor: user, action: ReadUsers},
+		{name: "ManagerReadsOwnMeals", actor: manager, action: ReadMeals},
+		{name: "ManagerReadsUsers", actor: manager, action: ReadUsers, expected: true},
+		{name: "ManagerCreatesUser", actor: manager, action: WriteUser, expected: true},
+		{name: "ManagerWritesStandardUser", actor: manager, action: WriteUser, target: &user, expected: true},
+		{name: "ManagerWritesCustomStandardUser", actor: manager, action: WriteUser, target: &coach, expected: true},
+		// the manager could reset password of the dietitian and write meals of any user
+		{name: "ManagerWritesStandardUserWithBroaderPermissions", actor: manager, action: WriteUser, target: &dietitian},
+		{name: "ManagerWritesManager", actor: manager, action: WriteUser, target: &otherManager},
+		{name: "ManagerWritesCustomManagingUser", actor: manager, action: WriteUser, target: &auditor},
+		{name: "ManagerManagesAccount", actor: manager, action: ManageAccount},
+		{name: "ManagerReadsMealsOfTeamMember", actor: manager, action: ReadMeals, target: &teamMember, expected: true},
+		{name: "ManagerWritesMealsOfTeamMember", actor: manager, action: WriteMeals, target: &teamMember},
+		{name: "ManagerReadsMealsOfUserOutOfTeam", actor: manager, action: ReadMeals, target: &user},
+		{name: "ManagerReadsMealsOfNonStandardTeamMember", actor: manager, action: ReadMeals, target: &teamManager},
+		{name: "OtherManagerReadsMealsOfTeamMember", actor: otherManager, action: ReadMeals, target: &teamMember},
+		{name: "AdminReadsOwnMeals", actor: admin, action: ReadMeals},
+		{name: "AdminWritesOwnMeals", actor: admin, action: WriteMeals},
+		{name: "AdminWritesOwnMealsAsTarget", actor: admin, action: WriteMeals, target: &admin, expected: true},
+		{name: "AdminWritesMealsOfUser", actor: admin, action: WriteMeals, target: &user, expected: true},
+		{name: "AdminWritesManager", actor: admin, action: WriteUser, target: &manager, expected: true},
+		{name: "AdminWritesOwner", actor: admin, action: WriteUser, target: &owner, expected: true},
+		{name: "AdminManagesAccount", actor: admin, action: ManageAccount},
+		{name: "OwnerReadsMealsOfUser", actor: owner, action: ReadMeals, target: &user},
+		{name: "OwnerWritesAdmin", actor: owner, action: WriteUser, target: &admin, expected: true},
+		{name: "OwnerManagesAccount", actor: owner, action: ManageAccount, expected: true},
+		{name: "AuditorReadsMealsOfUser", actor: auditor, action: ReadMeals, target: &user, expected: true},
+		{name: "AuditorWritesMealsOfUser", actor: auditor, action: WriteMeals, target: &user},
+		{name: "AuditorReadsUsers", actor: auditor, action: ReadUsers, expected: true},
+		{name: "AuditorWritesUser", actor: auditor, action: WriteUser, target: &user},
+		{name: "DietitianWritesMealsOfUser", actor: dietitian, action: WriteMeals, target: &user, expected: true},
+		{name: "UnknownAction", actor: owner, action: Action("users:delete")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if allowed := Can(tc.actor, tc.action, tc.target); allowed != tc.expected {
+				t.Errorf("Expected %v but was %v", tc.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestRequiresTeam(t *testing.T) {
+	manager := Subject{UserID: "manager", Role: builtin(t, models.UserManagerRole)}
+	admin := Subject{UserID: "admin", Role: builtin(t, models.AdminRole)}
+	if !RequiresTeam(manager, ReadMeals) {
+		t.Errorf("Expected team of manager to be needed to read meals")
+	}
+	if RequiresTeam(manager, WriteMeals) || RequiresTeam(admin, ReadMeals) {
+		t.Errorf("Expected team to be needed only for team permissions")
+	}
+}
+
+func TestCanAssign(t *testing.T) {
+	custom := models.Role{ID: 100, Name: "dietitian", Permissions: []string{MealsReadAny}}
+	teamReader := models.Role{ID: 103, Name: "coach", Permissions: []string{MealsReadOwn, MealsWriteOwn, MealsReadTeam}}
+	customManager := models.Role{ID: 101, Name: "auditor", Permissions: []string{UsersRead}}
+	customOwner := models.Role{ID: 102, Name: "co-owner", Permissions: []string{AccountManage}}
+
+	testCases := []struct {
+		name     string
+		actor    int
+		role     models.Role
+		expected bool
+	}{
+		{name: "ManagerAssignsUser", actor: models.UserManagerRole, role: builtin(t, models.UserRole), expected: true},
+		{name: "ManagerAssignsCustomStandardWithOwnPermissions", actor: models.UserManagerRole, role: teamReader,
+			expected: true},
+		{name: "ManagerAssignsCustomStandardWithBroaderPermission", actor: models.UserManagerRole, role: custom},
+		{name: "ManagerAssignsManager", actor: models.UserManagerRole, role: builtin(t, models.UserManagerRole)},
+		{name: "ManagerAssignsCustomManager", actor: models.UserManagerRole, role: customManager},
+		{name: "AdminAssignsAdmin", actor: models.AdminRole, role: builtin(t, models.AdminRole), expected: true},
+		{name: "AdminAssignsCustomManager", actor: models.AdminRole, role: customManager, expected: true},
+		{name: "AdminAssignsCustomStandard", actor: models.AdminRole, role: custom, expected: true},
+		{name: "OwnerAssignsAdmin", actor: models.OwnerRole, role: builtin(t, models.AdminRole), expected: true},
+		{name: "AdminAssignsOwner", actor: models.AdminRole, role: builtin(t, models.OwnerRole)},
+		{name: "OwnerAssignsOwner", actor: models.OwnerRole, role: builtin(t, models.OwnerRole)},
+		{name: "OwnerAssignsCustomOwner", actor: models.OwnerRole, role: customOwner},
+		{name: "UserAssignsUser", actor: models.UserRole, role: builtin(t, models.UserRole)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if allowed := CanAssign(builtin(t, tc.actor), tc.role); allowed != tc.expected {
+				t.Errorf("Expected %v but was %v", tc.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestHas(t *testing.T) {
+	admin := builtin(t, models.AdminRole)
+	for _, p := range []string{MealsReadTeam, UsersWriteStandard, UsersWriteAny} {
+		if !Has(admin, p) {
+			t.Errorf("Expected admin to have %s", p)
+		}
+	}
+	for _, p := range []string{MealsReadOwn, MealsWriteOwn, AccountManage} {
+		if Has(admin, p) {
+			t.Errorf("Expected admin not to have %s", p)
+		}
+	}
+	if Has(builtin(t, models.UserRole), MealsReadAny) {
+		t.Errorf("Expected own permission not to imply %s", MealsReadAny)
+	}
+}
+
+func TestValidPermission(t *testing.T) {
+	if !ValidPermission(UsersWriteStandard) {
+		t.Errorf("Expected %s to be valid", UsersWriteStandard)
+	}
+	if ValidPermission(AccountManage) {
+		t.Errorf("Expected %s not to be granted to custom roles", AccountManage)
+	}
+	if ValidPermission("meals:delete") {
+		t.Errorf("Expected unknown permission to be invalid")
+	}
+}
+
+func TestBuiltinRole(t *testing.T) {
+	role := builtin(t, models.UserRole)
+	role.Permissions[0] = AccountManage
+	if Has(builtin(t, models.UserRole), AccountManage) {
+		t.Errorf("Expected built-in roles not to be modified through returned role")
+	}
+	if _, ok := BuiltinRole(models.CustomRoleMinID); ok {
+		t.Errorf("Expected custom role ID not to be built-in")
+	}
+}
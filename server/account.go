@@ -0,0 +1,139 @@
+package server
+
+import (
+	"calories-counter/models"
+	"context"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// AccountDeletionGracePeriod is how long deleted account can be restored before its data is purged
+const AccountDeletionGracePeriod = 30 * 24 * time.Hour
+
+// AccountDeletionResponse is returned to the owner who deleted the account,
+// RestoreToken restores the account once until it is purged at PurgeAt
+type AccountDeletionResponse struct {
+	models.Account
+	PurgeAt      time.Time `json:"purge_at"`
+	RestoreToken string    `json:"restore_token"`
+}
+
+// GetAccount returns account of the caller, its name is used to sign in
+func (s *Server) GetAccount(c *gin.Context) {
+	caller := getCaller(c)
+
+	account, err := s.accounts.GetAccount(c.Request.Context(), caller.AccountID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// DeleteAccount deletes account of the caller, the owner confirms it with its password. Users of the account
+// can't sign in and their tokens stop working at once, the owner can restore the account with the returned
+// restore token until it is purged after AccountDeletionGracePeriod.
+func (s *Server) DeleteAccount(c *gin.Context) {
+	caller := getCaller(c)
+	ctx := c.Request.Context()
+	var body AccountDeleteBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := s.verifyCallerPassword(c, body.Password); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	account, err := s.accounts.GetAccount(ctx, caller.AccountID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	restoreToken, err := randomToken()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	// the store keeps deletion time in seconds
+	deletedAt := time.Unix(time.Now().Unix(), 0)
+	err = s.accounts.DeleteAccount(ctx, account.ID, deletedAt, hashToken(restoreToken))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	account.DeletedAt = &deletedAt
+
+	c.JSON(http.StatusAccepted, AccountDeletionResponse{
+		Account:      *account,
+		PurgeAt:      deletedAt.Add(AccountDeletionGracePeriod),
+		RestoreToken: restoreToken,
+	})
+}
+
+// RestoreAccount restores deleted account with form field restore_token, its users can sign in again.
+// The token is used up, so it does not restore the account after it is deleted again.
+func (s *Server) RestoreAccount(c *gin.Context) {
+	restoreToken := c.PostForm("restore_token")
+	if restoreToken == "" {
+		handleErrorResponse(c, ErrMissingRestoreToken)
+		return
+	}
+
+	deletedSince := time.Now().Add(-AccountDeletionGracePeriod)
+	account, err := s.accounts.RestoreAccount(c.Request.Context(), hashToken(restoreToken), deletedSince)
+	if err == models.ErrAccountNotFound {
+		handleErrorResponse(c, ErrInvalidRestoreToken)
+		return
+	} else if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// PurgeDeletedAccounts permanently removes accounts deleted more than AccountDeletionGracePeriod ago
+func (s *Server) PurgeDeletedAccounts(ctx context.Context) error {
+	accounts, err := s.accounts.GetDeletedAccounts(ctx, time.Now().Add(-AccountDeletionGracePeriod))
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		err := s.accounts.PurgeAccount(ctx, account.ID)
+		if err == models.ErrAccountNotFound {
+			// restored or purged by another instance in the meantime
+			continue
+		} else if err != nil {
+			return err
+		}
+		log.WithField("account_id", account.ID).Info("purged deleted account")
+	}
+	return nil
+}
+
+// RunAccountPurge purges deleted accounts every interval until ctx is done, failed purges are retried
+// with the next run
+func (s *Server) RunAccountPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.PurgeDeletedAccounts(ctx); err != nil {
+			log.WithError(err).Error("couldn't purge deleted accounts")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/models"
+	passwords "calories-counter/password"
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGetAccount(t *testing.T) {
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "AccountNotFound",
+			caller:        models.User{ID: "3", AccountID: "1"},
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrAccountNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAccount(gomock.Any(), "1").Return(nil, models.ErrAccountNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "AccountFound",
+			caller:       models.User{ID: "3", AccountID: "1"},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":"1","name":"acme"}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAccount(gomock.Any(), "1").Return(&models.Account{ID: "1", Name: "acme"}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "GET", "/v1/account", tc)
+		})
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	hash, err := passwords.Hash("Xyz123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caller := models.User{ID: "3", AccountID: "1", Username: "owner", RoleID: models.OwnerRole}
+	var restoreTokenHashes []string
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "MissingPassword",
+			caller:        caller,
+			body:          `{}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingPassword,
+		},
+		{
+			name:          "WrongPassword",
+			caller:        caller,
+			body:          `{"password": "Xyz124"}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrWrongPassword,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:owner")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "owner").Return(&hash, nil)
+				expectSignInFailure(m, "user:1:owner")
+			},
+		},
+		{
+			name:          "ErrWhenDeleteAccount",
+			caller:        caller,
+			body:          `{"password": "Xyz123"}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:owner")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "owner").Return(&hash, nil)
+				m.EXPECT().GetAccount(gomock.Any(), "1").Return(&models.Account{ID: "1", Name: "acme"}, nil)
+				m.EXPECT().DeleteAccount(gomock.Any(), "1", gomock.Any(), gomock.Any()).Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "AccountDeleted",
+			caller:       caller,
+			body:         `{"password": "Xyz123"}`,
+			expectedCode: http.StatusAccepted,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:owner")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "owner").Return(&hash, nil)
+				m.EXPECT().GetAccount(gomock.Any(), "1").Return(&models.Account{ID: "1", Name: "acme"}, nil)
+				m.EXPECT().DeleteAccount(gomock.Any(), "1", gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, deletedAt time.Time, restoreTokenHash string) error {
+					if time.Since(deletedAt) > time.Minute || deletedAt.Nanosecond() != 0 {
+						t.Errorf("Unexpected deletion time %v", deletedAt)
+					}
+					restoreTokenHashes = append(restoreTokenHashes, restoreTokenHash)
+					return nil
+				})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "DELETE", "/v1/account", tc)
+		})
+	}
+	// only hash of the returned restore token is stored
+	if len(restoreTokenHashes) != 1 || len(restoreTokenHashes[0]) != 64 {
+		t.Errorf("Expected hash of restore token to be stored but was %v", restoreTokenHashes)
+	}
+}
+
+func TestRestoreAccount(t *testing.T) {
+	form := url.Values{}
+	form.Set("restore_token", "token")
+	// accounts deleted before the grace period are not restored
+	deletedSince := gomock.AssignableToTypeOf(time.Time{})
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "MissingToken",
+			postForm:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingRestoreToken,
+		},
+		{
+			name:          "InvalidToken",
+			postForm:      true,
+			body:          form.Encode(),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidRestoreToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().RestoreAccount(gomock.Any(), hashToken("token"), deletedSince).Return(nil, models.ErrAccountNotFound)
+			},
+		},
+		{
+			name:          "ErrWhenRestoreAccount",
+			postForm:      true,
+			body:          form.Encode(),
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().RestoreAccount(gomock.Any(), hashToken("token"), deletedSince).Return(nil, errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "AccountRestored",
+			postForm:     true,
+			body:         form.Encode(),
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":"1","name":"acme"}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().RestoreAccount(gomock.Any(), hashToken("token"), deletedSince).DoAndReturn(
+					func(_ context.Context, _ string, deletedSince time.Time) (*models.Account, error) {
+						if d := time.Since(deletedSince) - AccountDeletionGracePeriod; d < 0 || d > time.Minute {
+							t.Errorf("Unexpected restore of accounts deleted since %v", deletedSince)
+						}
+						return &models.Account{ID: "1", Name: "acme"}, nil
+					})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/restore", tc)
+		})
+	}
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	deletedAt := time.Now().Add(-AccountDeletionGracePeriod - time.Hour)
+	accounts := []models.Account{{ID: "1", DeletedAt: &deletedAt}, {ID: "2", DeletedAt: &deletedAt}}
+	testCases := []struct {
+		name          string
+		setupMockUser func(m *user_datastore.MockUserDatastore)
+		expectedError bool
+	}{
+		{
+			name: "ErrWhenGetDeletedAccounts",
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetDeletedAccounts(gomock.Any(), gomock.Any()).Return(nil, errors.New("err"))
+			},
+			expectedError: true,
+		},
+		{
+			name: "ErrWhenPurgeAccount",
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetDeletedAccounts(gomock.Any(), gomock.Any()).Return(accounts, nil)
+				m.EXPECT().PurgeAccount(gomock.Any(), "1").Return(errors.New("err"))
+			},
+			expectedError: true,
+		},
+		{
+			name: "RestoredAccountSkipped",
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetDeletedAccounts(gomock.Any(), gomock.Any()).Return(accounts, nil)
+				m.EXPECT().PurgeAccount(gomock.Any(), "1").Return(models.ErrAccountNotFound)
+				m.EXPECT().PurgeAccount(gomock.Any(), "2").Return(nil)
+			},
+		},
+		{
+			name: "AccountsPastGracePeriodPurged",
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetDeletedAccounts(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, before time.Time) ([]models.Account, error) {
+					if d := time.Since(before) - AccountDeletionGracePeriod; d < 0 || d > time.Minute {
+						t.Errorf("Expected accounts deleted before %v to be purged", before)
+					}
+					return accounts, nil
+				})
+				m.EXPECT().PurgeAccount(gomock.Any(), "1").Return(nil)
+				m.EXPECT().PurgeAccount(gomock.Any(), "2").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+			mockUD := user_datastore.NewMockUserDatastore(controller)
+			tc.setupMockUser(mockUD)
+			s := &Server{accounts: mockUD}
+
+			err := s.PurgeDeletedAccounts(context.Background())
+			if (err != nil) != tc.expectedError {
+				t.Errorf("Expected error %t but was %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"calories-counter/models"
+	"calories-counter/policy"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// APIKeyPrefix starts every API key, it tells API keys apart from access tokens in Authorization header
+	APIKeyPrefix = "cc_"
+	// apiKeyDisplayLength is length of the start of the key shown in lists
+	apiKeyDisplayLength = len(APIKeyPrefix) + 6
+)
+
+// APIKeyResponse is returned when the key is created, Key is not shown again
+type APIKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// verifyAPIKey returns the key and its user, unknown and expired keys are unauthorized
+func (s *Server) verifyAPIKey(c *gin.Context, key string) (*models.APIKey, *models.User, error) {
+	stored, err := s.apiKeys.GetAPIKeyByHash(c.Request.Context(), hashToken(key))
+	if err == models.ErrAPIKeyNotFound {
+		return nil, nil, ErrUnauthorized
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if stored.Expired(time.Now()) {
+		return nil, nil, ErrUnauthorized
+	}
+
+	user, err := s.users.GetUserById(c.Request.Context(), stored.AccountID, stored.UserID)
+	if err != nil {
+		return nil, nil, ErrUnauthorized
+	}
+	return stored, user, nil
+}
+
+// CreateAPIKey creates API key of the caller, only users who can read users can create keys with users:manage scope
+func (s *Server) CreateAPIKey(c *gin.Context) {
+	caller := getCaller(c)
+	var body APIKeyPostBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	for _, scope := range body.Scopes {
+		if scope != models.ScopeUsersManage {
+			continue
+		}
+		if err := s.authorize(c, policy.ReadUsers, nil); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	key := APIKeyPrefix + token
+	apiKey := models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    caller.ID,
+		AccountID: caller.AccountID,
+		Name:      body.Name,
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   hashToken(key),
+		Scopes:    append([]string{}, body.Scopes...),
+		ExpiresAt: body.ExpiresAt,
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+	if err := s.apiKeys.SaveAPIKey(c.Request.Context(), apiKey); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIKeyResponse{APIKey: apiKey, Key: key})
+}
+
+func (s *Server) GetAPIKeys(c *gin.Context) {
+	caller := getCaller(c)
+
+	keys, err := s.apiKeys.GetAPIKeys(c.Request.Context(), caller.AccountID, caller.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": keys})
+}
+
+// DeleteAPIKey revokes API key of the caller
+func (s *Server) DeleteAPIKey(c *gin.Context) {
+	caller := getCaller(c)
+
+	err := s.apiKeys.DeleteAPIKey(c.Request.Context(), caller.AccountID, caller.ID, c.Param("key_id"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
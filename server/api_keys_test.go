@@ -0,0 +1,165 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", Username: "user", RoleID: models.UserRole}
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "MissingName",
+			caller:        caller,
+			body:          `{"scopes": ["meals:read"]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingName,
+		},
+		{
+			name:          "InvalidScope",
+			caller:        caller,
+			body:          `{"name": "script", "scopes": ["meals:delete"]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidScope,
+		},
+		{
+			name:          "DuplicateScope",
+			caller:        caller,
+			body:          `{"name": "script", "scopes": ["meals:read", "meals:read"]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidScope,
+		},
+		{
+			name:          "ExpiredInPast",
+			caller:        caller,
+			body:          `{"name": "script", "expires_at": "2020-01-01T00:00:00Z"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidExpiry,
+		},
+		{
+			name:          "InsufficientPermissionForUserToManageUsers",
+			caller:        caller,
+			body:          `{"name": "script", "scopes": ["users:manage"]}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+		},
+		{
+			name:          "ErrWhenSaveAPIKey",
+			caller:        caller,
+			body:          `{"name": "script"}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveAPIKey(gomock.Any(), gomock.Any()).Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "APIKeyCreated",
+			caller:       caller,
+			body:         fmt.Sprintf(`{"name": "script", "scopes": ["meals:read"], "expires_at": "%s"}`, expiresAt),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveAPIKey(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key models.APIKey) error {
+					if key.UserID != "3" || key.AccountID != "1" || key.Name != "script" || len(key.KeyHash) != 64 ||
+						!strings.HasPrefix(key.Prefix, APIKeyPrefix) || len(key.Scopes) != 1 || key.ExpiresAt == nil {
+						t.Errorf("Unexpected API key %+v", key)
+					}
+					return nil
+				})
+			},
+		},
+		{
+			name:         "UserManagerCreatedManageUsersKey",
+			caller:       models.User{ID: "3", AccountID: "1", RoleID: models.UserManagerRole},
+			body:         `{"name": "sync", "scopes": ["users:manage"]}`,
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveAPIKey(gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/me/api-keys", tc)
+		})
+	}
+}
+
+func TestGetAPIKeys(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1"}
+	createdAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []testCase{
+		{
+			name:          "ErrWhenGetAPIKeys",
+			caller:        caller,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAPIKeys(gomock.Any(), "1", "3").Return(nil, errors.New("err"))
+			},
+		},
+		{
+			name:         "KeysWithoutSecrets",
+			caller:       caller,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":[{"id":"key","name":"script","prefix":"cc_abcdef","scopes":[],"created_at":"2020-01-01T00:00:00Z"}]}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAPIKeys(gomock.Any(), "1", "3").Return([]models.APIKey{{
+					ID: "key", UserID: "3", AccountID: "1", Name: "script", Prefix: "cc_abcdef", KeyHash: hashToken("key"),
+					Scopes: []string{}, CreatedAt: createdAt,
+				}}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "GET", "/v1/me/api-keys", tc)
+		})
+	}
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1"}
+
+	testCases := []testCase{
+		{
+			name:          "APIKeyNotFound",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrAPIKeyNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().DeleteAPIKey(gomock.Any(), "1", "3", "key").Return(models.ErrAPIKeyNotFound)
+			},
+		},
+		{
+			name:         "APIKeyDeleted",
+			caller:       caller,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().DeleteAPIKey(gomock.Any(), "1", "3", "key").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "DELETE", "/v1/me/api-keys/key", tc)
+		})
+	}
+}
@@ -1,18 +1,19 @@
 package server
 
 import (
-	"calories-counter/common"
 	"calories-counter/models"
-	"github.com/dgrijalva/jwt-go"
+	passwords "calories-counter/password"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 	"net/http"
-	"time"
 )
 
-func SignUp(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
+// SignUp creates a new account with its owner, account name used at sign in defaults to username of the owner
+func (s *Server) SignUp(c *gin.Context) {
 	username := c.PostForm("username")
 	password := c.PostForm("password")
+	accountName := c.DefaultPostForm("account", username)
 	if err := ValidateUsername(username); err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -21,8 +22,18 @@ func SignUp(c *gin.Context) {
 		handleErrorResponse(c, err)
 		return
 	}
+	if err := ValidateAccountName(accountName); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
 
-	newAccount, err := userRepo.SaveRootUser(username, password)
+	newAccount, err := s.credentials.SaveRootUser(c.Request.Context(), accountName, username, hash)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -31,17 +42,37 @@ func SignUp(c *gin.Context) {
 	c.JSON(http.StatusCreated, newAccount)
 }
 
-func SignIn(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	secretKey := c.MustGet("secretKey").(string)
-
+// SignIn signs in user of account given by ID in the path
+func (s *Server) SignIn(c *gin.Context) {
 	accountID := c.Param("account_id")
-	username := c.PostForm("username")
-	password := c.PostForm("password")
 	if accountID == "" {
 		handleErrorResponse(c, ErrMissingAccountID)
 		return
 	}
+	s.signIn(c, accountID)
+}
+
+// SignInByAccountName signs in user of account given by its name in form field account
+func (s *Server) SignInByAccountName(c *gin.Context) {
+	accountName := c.PostForm("account")
+	if accountName == "" {
+		handleErrorResponse(c, ErrMissingAccountName)
+		return
+	}
+
+	account, err := s.accounts.GetAccountByName(c.Request.Context(), accountName)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	s.signIn(c, account.ID)
+}
+
+// signIn checks username and password from the form and responds with tokens
+// or with two-factor authentication challenge
+func (s *Server) signIn(c *gin.Context, accountID string) {
+	username := c.PostForm("username")
+	password := c.PostForm("password")
 	if username == "" {
 		handleErrorResponse(c, ErrMissingUsername)
 		return
@@ -51,35 +82,63 @@ func SignIn(c *gin.Context) {
 		return
 	}
 
-	pass, err := userRepo.GetUserPassword(accountID, username)
+	userKey := models.UserThrottleKey(accountID, username)
+	if err := s.checkSignInThrottle(c, userKey); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	pass, err := s.credentials.GetUserPassword(c.Request.Context(), accountID, username)
 	if err != nil {
+		// guessing usernames is throttled the same way as guessing passwords
+		if err == models.ErrUserNotFound {
+			s.recordSignInFailure(c, userKey)
+		}
 		handleErrorResponse(c, err)
 		return
-	} else if pass == nil || password != *pass {
+	}
+	ok, err := passwords.Verify(*pass, password)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	} else if !ok {
+		s.recordSignInFailure(c, userKey)
 		handleErrorResponse(c, ErrUnauthorized)
 		return
 	}
 
-	user, err := userRepo.GetUser(accountID, username)
+	user, err := s.users.GetUser(c.Request.Context(), accountID, username)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
 
-	claims := common.JWTClaims{
-		UUID:      user.ID,
-		AccountID: user.AccountID,
-		StandardClaims: jwt.StandardClaims{
-			IssuedAt:  time.Now().Unix(),
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
-		},
+	// legacy plaintext passwords and hashes with outdated cost are replaced while the password is known
+	if passwords.NeedsRehash(*pass) {
+		hash, err := passwords.Hash(password)
+		if err == nil {
+			err = s.credentials.UpdateUserPassword(c.Request.Context(), user.AccountID, user.ID, hash)
+		}
+		if err != nil {
+			log.WithError(err).WithField("user_id", user.ID).Warn("couldn't rehash password")
+		}
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secretKey))
+
+	challenge, err := s.twoFactorChallenge(c.Request.Context(), user)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	} else if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	tokens, err := s.issueTokens(c.Request.Context(), user, uuid.New().String())
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
+	s.resetSignInFailures(c, userKey)
 
-	c.JSON(http.StatusCreated, gin.H{"token": tokenString})
+	c.JSON(http.StatusCreated, tokens)
 }
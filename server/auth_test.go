@@ -4,11 +4,15 @@ import (
 	"calories-counter/adapters/user_datastore"
 	"calories-counter/common"
 	"calories-counter/models"
+	passwords "calories-counter/password"
+	"context"
 	"encoding/json"
 	"errors"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/golang/mock/gomock"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestSignUp(t *testing.T) {
@@ -50,6 +54,20 @@ func TestSignUp(t *testing.T) {
 			expectedCode:  http.StatusBadRequest,
 			expectedError: ErrInvalidPassword,
 		},
+		{
+			name:          "InvalidAccountName",
+			postForm:      true,
+			body:          signUpForm("xyz@xyz.xyz", "Xyz123", "a b"),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidAccountName,
+		},
+		{
+			name:          "InvalidAccountNameLength",
+			postForm:      true,
+			body:          signUpForm("xyz@xyz.xyz", "Xyz123", "ab"),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidAccountNameLength,
+		},
 		{
 			name:          "ErrWhenGetRootUser",
 			postForm:      true,
@@ -57,7 +75,7 @@ func TestSignUp(t *testing.T) {
 			expectedCode:  http.StatusInternalServerError,
 			expectedError: ErrInternalServerError,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().SaveRootUser("xyz@xyz.xyz", "Xyz123").Return(nil, errors.New("err")).Times(1)
+				m.EXPECT().SaveRootUser(gomock.Any(), "xyz@xyz.xyz", "xyz@xyz.xyz", passwordOf("Xyz123")).Return(nil, errors.New("err")).Times(1)
 			},
 		},
 		{
@@ -67,7 +85,7 @@ func TestSignUp(t *testing.T) {
 			expectedCode:  http.StatusConflict,
 			expectedError: models.ErrAccountAlreadyExists,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().SaveRootUser(gomock.Any(), gomock.Any()).Return(nil, models.ErrAccountAlreadyExists).Times(1)
+				m.EXPECT().SaveRootUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, models.ErrAccountAlreadyExists).Times(1)
 			},
 		},
 
@@ -79,7 +97,17 @@ func TestSignUp(t *testing.T) {
 			expectedBody: string(jsonTestUser),
 			expectedCode: http.StatusCreated,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().SaveRootUser(gomock.Any(), gomock.Any()).Return(testUser, nil)
+				m.EXPECT().SaveRootUser(gomock.Any(), "test@test.com", "test@test.com", passwordOf("Xyz123")).Return(testUser, nil)
+			},
+		},
+		{
+			name:         "AccountCreatedWithName",
+			postForm:     true,
+			body:         signUpForm("test@test.com", "Xyz123", "acme"),
+			expectedBody: string(jsonTestUser),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveRootUser(gomock.Any(), "acme", "test@test.com", passwordOf("Xyz123")).Return(testUser, nil)
 			},
 		},
 	}
@@ -92,6 +120,13 @@ func TestSignUp(t *testing.T) {
 }
 
 func TestSignIn(t *testing.T) {
+	hash, err := passwords.Hash("Xyz123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := models.Password{Hash: "Xyz123", Algorithm: passwords.Plaintext}
+	user := &models.User{ID: "3", AccountID: "1", Username: "user"}
+
 	testCases := []testCase{
 		// errors
 		{
@@ -111,23 +146,197 @@ func TestSignIn(t *testing.T) {
 		{
 			name:          "UnauthorizedWrongPassword",
 			postForm:      true,
-			body:          postForm("user", "Xyz123"),
+			body:          postForm("user", "Xyz124"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrUnauthorized,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				expectSignInFailure(m, "user:1:user")
+			},
+		},
+		{
+			name:          "UnauthorizedWrongLegacyPassword",
+			postForm:      true,
+			body:          postForm("user", "Xyz124"),
 			expectedCode:  http.StatusUnauthorized,
 			expectedError: ErrUnauthorized,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserPassword("1", "user").Return(common.String("pass"), nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&plaintext, nil)
+				expectSignInFailure(m, "user:1:user")
+			},
+		},
+
+		{
+			name:          "UserNotFound",
+			postForm:      true,
+			body:          postForm("user", "Xyz123"),
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrUserNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(nil, models.ErrUserNotFound)
+				expectSignInFailure(m, "user:1:user")
+			},
+		},
+		{
+			name:          "UserBackoff",
+			postForm:      true,
+			body:          postForm("user", "Xyz123"),
+			expectedCode:  http.StatusTooManyRequests,
+			expectedError: ErrTooManySignInAttempts,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetLoginFailures(gomock.Any(), "user:1:user").
+					Return(models.LoginFailures{Failures: FreeSignInAttempts + 2, LastFailure: time.Now()}, nil)
+			},
+		},
+		{
+			name:          "ClientIPBackoff",
+			postForm:      true,
+			body:          postForm("user", "Xyz123"),
+			expectedCode:  http.StatusTooManyRequests,
+			expectedError: ErrTooManySignInAttempts,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetLoginFailures(gomock.Any(), "user:1:user").Return(models.LoginFailures{}, nil)
+				m.EXPECT().GetLoginFailures(gomock.Any(), ipTestKey).
+					Return(models.LoginFailures{Failures: LockoutThreshold * 2, LastFailure: time.Now()}, nil)
+			},
+		},
+		{
+			name:          "UserLocked",
+			postForm:      true,
+			body:          postForm("user", "Xyz123"),
+			expectedCode:  http.StatusLocked,
+			expectedError: ErrUserLocked,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetLoginFailures(gomock.Any(), "user:1:user").
+					Return(models.LoginFailures{Failures: LockoutThreshold, LastFailure: time.Now().Add(-LockoutDuration / 2)}, nil)
+			},
+		},
+		{
+			name:          "ErrWhenGetLoginFailures",
+			postForm:      true,
+			body:          postForm("user", "Xyz123"),
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetLoginFailures(gomock.Any(), "user:1:user").Return(models.LoginFailures{}, errors.New("err"))
+			},
+		},
+
+		{
+			name:          "ErrWhenGetTOTP",
+			postForm:      true,
+			body:          postForm("user", "Xyz123"),
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(nil, errors.New("err"))
 			},
 		},
 
 		// success tests
+		{
+			name:         "TwoFactorChallenge",
+			postForm:     true,
+			body:         postForm("user", "Xyz123"),
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(&models.TOTP{UserID: "3", AccountID: "1", Enabled: true}, nil)
+			},
+		},
+		{
+			name:         "TwoFactorEnrollmentRequired",
+			postForm:     true,
+			body:         postForm("user", "Xyz123"),
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(nil, models.ErrTOTPNotFound)
+				m.EXPECT().GetTwoFactorRoles(gomock.Any(), "1").Return([]int{models.UserRole}, nil)
+			},
+		},
 		{
 			name:         "LoggedSuccessfully",
 			postForm:     true,
 			body:         postForm("user", "Xyz123"),
 			expectedCode: http.StatusCreated,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserPassword("1", "user").Return(common.String("Xyz123"), nil)
-				m.EXPECT().GetUser("1", "user").Return(&models.User{}, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				expectNoTwoFactor(m, user)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+		{
+			name:         "LockExpired",
+			postForm:     true,
+			body:         postForm("user", "Xyz123"),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetLoginFailures(gomock.Any(), "user:1:user").
+					Return(models.LoginFailures{Failures: LockoutThreshold, LastFailure: time.Now().Add(-LockoutDuration)}, nil)
+				m.EXPECT().GetLoginFailures(gomock.Any(), ipTestKey).Return(models.LoginFailures{}, nil)
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				expectNoTwoFactor(m, user)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+		{
+			name:         "LegacyPasswordRehashed",
+			postForm:     true,
+			body:         postForm("user", "Xyz123"),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&plaintext, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				m.EXPECT().UpdateUserPassword(gomock.Any(), "1", "3", passwordOf("Xyz123")).Return(nil)
+				expectNoTwoFactor(m, user)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+		{
+			name:         "LoggedInWhenRehashFails",
+			postForm:     true,
+			body:         postForm("user", "Xyz123"),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&plaintext, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				m.EXPECT().UpdateUserPassword(gomock.Any(), "1", "3", gomock.Any()).Return(errors.New("err"))
+				expectNoTwoFactor(m, user)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+		{
+			name:          "ErrWhenSaveRefreshToken",
+			postForm:      true,
+			body:          postForm("user", "Xyz123"),
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				expectNoTwoFactor(m, user)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), gomock.Any()).Return(errors.New("err"))
 			},
 		},
 	}
@@ -138,3 +347,288 @@ func TestSignIn(t *testing.T) {
 		})
 	}
 }
+
+// expectNoTwoFactor expects check of two-factor authentication of user which does not use it
+func expectNoTwoFactor(m *user_datastore.MockUserDatastore, user *models.User) {
+	m.EXPECT().GetTOTP(gomock.Any(), user.AccountID, user.ID).Return(nil, models.ErrTOTPNotFound)
+	m.EXPECT().GetTwoFactorRoles(gomock.Any(), user.AccountID).Return([]int{}, nil)
+}
+
+func TestSignInByAccountName(t *testing.T) {
+	hash, err := passwords.Hash("Xyz123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{ID: "3", AccountID: "1", Username: "user"}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "MissingAccountName",
+			postForm:      true,
+			body:          postForm("user", "Xyz123"),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingAccountName,
+		},
+		{
+			name:          "AccountNotFound",
+			postForm:      true,
+			body:          signInForm("acme", "user", "Xyz123"),
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrAccountNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAccountByName(gomock.Any(), "acme").Return(nil, models.ErrAccountNotFound)
+			},
+		},
+		{
+			name:          "UnauthorizedWrongPassword",
+			postForm:      true,
+			body:          signInForm("acme", "user", "Xyz124"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrUnauthorized,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAccountByName(gomock.Any(), "acme").Return(&models.Account{ID: "1", Name: "acme"}, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				expectSignInFailure(m, "user:1:user")
+			},
+		},
+
+		// success tests
+		{
+			name:         "LoggedSuccessfully",
+			postForm:     true,
+			body:         signInForm("acme", "user", "Xyz123"),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAccountByName(gomock.Any(), "acme").Return(&models.Account{ID: "1", Name: "acme"}, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				m.EXPECT().GetUser(gomock.Any(), "1", "user").Return(user, nil)
+				expectNoTwoFactor(m, user)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/signin", tc)
+		})
+	}
+}
+
+// ipTestKey is the throttle key of client address of runTest requests
+const ipTestKey = "ip:192.0.2.1"
+
+// expectSignInAllowed expects check of failed sign ins of the user and the client which have none
+func expectSignInAllowed(m *user_datastore.MockUserDatastore, userKey string) {
+	m.EXPECT().GetLoginFailures(gomock.Any(), userKey).Return(models.LoginFailures{}, nil)
+	m.EXPECT().GetLoginFailures(gomock.Any(), ipTestKey).Return(models.LoginFailures{}, nil)
+}
+
+// expectSignInFailure expects failed sign in recorded for the user and the client
+func expectSignInFailure(m *user_datastore.MockUserDatastore, userKey string) {
+	m.EXPECT().AddLoginFailure(gomock.Any(), userKey, gomock.Any(), gomock.Any()).Return(nil)
+	m.EXPECT().AddLoginFailure(gomock.Any(), ipTestKey, gomock.Any(), gomock.Any()).Return(nil)
+}
+
+func TestRefreshToken(t *testing.T) {
+	user := &models.User{ID: "3", AccountID: "1", Username: "user"}
+	tokenHash := hashToken("token")
+	stored := func(modify func(token *models.RefreshToken)) *models.RefreshToken {
+		token := &models.RefreshToken{
+			TokenHash: tokenHash,
+			FamilyID:  "family",
+			UserID:    "3",
+			AccountID: "1",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+		if modify != nil {
+			modify(token)
+		}
+		return token
+	}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "MissingRefreshToken",
+			postForm:      true,
+			body:          refreshForm(""),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingRefreshToken,
+		},
+		{
+			name:          "UnknownRefreshToken",
+			postForm:      true,
+			body:          refreshForm("token"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidRefreshToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UseRefreshToken(gomock.Any(), tokenHash).Return(nil, models.ErrRefreshTokenNotFound)
+			},
+		},
+		{
+			name:          "ExpiredRefreshToken",
+			postForm:      true,
+			body:          refreshForm("token"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidRefreshToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UseRefreshToken(gomock.Any(), tokenHash).Return(stored(func(token *models.RefreshToken) {
+					token.ExpiresAt = time.Now().Add(-time.Second)
+				}), nil)
+			},
+		},
+		{
+			name:          "RevokedRefreshToken",
+			postForm:      true,
+			body:          refreshForm("token"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidRefreshToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UseRefreshToken(gomock.Any(), tokenHash).Return(stored(func(token *models.RefreshToken) {
+					token.Used = true
+					token.Revoked = true
+				}), nil)
+			},
+		},
+		{
+			name:          "ReusedRefreshTokenRevokesFamily",
+			postForm:      true,
+			body:          refreshForm("token"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrRefreshTokenReused,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UseRefreshToken(gomock.Any(), tokenHash).Return(stored(func(token *models.RefreshToken) {
+					token.Used = true
+				}), nil)
+				m.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), "family").Return(nil)
+			},
+		},
+		{
+			name:          "DeletedUser",
+			postForm:      true,
+			body:          refreshForm("token"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidRefreshToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UseRefreshToken(gomock.Any(), tokenHash).Return(stored(nil), nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(nil, models.ErrUserNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "TokenRotated",
+			postForm:     true,
+			body:         refreshForm("token"),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UseRefreshToken(gomock.Any(), tokenHash).Return(stored(nil), nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).DoAndReturn(
+					func(_ context.Context, token models.RefreshToken) error {
+						if token.FamilyID != "family" || token.TokenHash == tokenHash {
+							t.Errorf("Expected new token of the same family but was %+v", token)
+						}
+						return nil
+					})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/token/refresh", tc)
+		})
+	}
+}
+
+func TestSignOut(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", Username: "user"}
+	expiresAt := time.Now().Add(time.Minute).Unix()
+	claims := common.JWTClaims{
+		UUID:           "3",
+		AccountID:      "1",
+		FamilyID:       "family",
+		StandardClaims: jwt.StandardClaims{Id: "jti", ExpiresAt: expiresAt},
+	}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "ErrWhenRevokeAccessToken",
+			caller:        caller,
+			claims:        claims,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().RevokeAccessToken(gomock.Any(), "jti", time.Unix(expiresAt, 0)).Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "SignedOut",
+			caller:       caller,
+			claims:       claims,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().RevokeAccessToken(gomock.Any(), "jti", time.Unix(expiresAt, 0)).Return(nil)
+				m.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), "family").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/signout", tc)
+		})
+	}
+}
+
+func TestSignOutAll(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", Username: "user"}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "ErrWhenRevokeUserTokenFamilies",
+			caller:        caller,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().RevokeUserTokenFamilies(gomock.Any(), "1", "3").Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "SignedOutAllSessions",
+			caller:       caller,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().RevokeUserTokenFamilies(gomock.Any(), "1", "3").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/signout/all", tc)
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	jwks, _ := json.Marshal(testKeys.JWKS())
+
+	tc := testCase{
+		expectedBody: string(jwks),
+		expectedCode: http.StatusOK,
+	}
+	runTest(t, "GET", "/.well-known/jwks.json", tc)
+}
@@ -3,7 +3,11 @@ package server
 import (
 	"calories-counter/adapters/calories_datastore"
 	"calories-counter/adapters/user_datastore"
+	"calories-counter/common"
 	"calories-counter/models"
+	passwords "calories-counter/password"
+	"calories-counter/signing"
+	"context"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"net/http"
@@ -11,6 +15,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 type testCase struct {
@@ -21,11 +26,24 @@ type testCase struct {
 	expectedCode      int
 	expectedError     error
 	caller            models.User
+	claims            common.JWTClaims
 	user              models.User
 	setupMockUser     func(m *user_datastore.MockUserDatastore)
 	setupMockCalories func(m *calories_datastore.MockCaloriesDatastore)
 }
 
+var testKeys = func() *signing.KeySet {
+	key, err := signing.GenerateKey("test")
+	if err != nil {
+		panic(err)
+	}
+	keys, err := signing.NewKeySet(key)
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}()
+
 func runTest(t *testing.T, method, path string, tc testCase) {
 	controller := gomock.NewController(t)
 	defer controller.Finish()
@@ -38,16 +56,43 @@ func runTest(t *testing.T, method, path string, tc testCase) {
 		tc.setupMockCalories(mockCD)
 	}
 
+	s, err := New(Config{
+		Keys:               testKeys,
+		UnitOfWork:         mockUD,
+		Credentials:        mockUD,
+		Accounts:           mockUD,
+		RefreshTokens:      mockUD,
+		Revocations:        mockUD,
+		TwoFactor:          mockUD,
+		LoginThrottle:      mockUD,
+		PasswordResets:     mockUD,
+		APIKeys:            mockUD,
+		Roles:              mockUD,
+		Teams:              mockUD,
+		OwnershipTransfers: mockUD,
+		Invitations:        mockUD,
+		Users:              mockUD,
+		Meals:              mockUD,
+		Settings:           mockUD,
+		DailyTotals:        mockUD,
+		Calories:           mockCD,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
-	r.Use(SetVars(map[string]interface{}{
-		"caller": tc.caller,
-		"user":   tc.user,
-	}))
-	setupTestRouter(r, "test_secret_key", mockUD, mockCD)
+	r.Use(func(c *gin.Context) {
+		setCaller(c, tc.caller)
+		setClaims(c, tc.claims)
+		setTargetUser(c, tc.user)
+	})
+	setupTestRouter(r, s)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(method, path, strings.NewReader(tc.body))
+	req.RemoteAddr = "192.0.2.1:1234"
 	if tc.postForm {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	} else {
@@ -69,6 +114,57 @@ func runTest(t *testing.T, method, path string, tc testCase) {
 	}
 }
 
+// withCaller middleware sets caller the way AuthVerify does
+func withCaller(caller models.User) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setCaller(c, caller)
+	}
+}
+
+// expectUnitOfWork runs the next unit of work with repositories of m
+func expectUnitOfWork(m *user_datastore.MockUserDatastore) {
+	m.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(ctx context.Context, repos models.Repositories) error) error {
+			return fn(ctx, models.Repositories{Credentials: m, Revocations: m, APIKeys: m, Users: m, Meals: m,
+				DailyTotals: m, Settings: m, OwnershipTransfers: m, Roles: m, TwoFactor: m, Teams: m, Invitations: m})
+		})
+}
+
+// passwordOf matches models.Password which is a hash of plain
+type passwordOf string
+
+func (p passwordOf) Matches(x interface{}) bool {
+	pass, ok := x.(models.Password)
+	if !ok || passwords.NeedsRehash(pass) {
+		return false
+	}
+	match, err := passwords.Verify(pass, string(p))
+	return err == nil && match
+}
+
+func (p passwordOf) String() string {
+	return "is hash of " + string(p)
+}
+
+// refreshTokenOf matches unused models.RefreshToken of user which is not expired
+type refreshTokenOf models.User
+
+func (u refreshTokenOf) Matches(x interface{}) bool {
+	token, ok := x.(models.RefreshToken)
+	return ok && token.UserID == u.ID && token.AccountID == u.AccountID && len(token.TokenHash) == 64 &&
+		token.FamilyID != "" && token.ExpiresAt.After(time.Now()) && !token.Used && !token.Revoked
+}
+
+func (u refreshTokenOf) String() string {
+	return "is refresh token of user " + u.ID
+}
+
+func refreshForm(token string) string {
+	form := url.Values{}
+	form.Set("refresh_token", token)
+	return form.Encode()
+}
+
 func postForm(username, password string) string {
 	form := url.Values{}
 	form.Set("username", username)
@@ -76,27 +172,79 @@ func postForm(username, password string) string {
 	return form.Encode()
 }
 
-func setupTestRouter(r *gin.Engine, secretKey string, datastore models.UserDatastore, caloriesDatastore models.CaloriesDatastore) {
-	r.Use(SetVars(map[string]interface{}{
-		"userDatastore":     datastore,
-		"secretKey":         secretKey,
-		"caloriesDatastore": caloriesDatastore,
-	}))
-	r.POST("/v1/signup", SignUp)
-	r.POST("/v1/account/:account_id/signin", SignIn)
-
-	r.POST("/v1/users", CreateUser)
-	r.GET("/v1/users", GetUsers)
-	r.GET("/v1/users/:user_id", GetUser)
-	r.PUT("/v1/users/:user_id", UpdateUser)
-	r.DELETE("/v1/users/:user_id", DeleteUser)
-
-	r.POST("/v1/meals", CreateMeal)
-	r.GET("/v1/meals", GetMeals)
-	r.GET("/v1/meals/:meal_id", GetMeal)
-	r.PUT("/v1/meals/:meal_id", UpdateMeal)
-	r.DELETE("/v1/meals/:meal_id", DeleteMeal)
-
-	r.PUT("/v1/settings", UpdateSettings)
-	r.GET("/v1/settings", GetSettings)
+func signUpForm(username, password, account string) string {
+	form := url.Values{}
+	form.Set("username", username)
+	form.Set("password", password)
+	form.Set("account", account)
+	return form.Encode()
+}
+
+func signInForm(account, username, password string) string {
+	form := url.Values{}
+	form.Set("account", account)
+	form.Set("username", username)
+	form.Set("password", password)
+	return form.Encode()
+}
+
+func setupTestRouter(r *gin.Engine, s *Server) {
+	r.GET("/.well-known/jwks.json", s.JWKS)
+	r.POST("/v1/signup", s.SignUp)
+	r.POST("/v1/account/:account_id/signin", s.SignIn)
+	r.POST("/v1/signin", s.SignInByAccountName)
+	r.POST("/v1/signin/2fa", s.SignInTwoFactor)
+	r.POST("/v1/signin/2fa/enroll", s.SignInTwoFactorEnroll)
+	r.POST("/v1/token/refresh", s.RefreshToken)
+	r.POST("/v1/password/reset", s.ResetPassword)
+	r.POST("/v1/signout", s.SignOut)
+	r.POST("/v1/signout/all", s.SignOutAll)
+	r.GET("/v1/account", s.GetAccount)
+	r.DELETE("/v1/account", s.DeleteAccount)
+	r.POST("/v1/restore", s.RestoreAccount)
+
+	r.PUT("/v1/me/password", s.ChangePassword)
+	r.POST("/v1/me/2fa", s.EnrollTOTP)
+	r.POST("/v1/me/2fa/confirm", s.ConfirmTOTP)
+	r.DELETE("/v1/me/2fa", s.DisableTOTP)
+	r.POST("/v1/me/api-keys", s.CreateAPIKey)
+	r.GET("/v1/me/api-keys", s.GetAPIKeys)
+	r.DELETE("/v1/me/api-keys/:key_id", s.DeleteAPIKey)
+	r.GET("/v1/account/2fa", s.GetTwoFactorRoles)
+	r.PUT("/v1/account/2fa", s.UpdateTwoFactorRoles)
+	r.GET("/v1/roles", s.GetRoles)
+	r.POST("/v1/roles", s.CreateRole)
+	r.PUT("/v1/roles/:role_id", s.UpdateRole)
+	r.DELETE("/v1/roles/:role_id", s.DeleteRole)
+	r.POST("/v1/owners/transfer", s.CreateOwnershipTransfer)
+	r.GET("/v1/owners/transfer", s.GetOwnershipTransfer)
+	r.DELETE("/v1/owners/transfer", s.CancelOwnershipTransfer)
+	r.POST("/v1/owners/transfer/accept", s.AcceptOwnershipTransfer)
+
+	r.POST("/v1/invitations", s.CreateInvitation)
+	r.GET("/v1/invitations", s.GetInvitations)
+	r.POST("/v1/invitations/:invitation_id/resend", s.ResendInvitation)
+	r.DELETE("/v1/invitations/:invitation_id", s.RevokeInvitation)
+	r.POST("/v1/signup/invitation", s.AcceptInvitation)
+
+	r.POST("/v1/users", s.CreateUser)
+	r.GET("/v1/users", s.GetUsers)
+	r.GET("/v1/users/:user_id", s.GetUser)
+	r.PUT("/v1/users/:user_id", s.UpdateUser)
+	r.DELETE("/v1/users/:user_id", s.DeleteUser)
+	r.POST("/v1/users/:user_id/unlock", s.UnlockUser)
+	r.POST("/v1/users/:user_id/password/reset", s.CreatePasswordReset)
+	r.GET("/v1/users/:user_id/team", s.GetTeam)
+	r.PUT("/v1/users/:user_id/team/:member_id", s.AddTeamMember)
+	r.DELETE("/v1/users/:user_id/team/:member_id", s.RemoveTeamMember)
+
+	r.POST("/v1/meals", s.CreateMeal)
+	r.GET("/v1/meals", s.GetMeals)
+	r.GET("/v1/meals/:meal_id", s.GetMeal)
+	r.PUT("/v1/meals/:meal_id", s.UpdateMeal)
+	r.DELETE("/v1/meals/:meal_id", s.DeleteMeal)
+
+	r.PUT("/v1/settings", s.UpdateSettings)
+	r.GET("/v1/settings", s.GetSettings)
+	r.GET("/v1/summary", s.GetSummary)
 }
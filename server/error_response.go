@@ -2,12 +2,19 @@ package server
 
 import (
 	"calories-counter/common"
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
 func handleErrorResponse(c *gin.Context, err error) {
 	log.Info("error response caused by err: ", err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = ErrRequestTimeout
+	} else if errors.Is(err, context.Canceled) {
+		err = ErrRequestCanceled
+	}
 	if e, ok := err.(common.ApiErr); ok {
 		c.JSON(e.Code, gin.H{"error": e.Error()})
 	} else {
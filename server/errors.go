@@ -7,12 +7,18 @@ import (
 	"net/http"
 )
 
+// StatusClientClosedRequest is a non standard status used when client disconnects before response is ready
+const StatusClientClosedRequest = 499
+
 const (
 	MaxUsernameLength = 50
 	MinUsernameLength = 5
 	MaxPasswordLength = 50
 	MinPasswordLength = 5
 	MaxNameLength     = 50
+
+	MaxAccountNameLength = 50
+	MinAccountNameLength = 3
 )
 
 var (
@@ -21,11 +27,126 @@ var (
 		Err:  errors.New(http.StatusText(http.StatusInternalServerError)),
 	}
 
+	ErrRequestTimeout = common.ApiErr{
+		Code: http.StatusGatewayTimeout,
+		Err:  errors.New("request timeout"),
+	}
+
+	ErrRequestCanceled = common.ApiErr{
+		Code: StatusClientClosedRequest,
+		Err:  errors.New("request canceled"),
+	}
+
 	ErrUnauthorized = common.ApiErr{
 		Code: http.StatusUnauthorized,
 		Err:  errors.New(http.StatusText(http.StatusUnauthorized)),
 	}
 
+	ErrInvalidRefreshToken = common.ApiErr{
+		Code: http.StatusUnauthorized,
+		Err:  errors.New("invalid refresh token"),
+	}
+
+	ErrRefreshTokenReused = common.ApiErr{
+		Code: http.StatusUnauthorized,
+		Err:  errors.New("refresh token reused, sign in again"),
+	}
+
+	ErrMissingRefreshToken = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("missing refresh token"),
+	}
+
+	ErrMissingMFAToken = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("missing mfa token"),
+	}
+
+	ErrInvalidMFAToken = common.ApiErr{
+		Code: http.StatusUnauthorized,
+		Err:  errors.New("invalid mfa token, sign in again"),
+	}
+
+	ErrMissingTwoFactorCode = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("missing two-factor code"),
+	}
+
+	ErrInvalidTwoFactorCode = common.ApiErr{
+		Code: http.StatusUnauthorized,
+		Err:  errors.New("invalid two-factor code"),
+	}
+
+	ErrTwoFactorRequired = common.ApiErr{
+		Code: http.StatusForbidden,
+		Err:  errors.New("two-factor authentication is required for the role"),
+	}
+
+	ErrWrongPassword = common.ApiErr{
+		Code: http.StatusForbidden,
+		Err:  errors.New("old password is wrong"),
+	}
+
+	ErrMissingResetToken = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("missing password reset token"),
+	}
+
+	ErrInvalidResetToken = common.ApiErr{
+		Code: http.StatusUnauthorized,
+		Err:  errors.New("invalid password reset token"),
+	}
+
+	ErrMissingInvitationToken = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("missing invitation token"),
+	}
+
+	ErrInvalidInvitationToken = common.ApiErr{
+		Code: http.StatusUnauthorized,
+		Err:  errors.New("invalid invitation token"),
+	}
+
+	ErrMissingRestoreToken = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("missing restore token"),
+	}
+
+	ErrInvalidRestoreToken = common.ApiErr{
+		Code: http.StatusUnauthorized,
+		Err:  errors.New("invalid restore token"),
+	}
+
+	ErrInvalidScope = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("invalid scope, scopes are meals:read, meals:write and users:manage"),
+	}
+
+	ErrInvalidExpiry = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("invalid expires_at, it has to be in the future"),
+	}
+
+	ErrInsufficientScope = common.ApiErr{
+		Code: http.StatusForbidden,
+		Err:  errors.New("API key does not grant access to the endpoint"),
+	}
+
+	ErrSessionRequired = common.ApiErr{
+		Code: http.StatusForbidden,
+		Err:  errors.New("endpoint requires signing in, API keys are not accepted"),
+	}
+
+	ErrTooManySignInAttempts = common.ApiErr{
+		Code: http.StatusTooManyRequests,
+		Err:  errors.New("too many failed sign in attempts, retry later"),
+	}
+
+	ErrUserLocked = common.ApiErr{
+		Code: http.StatusLocked,
+		Err:  errors.New("user is locked after too many failed sign in attempts"),
+	}
+
 	ErrInsufficientPermissions = common.ApiErr{
 		Code: http.StatusForbidden,
 		Err:  errors.New("insufficient permissions"),
@@ -36,14 +157,39 @@ var (
 		Err:  errors.New("invalid roleID"),
 	}
 
-	ErrMissingBearerToken = common.ApiErr{
-		Code: http.StatusUnauthorized,
-		Err:  errors.New("missing Bearer token"),
+	ErrInvalidPermission = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("invalid permission"),
+	}
+
+	ErrBuiltinRole = common.ApiErr{
+		Code: http.StatusForbidden,
+		Err:  errors.New("built-in roles can not be changed"),
+	}
+
+	ErrRoleInUse = common.ApiErr{
+		Code: http.StatusConflict,
+		Err:  errors.New("role is assigned to users"),
 	}
 
-	ErrUnexpectedSigningMethod = common.ApiErr{
+	ErrInvalidTeamMember = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("only standard users can be team members"),
+	}
+
+	ErrTeamMemberAssigned = common.ApiErr{
+		Code: http.StatusConflict,
+		Err:  errors.New("user is already in the team of another manager"),
+	}
+
+	ErrInvalidTransferTarget = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("ownership can be transferred only to a user who is not an owner"),
+	}
+
+	ErrMissingBearerToken = common.ApiErr{
 		Code: http.StatusUnauthorized,
-		Err:  errors.New("unexpected signing method"),
+		Err:  errors.New("missing Bearer token"),
 	}
 
 	ErrMissingName = common.ApiErr{
@@ -56,6 +202,11 @@ var (
 		Err:  errors.New("missing date"),
 	}
 
+	ErrInvalidDateRange = common.ApiErr{
+		Code: http.StatusBadRequest,
+		Err:  errors.New("invalid from or to, dates have to be YYYY-MM-DD and from can not be after to"),
+	}
+
 	ErrMissingTime = common.ApiErr{
 		Code: http.StatusBadRequest,
 		Err:  errors.New("missing time"),
@@ -77,11 +228,32 @@ var ErrMissingUsername = common.ApiErr{
 	Err:  errors.New("missing username"),
 }
 
+var ErrMissingUserID = common.ApiErr{
+	Code: http.StatusBadRequest,
+	Err:  errors.New("missing user_id"),
+}
+
 var ErrMissingAccountID = common.ApiErr{
 	Code: http.StatusBadRequest,
 	Err:  errors.New("missing accountID"),
 }
 
+var ErrMissingAccountName = common.ApiErr{
+	Code: http.StatusBadRequest,
+	Err:  errors.New("missing account name"),
+}
+
+var ErrInvalidAccountNameLength = common.ApiErr{
+	Code: http.StatusBadRequest,
+	Err: fmt.Errorf("invalid account name length, "+
+		"account name can not be shorter than %d and larger than %d characters", MinAccountNameLength, MaxAccountNameLength),
+}
+
+var ErrInvalidAccountName = common.ApiErr{
+	Code: http.StatusBadRequest,
+	Err:  errors.New("invalid account name"),
+}
+
 var ErrInvalidPasswordLength = common.ApiErr{
 	Code: http.StatusBadRequest,
 	Err: fmt.Errorf("invalid password length, "+
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"calories-counter/models"
+	passwords "calories-counter/password"
+	"calories-counter/policy"
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+const (
+	// InvitationTTL is how long invitation tokens are valid, resending an invitation issues a new token
+	InvitationTTL = 7 * 24 * time.Hour
+	// invitationAudience distinguishes invitation tokens from access tokens signed by the same keys
+	invitationAudience = "calories-counter/invitation"
+)
+
+// invitationClaims are claims of invitation tokens, StandardClaims.Id is TokenID of the invitation
+type invitationClaims struct {
+	InvitationID string `json:"invitation_id"`
+	AccountID    string `json:"account_id"`
+	jwt.StandardClaims
+}
+
+// InvitationResponse is returned to the manager who passes Token to the invitee
+type InvitationResponse struct {
+	models.Invitation
+	Token string `json:"token"`
+}
+
+func (s *Server) invitationResponse(invitation models.Invitation) (*InvitationResponse, error) {
+	token, err := s.keys.Sign(invitationClaims{
+		InvitationID: invitation.ID,
+		AccountID:    invitation.AccountID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  invitationAudience,
+			Id:        invitation.TokenID,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: invitation.ExpiresAt.Unix(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &InvitationResponse{Invitation: invitation, Token: token}, nil
+}
+
+// managedInvitation returns invitation of invitation_id when the caller can assign its role,
+// invitations of deleted roles can be handled by all who manage users
+func (s *Server) managedInvitation(c *gin.Context) (*models.Invitation, error) {
+	caller := getCaller(c)
+	invitation, err := s.invitations.GetInvitation(c.Request.Context(), caller.AccountID, c.Param("invitation_id"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authorizeRole(c, invitation.RoleID); err != nil && err != ErrInvalidRoleID {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// CreateInvitation invites a user with a role the caller can assign, the invitee chooses its username
+// and password when it accepts the invitation
+func (s *Server) CreateInvitation(c *gin.Context) {
+	caller := getCaller(c)
+	var body InvitationPostBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if _, err := s.authorizeRole(c, body.RoleID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	now := time.Now()
+	invitation := models.Invitation{
+		ID:        uuid.New().String(),
+		AccountID: caller.AccountID,
+		Name:      body.Name,
+		RoleID:    body.RoleID,
+		InvitedBy: caller.ID,
+		TokenID:   uuid.New().String(),
+		ExpiresAt: now.Add(InvitationTTL),
+		CreatedAt: now,
+	}
+	err := s.invitations.SaveInvitation(c.Request.Context(), invitation)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	response, err := s.invitationResponse(invitation)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetInvitations lists invitations of the account which were neither accepted nor revoked,
+// expired ones are listed too so they can be resent
+func (s *Server) GetInvitations(c *gin.Context) {
+	caller := getCaller(c)
+	invitations, err := s.invitations.GetInvitations(c.Request.Context(), caller.AccountID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": invitations})
+}
+
+// ResendInvitation issues a new token of the invitation with a new expiry, earlier tokens stop working
+func (s *Server) ResendInvitation(c *gin.Context) {
+	invitation, err := s.managedInvitation(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	invitation.TokenID = uuid.New().String()
+	invitation.ExpiresAt = time.Now().Add(InvitationTTL)
+	err = s.invitations.UpdateInvitation(c.Request.Context(), *invitation)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	response, err := s.invitationResponse(*invitation)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) RevokeInvitation(c *gin.Context) {
+	invitation, err := s.managedInvitation(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	err = s.invitations.DeleteInvitation(c.Request.Context(), invitation.AccountID, invitation.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// invitationRole returns the user who sent the invitation and the role it gives, ErrInvalidInvitationToken
+// when the account was deleted, the role no longer exists or the inviter can no longer assign it
+func (s *Server) invitationRole(ctx context.Context, invitation models.Invitation) (policy.Subject, *models.Role, error) {
+	account, err := s.accounts.GetAccount(ctx, invitation.AccountID)
+	if err == models.ErrAccountNotFound || err == nil && account.DeletedAt != nil {
+		return policy.Subject{}, nil, ErrInvalidInvitationToken
+	} else if err != nil {
+		return policy.Subject{}, nil, err
+	}
+	inviter, err := s.users.GetUserById(ctx, invitation.AccountID, invitation.InvitedBy)
+	if err == models.ErrUserNotFound {
+		return policy.Subject{}, nil, ErrInvalidInvitationToken
+	} else if err != nil {
+		return policy.Subject{}, nil, err
+	}
+	creator, err := s.subject(ctx, *inviter)
+	if err != nil {
+		return policy.Subject{}, nil, err
+	}
+	role, err := s.getRole(ctx, invitation.AccountID, invitation.RoleID)
+	if err == models.ErrRoleNotFound {
+		return policy.Subject{}, nil, ErrInvalidInvitationToken
+	} else if err != nil {
+		return policy.Subject{}, nil, err
+	}
+	if !policy.CanAssign(creator.Role, *role) {
+		return policy.Subject{}, nil, ErrInvalidInvitationToken
+	}
+	return creator, role, nil
+}
+
+// AcceptInvitation creates user of the invitation from form fields invitation_token, username and password.
+// The invitation is used up, the user joins team of the manager who invited it like users the manager creates,
+// all in one unit of work. The inviter has to be still able to assign the role of the invitation.
+func (s *Server) AcceptInvitation(c *gin.Context) {
+	tokenString := c.PostForm("invitation_token")
+	username := c.PostForm("username")
+	password := c.PostForm("password")
+	if tokenString == "" {
+		handleErrorResponse(c, ErrMissingInvitationToken)
+		return
+	}
+	// invalid username or password does not use up the invitation
+	if err := ValidateUsername(username); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if err := ValidatePassword(password); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	claims := &invitationClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keys.Keyfunc)
+	if err != nil || !token.Valid || !claims.VerifyAudience(invitationAudience, true) {
+		handleErrorResponse(c, ErrInvalidInvitationToken)
+		return
+	}
+	ctx := c.Request.Context()
+	invitation, err := s.invitations.GetInvitation(ctx, claims.AccountID, claims.InvitationID)
+	if err == models.ErrInvitationNotFound {
+		handleErrorResponse(c, ErrInvalidInvitationToken)
+		return
+	} else if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	// token of a resent invitation
+	if invitation.TokenID != claims.Id {
+		handleErrorResponse(c, ErrInvalidInvitationToken)
+		return
+	}
+	creator, role, err := s.invitationRole(ctx, *invitation)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	_, err = s.users.GetUser(ctx, invitation.AccountID, username)
+	if err == nil {
+		handleErrorResponse(c, models.ErrUserAlreadyExists)
+		return
+	} else if err != models.ErrUserNotFound {
+		handleErrorResponse(c, err)
+		return
+	}
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	var newUser *models.User
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context, repos models.Repositories) error {
+		// only one of concurrent accepts gets through and a token revoked by resending in the meantime is not accepted
+		err := repos.Invitations.UseInvitation(ctx, invitation.AccountID, invitation.ID, claims.Id)
+		if err == models.ErrInvitationNotFound {
+			return ErrInvalidInvitationToken
+		} else if err != nil {
+			return err
+		}
+		newUser, err = repos.Users.SaveUser(ctx, invitation.AccountID, username, hash, invitation.RoleID)
+		if err != nil {
+			return err
+		}
+		return addToCreatorTeam(ctx, repos.Teams, creator, *newUser, *role)
+	})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newUser)
+}
@@ -0,0 +1,449 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func acceptForm(token, username, password string) string {
+	form := url.Values{}
+	form.Set("invitation_token", token)
+	form.Set("username", username)
+	form.Set("password", password)
+	return form.Encode()
+}
+
+// invitationToken signs token of invitation like CreateInvitation does
+func invitationToken(t *testing.T, invitation models.Invitation) string {
+	response, err := (&Server{keys: testKeys}).invitationResponse(invitation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.Token
+}
+
+func TestCreateInvitation(t *testing.T) {
+	manager := models.User{ID: "4", AccountID: "1", Username: "manager", RoleID: models.UserManagerRole}
+	admin := models.User{ID: "6", AccountID: "1", Username: "admin", RoleID: models.AdminRole}
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "MissingName",
+			caller:        manager,
+			body:          `{"role_id": 0}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingName,
+		},
+		{
+			name:          "OwnerRole",
+			caller:        admin,
+			body:          `{"name": "Jane", "role_id": 3}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidRoleID,
+		},
+		{
+			name:          "ManagerInvitesAdmin",
+			caller:        manager,
+			body:          `{"name": "Jane", "role_id": 2}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+		},
+		{
+			name:          "CustomRoleNotFound",
+			caller:        admin,
+			body:          `{"name": "Jane", "role_id": 100}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidRoleID,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "1", 100).Return(nil, models.ErrRoleNotFound)
+			},
+		},
+		{
+			name:          "ErrWhenSaveInvitation",
+			caller:        manager,
+			body:          `{"name": "Jane", "role_id": 0}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveInvitation(gomock.Any(), gomock.Any()).Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "InvitationCreated",
+			caller:       manager,
+			body:         `{"name": "Jane", "role_id": 0}`,
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveInvitation(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, invitation models.Invitation) error {
+					expiresIn := time.Until(invitation.ExpiresAt)
+					if invitation.ID == "" || invitation.AccountID != "1" || invitation.Name != "Jane" ||
+						invitation.RoleID != models.UserRole || invitation.InvitedBy != "4" || invitation.TokenID == "" ||
+						expiresIn <= InvitationTTL-time.Minute || expiresIn > InvitationTTL {
+						t.Errorf("Unexpected invitation %+v", invitation)
+					}
+					return nil
+				})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/invitations", tc)
+		})
+	}
+}
+
+func TestGetInvitations(t *testing.T) {
+	caller := models.User{ID: "4", AccountID: "1", RoleID: models.UserManagerRole}
+	invitations := []models.Invitation{{ID: "7", AccountID: "1", Name: "Jane", InvitedBy: "4", TokenID: "t"}}
+	jsonInvitations, _ := json.Marshal(invitations)
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "ErrWhenGetInvitations",
+			caller:        caller,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetInvitations(gomock.Any(), "1").Return(nil, errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "InvitationsListed",
+			caller:       caller,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":` + string(jsonInvitations) + `}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetInvitations(gomock.Any(), "1").Return(invitations, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "GET", "/v1/invitations", tc)
+		})
+	}
+}
+
+func TestResendInvitation(t *testing.T) {
+	manager := models.User{ID: "4", AccountID: "1", RoleID: models.UserManagerRole}
+	invitation := models.Invitation{ID: "7", AccountID: "1", Name: "Jane", RoleID: models.UserRole, InvitedBy: "4",
+		TokenID: "t", ExpiresAt: time.Now().Add(-time.Hour)}
+	adminInvitation := invitation
+	adminInvitation.RoleID = models.AdminRole
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "InvitationNotFound",
+			caller:        manager,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrInvitationNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(nil, models.ErrInvitationNotFound)
+			},
+		},
+		{
+			name:          "ManagerResendsAdminInvitation",
+			caller:        manager,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				invitation := adminInvitation
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(&invitation, nil)
+			},
+		},
+		{
+			name:          "ErrWhenUpdateInvitation",
+			caller:        manager,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				invitation := invitation
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(&invitation, nil)
+				m.EXPECT().UpdateInvitation(gomock.Any(), gomock.Any()).Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "ExpiredInvitationResent",
+			caller:       manager,
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				invitation := invitation
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(&invitation, nil)
+				m.EXPECT().UpdateInvitation(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, updated models.Invitation) error {
+					if updated.ID != "7" || updated.TokenID == "t" || updated.TokenID == "" ||
+						time.Until(updated.ExpiresAt) <= InvitationTTL-time.Minute {
+						t.Errorf("Unexpected invitation %+v", updated)
+					}
+					return nil
+				})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/invitations/7/resend", tc)
+		})
+	}
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	manager := models.User{ID: "4", AccountID: "1", RoleID: models.UserManagerRole}
+	invitation := models.Invitation{ID: "7", AccountID: "1", Name: "Jane", RoleID: 100, InvitedBy: "4", TokenID: "t"}
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "ErrWhenDeleteInvitation",
+			caller:        manager,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(&invitation, nil)
+				m.EXPECT().GetRole(gomock.Any(), "1", 100).Return(nil, models.ErrRoleNotFound)
+				m.EXPECT().DeleteInvitation(gomock.Any(), "1", "7").Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "InvitationOfDeletedRoleRevoked",
+			caller:       manager,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(&invitation, nil)
+				m.EXPECT().GetRole(gomock.Any(), "1", 100).Return(nil, models.ErrRoleNotFound)
+				m.EXPECT().DeleteInvitation(gomock.Any(), "1", "7").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "DELETE", "/v1/invitations/7", tc)
+		})
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	manager := models.User{ID: "4", AccountID: "1", Username: "manager", RoleID: models.UserManagerRole}
+	admin := models.User{ID: "6", AccountID: "1", Username: "admin", RoleID: models.AdminRole}
+	invitation := models.Invitation{ID: "7", AccountID: "1", Name: "Jane", RoleID: models.UserRole, InvitedBy: "4",
+		TokenID: "t", ExpiresAt: time.Now().Add(time.Hour)}
+	token := invitationToken(t, invitation)
+	expired := invitation
+	expired.ExpiresAt = time.Now().Add(-time.Hour)
+	accessToken, err := testKeys.Sign(jwt.StandardClaims{Id: "t", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newUser := models.User{ID: "8", AccountID: "1", Username: "janedoe", RoleID: models.UserRole}
+	jsonUser, _ := json.Marshal(newUser)
+	customInvitation := invitation
+	customInvitation.RoleID = 100
+	customInvitation.InvitedBy = "6"
+	// expectInvitation expects invitation to be found in its live account and sent by inviter
+	expectInvitation := func(m *user_datastore.MockUserDatastore, invitation models.Invitation, inviter models.User) {
+		m.EXPECT().GetInvitation(gomock.Any(), "1", invitation.ID).Return(&invitation, nil)
+		m.EXPECT().GetAccount(gomock.Any(), "1").Return(&models.Account{ID: "1"}, nil)
+		m.EXPECT().GetUserById(gomock.Any(), "1", invitation.InvitedBy).Return(&inviter, nil)
+	}
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "MissingToken",
+			postForm:      true,
+			body:          acceptForm("", "janedoe", "Abc123"),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingInvitationToken,
+		},
+		{
+			name:          "InvalidPassword",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "abc"),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidPasswordLength,
+		},
+		{
+			name:          "ExpiredToken",
+			postForm:      true,
+			body:          acceptForm(invitationToken(t, expired), "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+		},
+		{
+			name:          "TokenOfOtherAudience",
+			postForm:      true,
+			body:          acceptForm(accessToken, "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+		},
+		{
+			name:          "RevokedInvitation",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(nil, models.ErrInvitationNotFound)
+			},
+		},
+		{
+			name:          "TokenOfResentInvitation",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				resent := invitation
+				resent.TokenID = "u"
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(&resent, nil)
+			},
+		},
+		{
+			name:          "AccountDeleted",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				deletedAt := time.Now()
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(&invitation, nil)
+				m.EXPECT().GetAccount(gomock.Any(), "1").Return(&models.Account{ID: "1", DeletedAt: &deletedAt}, nil)
+			},
+		},
+		{
+			name:          "InviterDeleted",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetInvitation(gomock.Any(), "1", "7").Return(&invitation, nil)
+				m.EXPECT().GetAccount(gomock.Any(), "1").Return(&models.Account{ID: "1"}, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "InviterCanNoLongerAssignRole",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				demoted := manager
+				demoted.RoleID = models.UserRole
+				expectInvitation(m, invitation, demoted)
+			},
+		},
+		{
+			name:          "RoleDeleted",
+			postForm:      true,
+			body:          acceptForm(invitationToken(t, customInvitation), "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectInvitation(m, customInvitation, admin)
+				m.EXPECT().GetRole(gomock.Any(), "1", 100).Return(nil, models.ErrRoleNotFound)
+			},
+		},
+		{
+			name:          "UsernameTaken",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "Abc123"),
+			expectedCode:  http.StatusConflict,
+			expectedError: models.ErrUserAlreadyExists,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectInvitation(m, invitation, manager)
+				m.EXPECT().GetUser(gomock.Any(), "1", "janedoe").Return(&newUser, nil)
+			},
+		},
+		{
+			name:          "InvitationUsedOrResentConcurrently",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidInvitationToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectInvitation(m, invitation, manager)
+				m.EXPECT().GetUser(gomock.Any(), "1", "janedoe").Return(nil, models.ErrUserNotFound)
+				expectUnitOfWork(m)
+				m.EXPECT().UseInvitation(gomock.Any(), "1", "7", "t").Return(models.ErrInvitationNotFound)
+			},
+		},
+		{
+			name:          "ErrWhenAddTeamMember",
+			postForm:      true,
+			body:          acceptForm(token, "janedoe", "Abc123"),
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectInvitation(m, invitation, manager)
+				m.EXPECT().GetUser(gomock.Any(), "1", "janedoe").Return(nil, models.ErrUserNotFound)
+				expectUnitOfWork(m)
+				m.EXPECT().UseInvitation(gomock.Any(), "1", "7", "t").Return(nil)
+				m.EXPECT().SaveUser(gomock.Any(), "1", "janedoe", passwordOf("Abc123"), models.UserRole).Return(&newUser, nil)
+				m.EXPECT().AddTeamMember(gomock.Any(), "1", "4", "8").Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "UserJoinsTeamOfManager",
+			postForm:     true,
+			body:         acceptForm(token, "janedoe", "Abc123"),
+			expectedCode: http.StatusCreated,
+			expectedBody: string(jsonUser),
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectInvitation(m, invitation, manager)
+				m.EXPECT().GetUser(gomock.Any(), "1", "janedoe").Return(nil, models.ErrUserNotFound)
+				expectUnitOfWork(m)
+				m.EXPECT().UseInvitation(gomock.Any(), "1", "7", "t").Return(nil)
+				m.EXPECT().SaveUser(gomock.Any(), "1", "janedoe", passwordOf("Abc123"), models.UserRole).Return(&newUser, nil)
+				m.EXPECT().AddTeamMember(gomock.Any(), "1", "4", "8").Return(nil)
+			},
+		},
+		{
+			name:         "InvitedByAdmin",
+			postForm:     true,
+			body:         acceptForm(token, "janedoe", "Abc123"),
+			expectedCode: http.StatusCreated,
+			expectedBody: string(jsonUser),
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectInvitation(m, invitation, admin)
+				m.EXPECT().GetUser(gomock.Any(), "1", "janedoe").Return(nil, models.ErrUserNotFound)
+				expectUnitOfWork(m)
+				m.EXPECT().UseInvitation(gomock.Any(), "1", "7", "t").Return(nil)
+				m.EXPECT().SaveUser(gomock.Any(), "1", "janedoe", passwordOf("Abc123"), models.UserRole).Return(&newUser, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/signup/invitation", tc)
+		})
+	}
+}
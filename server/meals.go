@@ -2,18 +2,15 @@ package server
 
 import (
 	"calories-counter/models"
+	"context"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
+	"time"
 )
 
-func CreateMeal(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	caloriesDatastore := c.MustGet("caloriesDatastore").(models.CaloriesDatastore)
-	user := c.MustGet("caller").(models.User)
-	if _, ok := c.Get("user"); ok {
-		user = c.MustGet("user").(models.User)
-	}
+func (s *Server) CreateMeal(c *gin.Context) {
+	user := targetUser(c)
 
 	var body MealPostBody
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -27,7 +24,7 @@ func CreateMeal(c *gin.Context) {
 	}
 
 	if body.Calories == nil {
-		calories, err := caloriesDatastore.GetCalories(body.Name)
+		calories, err := s.calories.GetCalories(c.Request.Context(), body.Name)
 		if err != nil {
 			log.Printf("couldn not get calories for meal: %s", body.Name)
 		}
@@ -39,11 +36,20 @@ func CreateMeal(c *gin.Context) {
 		}
 	}
 
-	newMeal, err := userRepo.SaveMeal(user.ID, models.Meal{
-		Date:     body.Date.String(),
-		Time:     body.Time.String(),
-		Name:     body.Name,
-		Calories: *body.Calories,
+	var newMeal *models.Meal
+	err := s.unitOfWork.Do(c.Request.Context(), func(ctx context.Context, repos models.Repositories) error {
+		var err error
+		newMeal, err = repos.Meals.SaveMeal(ctx, user.ID, models.Meal{
+			Date:     body.Date.String(),
+			Time:     body.Time.String(),
+			Name:     body.Name,
+			Calories: *body.Calories,
+		})
+		if err != nil {
+			return err
+		}
+		newMeal.CaloriesDeficit, err = repos.DailyTotals.UpdateDailyTotal(ctx, user.ID, newMeal.Date)
+		return err
 	})
 	if err != nil {
 		handleErrorResponse(c, err)
@@ -53,15 +59,11 @@ func CreateMeal(c *gin.Context) {
 	c.JSON(http.StatusCreated, newMeal)
 }
 
-func GetMeals(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	user := c.MustGet("caller").(models.User)
-	if _, ok := c.Get("user"); ok {
-		user = c.MustGet("user").(models.User)
-	}
+func (s *Server) GetMeals(c *gin.Context) {
+	user := targetUser(c)
 
-	page, perPage, filter := PageParams(c)
-	meals, err := userRepo.GetMeals(user.ID, page, perPage, filter)
+	params := PageParams(c)
+	meals, err := s.meals.GetMeals(c.Request.Context(), user.ID, params)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -72,20 +74,16 @@ func GetMeals(c *gin.Context) {
 		Links []Link `json:"links"`
 	}{
 		MealSlice: meals,
-		Links:     CreateLinks(c, meals.Total, page, perPage),
+		Links:     CreateLinks(c, meals.Total, params, meals.NextCursor),
 	}
 
 	c.PureJSON(http.StatusOK, result)
 }
 
-func GetMeal(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	user := c.MustGet("caller").(models.User)
-	if _, ok := c.Get("user"); ok {
-		user = c.MustGet("user").(models.User)
-	}
+func (s *Server) GetMeal(c *gin.Context) {
+	user := targetUser(c)
 
-	meal, err := userRepo.GetMeal(user.ID, c.Param("meal_id"))
+	meal, err := s.meals.GetMeal(c.Request.Context(), user.ID, c.Param("meal_id"))
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -94,12 +92,8 @@ func GetMeal(c *gin.Context) {
 	c.JSON(http.StatusOK, meal)
 }
 
-func UpdateMeal(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	user := c.MustGet("caller").(models.User)
-	if _, ok := c.Get("user"); ok {
-		user = c.MustGet("user").(models.User)
-	}
+func (s *Server) UpdateMeal(c *gin.Context) {
+	user := targetUser(c)
 
 	var body MealPutBody
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -112,54 +106,71 @@ func UpdateMeal(c *gin.Context) {
 		return
 	}
 
-	meal, err := userRepo.GetMeal(user.ID, c.Param("meal_id"))
-	if err != nil {
-		handleErrorResponse(c, err)
-		return
-	}
+	// the meal is read in the unit of work which updates daily totals of its old and new date
+	var updatedMeal *models.Meal
+	err := s.unitOfWork.Do(c.Request.Context(), func(ctx context.Context, repos models.Repositories) error {
+		meal, err := repos.Meals.GetMeal(ctx, user.ID, c.Param("meal_id"))
+		if err != nil {
+			return err
+		}
+		oldDate := meal.Date
 
-	if body.Name != "" {
-		meal.Name = body.Name
-	}
-	if body.Time != nil {
-		meal.Time = body.Time.String()
-	}
-	if body.Date != nil {
-		meal.Date = body.Date.String()
-	}
-	if body.Calories != nil {
-		meal.Calories = *body.Calories
-	}
-	updateMeal, err := userRepo.UpdateMeal(user.ID, *meal)
+		if body.Name != "" {
+			meal.Name = body.Name
+		}
+		if body.Time != nil {
+			meal.Time = body.Time.String()
+		}
+		if body.Date != nil {
+			meal.Date = body.Date.String()
+		}
+		if body.Calories != nil {
+			meal.Calories = *body.Calories
+		}
+		updatedMeal, err = repos.Meals.UpdateMeal(ctx, user.ID, *meal)
+		if err != nil {
+			return err
+		}
+		updatedMeal.CaloriesDeficit, err = repos.DailyTotals.UpdateDailyTotal(ctx, user.ID, updatedMeal.Date)
+		if err != nil || updatedMeal.Date == oldDate {
+			return err
+		}
+		_, err = repos.DailyTotals.UpdateDailyTotal(ctx, user.ID, oldDate)
+		return err
+	})
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, updateMeal)
+	c.JSON(http.StatusOK, updatedMeal)
 }
 
-func DeleteMeal(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	user := c.MustGet("caller").(models.User)
-	if _, ok := c.Get("user"); ok {
-		user = c.MustGet("user").(models.User)
-	}
+func (s *Server) DeleteMeal(c *gin.Context) {
+	user := targetUser(c)
 
-	err := userRepo.DeleteMeal(user.ID, c.Param("meal_id"))
+	err := s.unitOfWork.Do(c.Request.Context(), func(ctx context.Context, repos models.Repositories) error {
+		meal, err := repos.Meals.GetMeal(ctx, user.ID, c.Param("meal_id"))
+		if err != nil {
+			return err
+		}
+		err = repos.Meals.DeleteMeal(ctx, user.ID, meal.ID)
+		if err != nil {
+			return err
+		}
+		_, err = repos.DailyTotals.UpdateDailyTotal(ctx, user.ID, meal.Date)
+		return err
+	})
 	if err != nil {
 		handleErrorResponse(c, err)
+		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
-func UpdateSettings(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	user := c.MustGet("caller").(models.User)
-	if _, ok := c.Get("user"); ok {
-		user = c.MustGet("user").(models.User)
-	}
+func (s *Server) UpdateSettings(c *gin.Context) {
+	user := targetUser(c)
 	var body SettingsPutBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		handleErrorResponse(c, ErrInvalidJSON)
@@ -170,7 +181,7 @@ func UpdateSettings(c *gin.Context) {
 		return
 	}
 
-	setting, err := userRepo.UpdateSettings(user.ID, models.Settings{ExpectedDailyCalories: *body.ExpectedDailyCalories})
+	setting, err := s.settings.UpdateSettings(c.Request.Context(), user.ID, models.Settings{ExpectedDailyCalories: *body.ExpectedDailyCalories})
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -179,14 +190,10 @@ func UpdateSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, setting)
 }
 
-func GetSettings(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	user := c.MustGet("caller").(models.User)
-	if _, ok := c.Get("user"); ok {
-		user = c.MustGet("user").(models.User)
-	}
+func (s *Server) GetSettings(c *gin.Context) {
+	user := targetUser(c)
 
-	setting, err := userRepo.GetSettings(user.ID)
+	setting, err := s.settings.GetSettings(c.Request.Context(), user.ID)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -194,3 +201,28 @@ func GetSettings(c *gin.Context) {
 
 	c.JSON(http.StatusOK, setting)
 }
+
+// GetSummary lists daily totals of the user, optional from and to query parameters (YYYY-MM-DD) limit the days
+func (s *Server) GetSummary(c *gin.Context) {
+	user := targetUser(c)
+
+	from, to := c.Query("from"), c.Query("to")
+	for _, date := range []string{from, to} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			handleErrorResponse(c, ErrInvalidDateRange)
+			return
+		}
+	}
+	if from != "" && to != "" && from > to {
+		handleErrorResponse(c, ErrInvalidDateRange)
+		return
+	}
+
+	totals, err := s.dailyTotals.GetDailyTotals(c.Request.Context(), user.ID, from, to)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": totals})
+}
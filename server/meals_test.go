@@ -4,6 +4,7 @@ import (
 	"calories-counter/adapters/user_datastore"
 	"calories-counter/models"
 	"encoding/json"
+	"errors"
 	"github.com/golang/mock/gomock"
 	"net/http"
 	"testing"
@@ -33,14 +34,32 @@ func TestCreateMeal(t *testing.T) {
 			expectedError: ErrMissingDate,
 		},
 
+		{
+			name:          "ErrWhenUpdateDailyTotal",
+			body:          `{"name":"chicken", "date":"2020-01-01", "time":"10:10:10", "calories":100}`,
+			user:          models.User{ID: "1"},
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				saved := meal
+				m.EXPECT().SaveMeal(gomock.Any(), "1", gomock.Any()).Return(&saved, nil)
+				m.EXPECT().UpdateDailyTotal(gomock.Any(), "1", "2020-01-01").Return(false, errors.New("err"))
+			},
+		},
+
 		// success tests
 		{
 			name:         "SavedMeal",
 			body:         `{"name":"chicken", "date":"2020-01-01", "time":"10:10:10", "calories":100}`,
 			user:         models.User{ID: "1"},
 			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":"1","date":"2020-01-01","time":"11:11:11","name":"test","calories":100,"calories_deficit":true}`,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().SaveMeal("1", gomock.Any()).Return(&meal, nil)
+				expectUnitOfWork(m)
+				saved := meal
+				m.EXPECT().SaveMeal(gomock.Any(), "1", gomock.Any()).Return(&saved, nil)
+				m.EXPECT().UpdateDailyTotal(gomock.Any(), "1", "2020-01-01").Return(true, nil)
 			},
 		},
 	}
@@ -71,7 +90,7 @@ func TestGetMeal(t *testing.T) {
 			expectedError: models.ErrMealNotFound,
 			user:          models.User{ID: "1"},
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetMeal("1", "1").Return(nil, models.ErrMealNotFound)
+				m.EXPECT().GetMeal(gomock.Any(), "1", "1").Return(nil, models.ErrMealNotFound)
 			},
 		},
 
@@ -82,7 +101,7 @@ func TestGetMeal(t *testing.T) {
 			expectedBody: string(jsonTestMeal),
 			user:         models.User{ID: "1"},
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetMeal("1", "1").Return(testMeal, nil)
+				m.EXPECT().GetMeal(gomock.Any(), "1", "1").Return(testMeal, nil)
 			},
 		},
 	}
@@ -95,7 +114,7 @@ func TestGetMeal(t *testing.T) {
 }
 
 func TestUpdateMeal(t *testing.T) {
-	testMeal := &models.Meal{
+	testMeal := models.Meal{
 		ID:              "1",
 		Date:            "2020-01-01",
 		Time:            "11:11:11",
@@ -103,6 +122,11 @@ func TestUpdateMeal(t *testing.T) {
 		Calories:        10,
 		CaloriesDeficit: false,
 	}
+	updatedMeal := testMeal
+	updatedMeal.Name = "chicken"
+	updatedMeal.Calories = 100
+	movedMeal := testMeal
+	movedMeal.Date = "2020-01-02"
 
 	testCases := []testCase{
 		// error tests
@@ -117,7 +141,8 @@ func TestUpdateMeal(t *testing.T) {
 			expectedCode:  http.StatusNotFound,
 			expectedError: models.ErrMealNotFound,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetMeal("", "1").Return(nil, models.ErrMealNotFound)
+				expectUnitOfWork(m)
+				m.EXPECT().GetMeal(gomock.Any(), "", "1").Return(nil, models.ErrMealNotFound)
 			},
 		},
 
@@ -126,11 +151,29 @@ func TestUpdateMeal(t *testing.T) {
 			name:         "UpdateMeal",
 			body:         `{"name":"chicken", "calories":100}`,
 			expectedCode: http.StatusOK,
+			expectedBody: `{"id":"1","date":"2020-01-01","time":"11:11:11","name":"chicken","calories":100,"calories_deficit":false}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				meal := testMeal
+				m.EXPECT().GetMeal(gomock.Any(), "", "1").Return(&meal, nil)
+				updated := updatedMeal
+				m.EXPECT().UpdateMeal(gomock.Any(), "", updatedMeal).Return(&updated, nil)
+				m.EXPECT().UpdateDailyTotal(gomock.Any(), "", "2020-01-01").Return(false, nil)
+			},
+		},
+		{
+			name:         "MovedMealUpdatesBothDays",
+			body:         `{"date":"2020-01-02"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":"1","date":"2020-01-02","time":"11:11:11","name":"meal","calories":10,"calories_deficit":true}`,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetMeal("", "1").Return(testMeal, nil)
-				testMeal.Name = "chicken"
-				testMeal.Calories = 100
-				m.EXPECT().UpdateMeal("", *testMeal)
+				expectUnitOfWork(m)
+				meal := testMeal
+				m.EXPECT().GetMeal(gomock.Any(), "", "1").Return(&meal, nil)
+				moved := movedMeal
+				m.EXPECT().UpdateMeal(gomock.Any(), "", movedMeal).Return(&moved, nil)
+				m.EXPECT().UpdateDailyTotal(gomock.Any(), "", "2020-01-02").Return(true, nil)
+				m.EXPECT().UpdateDailyTotal(gomock.Any(), "", "2020-01-01").Return(true, nil)
 			},
 		},
 	}
@@ -141,3 +184,131 @@ func TestUpdateMeal(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteMeal(t *testing.T) {
+	testMeal := models.Meal{ID: "1", Date: "2020-01-01", Time: "11:11:11", Name: "meal", Calories: 10}
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "MealNotFound",
+			user:          models.User{ID: "1"},
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrMealNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				m.EXPECT().GetMeal(gomock.Any(), "1", "1").Return(nil, models.ErrMealNotFound)
+			},
+		},
+		{
+			name:          "ErrWhenUpdateDailyTotal",
+			user:          models.User{ID: "1"},
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				meal := testMeal
+				m.EXPECT().GetMeal(gomock.Any(), "1", "1").Return(&meal, nil)
+				m.EXPECT().DeleteMeal(gomock.Any(), "1", "1").Return(nil)
+				m.EXPECT().UpdateDailyTotal(gomock.Any(), "1", "2020-01-01").Return(false, errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "MealDeleted",
+			user:         models.User{ID: "1"},
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				meal := testMeal
+				m.EXPECT().GetMeal(gomock.Any(), "1", "1").Return(&meal, nil)
+				m.EXPECT().DeleteMeal(gomock.Any(), "1", "1").Return(nil)
+				m.EXPECT().UpdateDailyTotal(gomock.Any(), "1", "2020-01-01").Return(true, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "DELETE", "/v1/meals/1", tc)
+		})
+	}
+}
+
+func TestGetSummary(t *testing.T) {
+	totals := []models.DailyTotal{
+		{Date: "2020-01-01", TotalCalories: 90, CaloriesDeficit: true},
+		{Date: "2020-01-02", TotalCalories: 120},
+	}
+	jsonTotals, _ := json.Marshal(totals)
+
+	testCases := []struct {
+		testCase
+		query string
+	}{
+		// error tests
+		{
+			testCase: testCase{
+				name:          "InvalidFrom",
+				user:          models.User{ID: "1"},
+				expectedCode:  http.StatusBadRequest,
+				expectedError: ErrInvalidDateRange,
+			},
+			query: "?from=2020-13-01",
+		},
+		{
+			testCase: testCase{
+				name:          "FromAfterTo",
+				user:          models.User{ID: "1"},
+				expectedCode:  http.StatusBadRequest,
+				expectedError: ErrInvalidDateRange,
+			},
+			query: "?from=2020-01-02&to=2020-01-01",
+		},
+		{
+			testCase: testCase{
+				name:          "ErrWhenGetDailyTotals",
+				user:          models.User{ID: "1"},
+				expectedCode:  http.StatusInternalServerError,
+				expectedError: ErrInternalServerError,
+				setupMockUser: func(m *user_datastore.MockUserDatastore) {
+					m.EXPECT().GetDailyTotals(gomock.Any(), "1", "", "").Return(nil, errors.New("err"))
+				},
+			},
+		},
+
+		// success tests
+		{
+			testCase: testCase{
+				name:         "SummaryReturned",
+				user:         models.User{ID: "1"},
+				expectedCode: http.StatusOK,
+				expectedBody: `{"items":` + string(jsonTotals) + `}`,
+				setupMockUser: func(m *user_datastore.MockUserDatastore) {
+					m.EXPECT().GetDailyTotals(gomock.Any(), "1", "2020-01-01", "2020-01-02").Return(totals, nil)
+				},
+			},
+			query: "?from=2020-01-01&to=2020-01-02",
+		},
+		{
+			testCase: testCase{
+				name:         "SummaryOfTeamMemberReturned",
+				caller:       models.User{ID: "4", RoleID: models.UserManagerRole},
+				user:         models.User{ID: "1"},
+				expectedCode: http.StatusOK,
+				expectedBody: `{"items":` + string(jsonTotals) + `}`,
+				setupMockUser: func(m *user_datastore.MockUserDatastore) {
+					m.EXPECT().GetDailyTotals(gomock.Any(), "1", "2020-01-01", "").Return(totals, nil)
+				},
+			},
+			query: "?from=2020-01-01",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "GET", "/v1/summary"+tc.query, tc.testCase)
+		})
+	}
+}
@@ -1,28 +1,45 @@
 package server
 
 import (
+	"calories-counter/common"
 	"calories-counter/models"
+	"calories-counter/policy"
+	"context"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
+	"net/http"
 	"strings"
+	"time"
 )
 
-// SetVars middleware function which pass variables (like secrets, db connectors) to handlers
-func SetVars(m map[string]interface{}) gin.HandlerFunc {
+// keys of request values set by the middlewares, handlers read them with the typed accessors below
+const (
+	callerKey        = "caller"
+	claimsKey        = "claims"
+	apiKeyKey        = "api_key"
+	targetUserKey    = "user"
+	callerSubjectKey = "caller_subject"
+)
+
+// RequestTimeout middleware function which sets deadline on request context,
+// datastore and external api calls made by handlers are cancelled when it passes or the client disconnects
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		for k, v := range m {
-			c.Set(k, v)
+		if timeout <= 0 {
+			return
 		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
 	}
 }
 
-// AuthVerify middleware function which verifies jwt tokens
-func AuthVerify() gin.HandlerFunc {
+// AuthVerify middleware function which verifies jwt tokens and rejects revoked ones,
+// bearer token can be an API key as well
+func (s *Server) AuthVerify() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-		secretKey := c.MustGet("secretKey").(string)
-
 		auth := strings.Split(c.GetHeader("Authorization"), " ")
 		if !(len(auth) == 2 && auth[0] == "Bearer") {
 			c.Abort()
@@ -31,69 +48,167 @@ func AuthVerify() gin.HandlerFunc {
 		}
 
 		tokenString := auth[1]
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, ErrUnexpectedSigningMethod
+		if isAPIKey(tokenString) {
+			key, user, err := s.verifyAPIKey(c, tokenString)
+			if err != nil {
+				c.Abort()
+				handleErrorResponse(c, err)
+				return
 			}
-			return []byte(secretKey), nil
-		})
+			// set caller and the key it is authenticated with
+			setCaller(c, *user)
+			setAPIKey(c, *key)
+			return
+		}
+
+		claims := &common.JWTClaims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, s.keys.Keyfunc)
 		if err != nil {
 			c.Abort()
 			log.Info(err)
 			handleErrorResponse(c, ErrUnauthorized)
 			return
 		}
+		// tokens without jti can not be revoked, tokens with audience like mfa tokens are not access tokens
+		if !token.Valid || claims.Id == "" || claims.Audience != "" {
+			c.Abort()
+			handleErrorResponse(c, ErrUnauthorized)
+			return
+		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			log.Info(claims["UUID"], claims["AccountID"], claims["iat"], claims["exp"])
-			user, err := userRepo.GetUserById(claims["AccountID"].(string), claims["UUID"].(string))
-			if err != nil {
-				c.Abort()
-				handleErrorResponse(c, ErrUnauthorized)
-				return
-			}
+		log.Info(claims.UUID, claims.AccountID, claims.IssuedAt, claims.ExpiresAt)
+		revoked, err := s.revocations.IsAccessTokenRevoked(c.Request.Context(), claims.Id, claims.FamilyID)
+		if err != nil {
+			c.Abort()
+			handleErrorResponse(c, err)
+			return
+		} else if revoked {
+			c.Abort()
+			handleErrorResponse(c, ErrUnauthorized)
+			return
+		}
 
-			// set caller
-			c.Set("caller", *user)
-		} else {
+		user, err := s.users.GetUserById(c.Request.Context(), claims.AccountID, claims.UUID)
+		if err != nil {
 			c.Abort()
 			handleErrorResponse(c, ErrUnauthorized)
 			return
 		}
+
+		// set caller and claims of its token
+		setCaller(c, *user)
+		setClaims(c, *claims)
 	}
 }
 
-func RoleAccessVerify(roleIDs ...int) gin.HandlerFunc {
+// methodAction returns readAction for GET requests and writeAction for the others
+func methodAction(c *gin.Context, readAction, writeAction policy.Action) policy.Action {
+	if c.Request.Method == http.MethodGet {
+		return readAction
+	}
+	return writeAction
+}
+
+// PermissionVerify middleware function which checks the policy lets the caller do readAction for GET requests
+// and writeAction for the others, the action is not done to a particular user
+func (s *Server) PermissionVerify(readAction, writeAction policy.Action) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		caller := c.MustGet("caller").(models.User)
-
-		if roleIDs != nil {
-			var hasAccess bool
-			for _, roleID := range roleIDs {
-				if roleID == caller.RoleID {
-					hasAccess = true
-				}
-			}
-			if !hasAccess {
-				c.Abort()
-				handleErrorResponse(c, ErrInsufficientPermissions)
-				return
-			}
+		if err := s.authorize(c, methodAction(c, readAction, writeAction), nil); err != nil {
+			c.Abort()
+			handleErrorResponse(c, err)
 		}
 	}
 }
 
-func UserVerify() gin.HandlerFunc {
+// ScopeVerify middleware function which checks API key of the caller grants readScope for GET requests
+// and writeScope for the others, callers signed in with a password are not limited by scopes
+func ScopeVerify(readScope, writeScope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		caller := c.MustGet("caller").(models.User)
-		userRepo := c.MustGet("userDatastore").(models.UserDatastore)
+		key, ok := getAPIKey(c)
+		if !ok {
+			return
+		}
+		scope := writeScope
+		if c.Request.Method == http.MethodGet {
+			scope = readScope
+		}
+		if !key.Allows(scope) {
+			c.Abort()
+			handleErrorResponse(c, ErrInsufficientScope)
+		}
+	}
+}
 
-		user, err := userRepo.GetUserById(caller.AccountID, c.Param("user_id"))
+// SessionVerify middleware function which rejects API keys on endpoints managing credentials and sessions
+func SessionVerify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := getAPIKey(c); ok {
+			c.Abort()
+			handleErrorResponse(c, ErrSessionRequired)
+		}
+	}
+}
+
+// UserVerify middleware function which loads user of user_id path parameter and checks the policy lets the caller
+// do readAction to it for GET requests and writeAction for the others, e.g. managers read meals of their team
+func (s *Server) UserVerify(readAction, writeAction policy.Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := getCaller(c)
+
+		user, err := s.users.GetUserById(c.Request.Context(), caller.AccountID, c.Param("user_id"))
 		if err != nil {
 			c.Abort()
 			handleErrorResponse(c, err)
 			return
 		}
-		c.Set("user", *user)
+		if err := s.authorize(c, methodAction(c, readAction, writeAction), user); err != nil {
+			c.Abort()
+			handleErrorResponse(c, err)
+			return
+		}
+		setTargetUser(c, *user)
+	}
+}
+
+func setClaims(c *gin.Context, claims common.JWTClaims) {
+	c.Set(claimsKey, claims)
+}
+
+// getClaims returns claims of the access token verified by AuthVerify
+func getClaims(c *gin.Context) common.JWTClaims {
+	return c.MustGet(claimsKey).(common.JWTClaims)
+}
+
+func setAPIKey(c *gin.Context, key models.APIKey) {
+	c.Set(apiKeyKey, key)
+}
+
+// getAPIKey returns API key the caller is authenticated with, ok is false for access tokens
+func getAPIKey(c *gin.Context) (models.APIKey, bool) {
+	key, ok := c.Get(apiKeyKey)
+	if !ok {
+		return models.APIKey{}, false
+	}
+	return key.(models.APIKey), true
+}
+
+func setCaller(c *gin.Context, caller models.User) {
+	c.Set(callerKey, caller)
+}
+
+// getCaller returns user authenticated by AuthVerify
+func getCaller(c *gin.Context) models.User {
+	return c.MustGet(callerKey).(models.User)
+}
+
+func setTargetUser(c *gin.Context, user models.User) {
+	c.Set(targetUserKey, user)
+}
+
+// targetUser returns user set by UserVerify for admin routes or the caller
+func targetUser(c *gin.Context) models.User {
+	if user, ok := c.Get(targetUserKey); ok {
+		return user.(models.User)
 	}
+	return getCaller(c)
 }
@@ -0,0 +1,502 @@
+package server
+
+import (
+	"calories-counter/adapters/calories_datastore"
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/common"
+	"calories-counter/models"
+	"calories-counter/policy"
+	"calories-counter/signing"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthVerify(t *testing.T) {
+	user := &models.User{ID: "3", AccountID: "1", Username: "user"}
+	otherKey, err := signing.GenerateKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeys, err := signing.NewKeySet(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := func(keys *signing.KeySet, modify func(claims *common.JWTClaims)) string {
+		claims := common.JWTClaims{
+			UUID:      "3",
+			AccountID: "1",
+			FamilyID:  "family",
+			StandardClaims: jwt.StandardClaims{
+				Id:        "jti",
+				IssuedAt:  time.Now().Unix(),
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+			},
+		}
+		if modify != nil {
+			modify(&claims)
+		}
+		token, _ := keys.Sign(claims)
+		return token
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	apiKey := &models.APIKey{ID: "key", UserID: "3", AccountID: "1", KeyHash: hashToken(APIKeyPrefix + "key")}
+	expiredAPIKey := *apiKey
+	expiredAPIKey.ExpiresAt = &expired
+
+	testCases := []struct {
+		name          string
+		token         string
+		expectedCode  int
+		setupMockUser func(m *user_datastore.MockUserDatastore)
+	}{
+		// errors
+		{
+			name:         "InvalidSignature",
+			token:        signed(otherKeys, nil),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "Expired",
+			token: signed(testKeys, func(claims *common.JWTClaims) {
+				claims.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+			}),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "HMAC",
+			token: func() string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Id: "jti"}).SignedString([]byte("test"))
+				return token
+			}(),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "MFAToken",
+			token:        signed(testKeys, func(claims *common.JWTClaims) { claims.Audience = mfaAudience }),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "MissingJTI",
+			token:        signed(testKeys, func(claims *common.JWTClaims) { claims.Id = "" }),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Revoked",
+			token:        signed(testKeys, nil),
+			expectedCode: http.StatusUnauthorized,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti", "family").Return(true, nil)
+			},
+		},
+		{
+			name:         "ErrWhenIsAccessTokenRevoked",
+			token:        signed(testKeys, nil),
+			expectedCode: http.StatusInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti", "family").Return(false, errors.New("err"))
+			},
+		},
+		{
+			name:         "DeletedUser",
+			token:        signed(testKeys, nil),
+			expectedCode: http.StatusUnauthorized,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti", "family").Return(false, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:         "UnknownAPIKey",
+			token:        APIKeyPrefix + "key",
+			expectedCode: http.StatusUnauthorized,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAPIKeyByHash(gomock.Any(), hashToken(APIKeyPrefix+"key")).Return(nil, models.ErrAPIKeyNotFound)
+			},
+		},
+		{
+			name:         "ExpiredAPIKey",
+			token:        APIKeyPrefix + "key",
+			expectedCode: http.StatusUnauthorized,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAPIKeyByHash(gomock.Any(), hashToken(APIKeyPrefix+"key")).Return(&expiredAPIKey, nil)
+			},
+		},
+		{
+			name:         "APIKeyOfDeletedUser",
+			token:        APIKeyPrefix + "key",
+			expectedCode: http.StatusUnauthorized,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAPIKeyByHash(gomock.Any(), hashToken(APIKeyPrefix+"key")).Return(apiKey, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(nil, models.ErrUserNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "Verified",
+			token:        signed(testKeys, nil),
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti", "family").Return(false, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+			},
+		},
+		{
+			name:         "VerifiedAPIKey",
+			token:        APIKeyPrefix + "key",
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetAPIKeyByHash(gomock.Any(), hashToken(APIKeyPrefix+"key")).Return(apiKey, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+			mockUD := user_datastore.NewMockUserDatastore(controller)
+			if tc.setupMockUser != nil {
+				tc.setupMockUser(mockUD)
+			}
+
+			s, err := New(Config{
+				Keys:               testKeys,
+				UnitOfWork:         mockUD,
+				Credentials:        mockUD,
+				Accounts:           mockUD,
+				RefreshTokens:      mockUD,
+				Revocations:        mockUD,
+				TwoFactor:          mockUD,
+				LoginThrottle:      mockUD,
+				PasswordResets:     mockUD,
+				APIKeys:            mockUD,
+				Roles:              mockUD,
+				Teams:              mockUD,
+				OwnershipTransfers: mockUD,
+				Invitations:        mockUD,
+				Users:              mockUD,
+				Meals:              mockUD,
+				Settings:           mockUD,
+				DailyTotals:        mockUD,
+				Calories:           calories_datastore.NewMockCaloriesDatastore(controller),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/v1/ping", s.AuthVerify(), func(c *gin.Context) {
+				if key, ok := getAPIKey(c); ok {
+					if getCaller(c).ID != user.ID || key.ID != "key" {
+						t.Errorf("Expected caller and API key to be set")
+					}
+				} else if getCaller(c).ID != user.ID || getClaims(c).Id != "jti" {
+					t.Errorf("Expected caller and claims to be set")
+				}
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/v1/ping", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status code to be %d but was %d", tc.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestScopeVerify(t *testing.T) {
+	readOnly := models.APIKey{Scopes: []string{models.ScopeMealsRead}}
+	testCases := []struct {
+		name         string
+		method       string
+		apiKey       *models.APIKey
+		expectedCode int
+	}{
+		{name: "AccessToken", method: "POST", expectedCode: http.StatusOK},
+		{name: "KeyWithoutScopes", method: "POST", apiKey: &models.APIKey{}, expectedCode: http.StatusOK},
+		{name: "ReadScope", method: "GET", apiKey: &readOnly, expectedCode: http.StatusOK},
+		{name: "MissingWriteScope", method: "POST", apiKey: &readOnly, expectedCode: http.StatusForbidden},
+		{name: "MissingReadScope", method: "GET", apiKey: &models.APIKey{Scopes: []string{models.ScopeUsersManage}}, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tc.apiKey != nil {
+					setAPIKey(c, *tc.apiKey)
+				}
+			})
+			r.Handle(tc.method, "/v1/meals", ScopeVerify(models.ScopeMealsRead, models.ScopeMealsWrite), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, "/v1/meals", nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status code to be %d but was %d", tc.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestSessionVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/v1/me", SessionVerify(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.GET("/v1/key/me", func(c *gin.Context) {
+		setAPIKey(c, models.APIKey{})
+	}, SessionVerify(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for path, expectedCode := range map[string]int{"/v1/me": http.StatusOK, "/v1/key/me": http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != expectedCode {
+			t.Errorf("Expected status code of %s to be %d but was %d", path, expectedCode, w.Code)
+		}
+	}
+}
+
+func TestPermissionVerify(t *testing.T) {
+	dietitian := models.User{ID: "3", AccountID: "1", RoleID: models.CustomRoleMinID}
+	testCases := []struct {
+		name          string
+		method        string
+		caller        models.User
+		setupMockUser func(m *user_datastore.MockUserDatastore)
+		expectedCode  int
+	}{
+		{name: "UserReadsOwnMeals", method: "GET", caller: models.User{ID: "1", RoleID: models.UserRole},
+			expectedCode: http.StatusOK},
+		{name: "ManagerReadsOwnMeals", method: "GET", caller: models.User{ID: "4", RoleID: models.UserManagerRole},
+			expectedCode: http.StatusForbidden},
+		{
+			name:   "CustomRoleWritesOwnMeals",
+			method: "POST",
+			caller: dietitian,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "1", models.CustomRoleMinID).
+					Return(&models.Role{ID: models.CustomRoleMinID, Permissions: []string{policy.MealsWriteOwn}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "CustomRoleWritingMealsOfAnyUserWritesOwnMeals",
+			method: "POST",
+			caller: dietitian,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "1", models.CustomRoleMinID).
+					Return(&models.Role{ID: models.CustomRoleMinID, Permissions: []string{policy.MealsWriteAny}}, nil)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "DeletedCustomRole",
+			method: "GET",
+			caller: dietitian,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "1", models.CustomRoleMinID).Return(nil, models.ErrRoleNotFound)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "ErrWhenGetRole",
+			method: "GET",
+			caller: dietitian,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "1", models.CustomRoleMinID).Return(nil, errors.New("err"))
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+			mockUD := user_datastore.NewMockUserDatastore(controller)
+			if tc.setupMockUser != nil {
+				tc.setupMockUser(mockUD)
+			}
+			s := &Server{roles: mockUD}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Handle(tc.method, "/v1/meals", withCaller(tc.caller),
+				s.PermissionVerify(policy.ReadMeals, policy.WriteMeals), func(c *gin.Context) {
+					c.Status(http.StatusOK)
+				})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, "/v1/meals", nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status code to be %d but was %d", tc.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestUserVerify(t *testing.T) {
+	user := models.User{ID: "1", AccountID: "1", RoleID: models.UserRole}
+	manager := models.User{ID: "4", AccountID: "1", RoleID: models.UserManagerRole}
+	dietitian := models.User{ID: "3", AccountID: "1", RoleID: models.CustomRoleMinID}
+	testCases := []struct {
+		name          string
+		method        string
+		caller        models.User
+		setupMockUser func(m *user_datastore.MockUserDatastore)
+		expectedCode  int
+	}{
+		{
+			name:   "UserNotFound",
+			method: "GET",
+			caller: manager,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(nil, models.ErrUserNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "UserReadsOwnMeals",
+			method: "GET",
+			caller: user,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "UserReadsMealsOfOtherUser",
+			method: "GET",
+			caller: models.User{ID: "2", AccountID: "1", RoleID: models.UserRole},
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "AdminWritesMealsOfUser",
+			method: "POST",
+			caller: models.User{ID: "5", AccountID: "1", RoleID: models.AdminRole},
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "ManagerReadsMealsOfTeamMember",
+			method: "GET",
+			caller: manager,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+				m.EXPECT().GetTeamManagers(gomock.Any(), "1", "1").Return([]string{"4"}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "ManagerReadsMealsOfUserOutOfTeam",
+			method: "GET",
+			caller: manager,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+				m.EXPECT().GetTeamManagers(gomock.Any(), "1", "1").Return([]string{"6"}, nil)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "ManagerWritesMealsOfTeamMember",
+			method: "POST",
+			caller: manager,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "ErrWhenGetTeamManagers",
+			method: "GET",
+			caller: manager,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+				m.EXPECT().GetTeamManagers(gomock.Any(), "1", "1").Return(nil, errors.New("err"))
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:   "CustomRoleReadsMealsOfUser",
+			method: "GET",
+			caller: dietitian,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+				m.EXPECT().GetRole(gomock.Any(), "1", models.CustomRoleMinID).
+					Return(&models.Role{ID: models.CustomRoleMinID, Permissions: []string{policy.MealsReadAny}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "CustomRoleWritesMealsOfUser",
+			method: "POST",
+			caller: dietitian,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&user, nil)
+				m.EXPECT().GetRole(gomock.Any(), "1", models.CustomRoleMinID).
+					Return(&models.Role{ID: models.CustomRoleMinID, Permissions: []string{policy.MealsReadAny}}, nil)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+			mockUD := user_datastore.NewMockUserDatastore(controller)
+			if tc.setupMockUser != nil {
+				tc.setupMockUser(mockUD)
+			}
+			s := &Server{users: mockUD, roles: mockUD, teams: mockUD}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Handle(tc.method, "/v1/users/:user_id/meals", withCaller(tc.caller),
+				s.UserVerify(policy.ReadMeals, policy.WriteMeals), func(c *gin.Context) {
+					if targetUser(c).ID != user.ID {
+						t.Errorf("Expected user to be set")
+					}
+					c.Status(http.StatusOK)
+				})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, "/v1/users/1/meals", nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status code to be %d but was %d", tc.expectedCode, w.Code)
+			}
+		})
+	}
+}
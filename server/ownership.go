@@ -0,0 +1,141 @@
+package server
+
+import (
+	"calories-counter/models"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// OwnershipTransferTTL is how long the offered user can accept ownership
+const OwnershipTransferTTL = 7 * 24 * time.Hour
+
+// pendingOwnershipTransfer returns transfer of the caller's account which did not expire yet
+func (s *Server) pendingOwnershipTransfer(c *gin.Context) (*models.OwnershipTransfer, error) {
+	transfer, err := s.ownershipTransfers.GetOwnershipTransfer(c.Request.Context(), getCaller(c).AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(transfer.ExpiresAt) {
+		return nil, models.ErrOwnershipTransferNotFound
+	}
+	return transfer, nil
+}
+
+// CreateOwnershipTransfer offers ownership of the account to a user who is not an owner, the owner confirms
+// it with its password. The offer replaces pending one and takes effect when the user accepts it.
+func (s *Server) CreateOwnershipTransfer(c *gin.Context) {
+	caller := getCaller(c)
+	var body OwnershipTransferBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := s.verifyCallerPassword(c, body.Password); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	user, err := s.users.GetUserById(c.Request.Context(), caller.AccountID, body.UserID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if user.RoleID == models.OwnerRole {
+		handleErrorResponse(c, ErrInvalidTransferTarget)
+		return
+	}
+
+	transfer := models.OwnershipTransfer{
+		AccountID:     caller.AccountID,
+		FromUserID:    caller.ID,
+		ToUserID:      user.ID,
+		KeepOwnership: body.KeepOwnership,
+		ExpiresAt:     time.Now().Add(OwnershipTransferTTL),
+	}
+	err = s.ownershipTransfers.SaveOwnershipTransfer(c.Request.Context(), transfer)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transfer)
+}
+
+func (s *Server) GetOwnershipTransfer(c *gin.Context) {
+	transfer, err := s.pendingOwnershipTransfer(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+func (s *Server) CancelOwnershipTransfer(c *gin.Context) {
+	caller := getCaller(c)
+	err := s.ownershipTransfers.DeleteOwnershipTransfer(c.Request.Context(), caller.AccountID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptOwnershipTransfer makes the caller an owner when ownership was offered to it. The owner who offered it
+// becomes an admin unless it keeps ownership, offer of a user who is no longer an owner is void.
+// The offer is consumed and the roles change in one unit of work.
+func (s *Server) AcceptOwnershipTransfer(c *gin.Context) {
+	caller := getCaller(c)
+	transfer, err := s.pendingOwnershipTransfer(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if transfer.ToUserID != caller.ID {
+		handleErrorResponse(c, models.ErrOwnershipTransferNotFound)
+		return
+	}
+	var owner *models.User
+	err = s.unitOfWork.Do(c.Request.Context(), func(ctx context.Context, repos models.Repositories) error {
+		previousOwner, err := repos.Users.GetUserById(ctx, caller.AccountID, transfer.FromUserID)
+		if err == models.ErrUserNotFound {
+			return models.ErrOwnershipTransferNotFound
+		} else if err != nil {
+			return err
+		}
+		if previousOwner.RoleID != models.OwnerRole {
+			return models.ErrOwnershipTransferNotFound
+		}
+
+		// only one of concurrent accepts gets through and an offer replaced in the meantime is not accepted
+		err = repos.OwnershipTransfers.UseOwnershipTransfer(ctx, *transfer)
+		if err != nil {
+			return err
+		}
+
+		// the new owner is promoted first so the account is never without an owner
+		caller.RoleID = models.OwnerRole
+		owner, err = repos.Users.UpdateUser(ctx, caller)
+		if err != nil {
+			return err
+		}
+		if !transfer.KeepOwnership {
+			previousOwner.RoleID = models.AdminRole
+			_, err = repos.Users.UpdateUser(ctx, *previousOwner)
+		}
+		return err
+	})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, owner)
+}
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/models"
+	passwords "calories-counter/password"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// ownershipTransferOf matches models.OwnershipTransfer equal to the expected one except ExpiresAt,
+// which has to be OwnershipTransferTTL from now
+type ownershipTransferOf models.OwnershipTransfer
+
+func (t ownershipTransferOf) Matches(x interface{}) bool {
+	transfer, ok := x.(models.OwnershipTransfer)
+	if !ok {
+		return false
+	}
+	expiresIn := time.Until(transfer.ExpiresAt)
+	transfer.ExpiresAt = time.Time{}
+	return transfer == models.OwnershipTransfer(t) && expiresIn > OwnershipTransferTTL-time.Minute &&
+		expiresIn <= OwnershipTransferTTL
+}
+
+func (t ownershipTransferOf) String() string {
+	return "is ownership transfer to user " + t.ToUserID
+}
+
+func TestCreateOwnershipTransfer(t *testing.T) {
+	hash, err := passwords.Hash("Xyz123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caller := models.User{ID: "3", AccountID: "1", Username: "owner", RoleID: models.OwnerRole}
+	admin := models.User{ID: "5", AccountID: "1", Username: "admin", RoleID: models.AdminRole}
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "MissingUserID",
+			caller:        caller,
+			body:          `{"password": "Xyz123"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingUserID,
+		},
+		{
+			name:          "MissingPassword",
+			caller:        caller,
+			body:          `{"user_id": "5"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingPassword,
+		},
+		{
+			name:          "WrongPassword",
+			caller:        caller,
+			body:          `{"user_id": "5", "password": "Xyz124"}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrWrongPassword,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:owner")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "owner").Return(&hash, nil)
+				expectSignInFailure(m, "user:1:owner")
+			},
+		},
+		{
+			name:          "UserNotFound",
+			caller:        caller,
+			body:          `{"user_id": "5", "password": "Xyz123"}`,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrUserNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:owner")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "owner").Return(&hash, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "5").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "TransferToOwner",
+			caller:        caller,
+			body:          `{"user_id": "3", "password": "Xyz123"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidTransferTarget,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:owner")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "owner").Return(&hash, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(&caller, nil)
+			},
+		},
+		{
+			name:          "ErrWhenSaveOwnershipTransfer",
+			caller:        caller,
+			body:          `{"user_id": "5", "password": "Xyz123"}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:owner")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "owner").Return(&hash, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "5").Return(&admin, nil)
+				m.EXPECT().SaveOwnershipTransfer(gomock.Any(), gomock.Any()).Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "TransferOffered",
+			caller:       caller,
+			body:         `{"user_id": "5", "password": "Xyz123", "keep_ownership": true}`,
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:owner")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "owner").Return(&hash, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "5").Return(&admin, nil)
+				m.EXPECT().SaveOwnershipTransfer(gomock.Any(), ownershipTransferOf{
+					AccountID: "1", FromUserID: "3", ToUserID: "5", KeepOwnership: true,
+				}).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/owners/transfer", tc)
+		})
+	}
+}
+
+func TestGetOwnershipTransfer(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", RoleID: models.OwnerRole}
+	expiresAt := time.Date(2100, 1, 2, 3, 4, 5, 0, time.UTC)
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "TransferNotFound",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrOwnershipTransferNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(nil, models.ErrOwnershipTransferNotFound)
+			},
+		},
+		{
+			name:          "TransferExpired",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrOwnershipTransferNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(&models.OwnershipTransfer{
+					AccountID: "1", FromUserID: "3", ToUserID: "5", ExpiresAt: time.Now().Add(-time.Second),
+				}, nil)
+			},
+		},
+
+		// success tests
+		{
+			name:         "PendingTransfer",
+			caller:       caller,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"from_user_id":"3","to_user_id":"5","keep_ownership":false,"expires_at":"2100-01-02T03:04:05Z"}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(&models.OwnershipTransfer{
+					AccountID: "1", FromUserID: "3", ToUserID: "5", ExpiresAt: expiresAt,
+				}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "GET", "/v1/owners/transfer", tc)
+		})
+	}
+}
+
+func TestCancelOwnershipTransfer(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", RoleID: models.OwnerRole}
+	testCases := []testCase{
+		{
+			name:          "TransferNotFound",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrOwnershipTransferNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().DeleteOwnershipTransfer(gomock.Any(), "1").Return(models.ErrOwnershipTransferNotFound)
+			},
+		},
+		{
+			name:         "TransferCanceled",
+			caller:       caller,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().DeleteOwnershipTransfer(gomock.Any(), "1").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "DELETE", "/v1/owners/transfer", tc)
+		})
+	}
+}
+
+func TestAcceptOwnershipTransfer(t *testing.T) {
+	caller := models.User{ID: "5", AccountID: "1", Username: "admin", RoleID: models.AdminRole}
+	owner := models.User{ID: "3", AccountID: "1", Username: "owner", RoleID: models.OwnerRole}
+	newOwner := models.User{ID: "5", AccountID: "1", Username: "admin", RoleID: models.OwnerRole}
+	expiresAt := time.Now().Add(time.Hour)
+	transfer := func(keepOwnership bool) *models.OwnershipTransfer {
+		return &models.OwnershipTransfer{AccountID: "1", FromUserID: "3", ToUserID: "5",
+			KeepOwnership: keepOwnership, ExpiresAt: expiresAt}
+	}
+
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "TransferNotFound",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrOwnershipTransferNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(nil, models.ErrOwnershipTransferNotFound)
+			},
+		},
+		{
+			name:          "TransferToOtherUser",
+			caller:        models.User{ID: "6", AccountID: "1", RoleID: models.UserRole},
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrOwnershipTransferNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(transfer(false), nil)
+			},
+		},
+		{
+			name:          "PreviousOwnerDeleted",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrOwnershipTransferNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(transfer(false), nil)
+				expectUnitOfWork(m)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "PreviousOwnerNoLongerOwner",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrOwnershipTransferNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(transfer(false), nil)
+				expectUnitOfWork(m)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").
+					Return(&models.User{ID: "3", AccountID: "1", RoleID: models.AdminRole}, nil)
+			},
+		},
+		{
+			name:          "TransferAcceptedOrReplacedConcurrently",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrOwnershipTransferNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(transfer(false), nil)
+				expectUnitOfWork(m)
+				previousOwner := owner
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(&previousOwner, nil)
+				m.EXPECT().UseOwnershipTransfer(gomock.Any(), *transfer(false)).Return(models.ErrOwnershipTransferNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "OwnershipTransferred",
+			caller:       caller,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":"5","account_id":"1","username":"admin","role_id":3}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(transfer(false), nil)
+				expectUnitOfWork(m)
+				previousOwner := owner
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(&previousOwner, nil)
+				m.EXPECT().UseOwnershipTransfer(gomock.Any(), *transfer(false)).Return(nil)
+				gomock.InOrder(
+					m.EXPECT().UpdateUser(gomock.Any(), newOwner).Return(&newOwner, nil),
+					m.EXPECT().UpdateUser(gomock.Any(), models.User{ID: "3", AccountID: "1", Username: "owner",
+						RoleID: models.AdminRole}).Return(&owner, nil),
+				)
+			},
+		},
+		{
+			name:         "OwnershipShared",
+			caller:       caller,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":"5","account_id":"1","username":"admin","role_id":3}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetOwnershipTransfer(gomock.Any(), "1").Return(transfer(true), nil)
+				expectUnitOfWork(m)
+				previousOwner := owner
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(&previousOwner, nil)
+				m.EXPECT().UseOwnershipTransfer(gomock.Any(), *transfer(true)).Return(nil)
+				m.EXPECT().UpdateUser(gomock.Any(), newOwner).Return(&newOwner, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/owners/transfer/accept", tc)
+		})
+	}
+}
@@ -2,11 +2,10 @@ package server
 
 import (
 	"calories-counter/common"
+	"calories-counter/models"
 	"github.com/gin-gonic/gin"
 	"math"
-	"net/url"
 	"strconv"
-	"strings"
 )
 
 type Link struct {
@@ -14,63 +13,80 @@ type Link struct {
 	Href *string `json:"href"`
 }
 
-func PageParams(c *gin.Context) (page int, perPage int, filter string) {
-	page, _ = strconv.Atoi(c.Query("page"))
-	perPage, _ = strconv.Atoi(c.Query("per_page"))
+// PageParams reads list params from page, per_page, filter, sort and cursor query parameters
+func PageParams(c *gin.Context) models.ListParams {
+	page, _ := strconv.Atoi(c.Query("page"))
+	perPage, _ := strconv.Atoi(c.Query("per_page"))
 	if perPage > 100 {
 		perPage = 100
 	} else if perPage <= 0 {
 		perPage = 10
 	}
 
-	return page, perPage, c.Query("filter")
+	return models.ListParams{
+		Page:    page,
+		PerPage: perPage,
+		Filter:  c.Query("filter"),
+		Sort:    c.Query("sort"),
+		Cursor:  c.Query("cursor"),
+	}
 }
 
-func CreateLinks(c *gin.Context, total, page, perPage int) []Link {
-	params := url.Values{}
-	if c.Query("page") == "" {
-		params.Add("page", strconv.Itoa(page))
-	}
-	if c.Query("per_page") == "" {
-		params.Add("per_page", strconv.Itoa(perPage))
+// CreateLinks returns links to the pages of the list, all other query parameters
+// of the request (filter, sort) are kept in the links.
+// When the request has a cursor, next link points to nextCursor and there is no prev link,
+// first and last links switch back to offset pages.
+func CreateLinks(c *gin.Context, total int, params models.ListParams, nextCursor string) []Link {
+	pageUrl := func(page int) string {
+		query := c.Request.URL.Query()
+		query.Del("cursor")
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(params.PerPage))
+		return c.Request.Host + c.Request.URL.Path + "?" + query.Encode()
 	}
-	param := params.Encode()
-
-	query := c.Request.URL.Query()
-	appUrl := c.Request.Host + c.Request.URL.RequestURI()
-	if len(query) == 0 {
-		appUrl = appUrl + "?" + param
-	} else if param != "" {
-		appUrl = appUrl + "&" + param
+	cursorUrl := func(cursor string) string {
+		query := c.Request.URL.Query()
+		query.Del("page")
+		query.Set("per_page", strconv.Itoa(params.PerPage))
+		query.Set("cursor", cursor)
+		return c.Request.Host + c.Request.URL.Path + "?" + query.Encode()
 	}
 
-	var links []Link
 	lastPage := 0
 	if total > 0 {
-		lastPage = int(math.Ceil(float64(total)/float64(perPage))) - 1
+		lastPage = int(math.Ceil(float64(total)/float64(params.PerPage))) - 1
 	}
 
-	first := strings.Replace(appUrl, "page="+strconv.Itoa(page), "page=0", 1)
+	if params.Cursor != "" {
+		var next *string
+		if nextCursor != "" {
+			next = common.String(cursorUrl(nextCursor))
+		}
+
+		return []Link{
+			{Rel: "self", Href: common.String(cursorUrl(params.Cursor))},
+			{Rel: "first", Href: common.String(pageUrl(0))},
+			{Rel: "prev", Href: nil},
+			{Rel: "next", Href: next},
+			{Rel: "last", Href: common.String(pageUrl(lastPage))},
+		}
+	}
 
 	var prev *string
-	if page > 0 {
-		prev = common.String(strings.Replace(appUrl, "page="+strconv.Itoa(page), "page="+strconv.Itoa(page-1), 1))
+	if params.Page > 0 {
+		prev = common.String(pageUrl(params.Page - 1))
 	}
 
 	var next *string
-	if page < lastPage {
-		next = common.String(strings.Replace(appUrl, "page="+strconv.Itoa(page), "page="+strconv.Itoa(page+1), 1))
+	if params.Page < lastPage {
+		next = common.String(pageUrl(params.Page + 1))
 	}
 
-	last := strings.Replace(appUrl, "page="+strconv.Itoa(page), "page="+strconv.Itoa(lastPage), 1)
-
-	links = []Link{
-		{Rel: "self", Href: &appUrl},
-		{Rel: "first", Href: &first},
+	return []Link{
+		{Rel: "self", Href: common.String(pageUrl(params.Page))},
+		{Rel: "first", Href: common.String(pageUrl(0))},
 		{Rel: "prev", Href: prev},
 		{Rel: "next", Href: next},
-		{Rel: "last", Href: &last},
+		{Rel: "last", Href: common.String(pageUrl(lastPage))},
 	}
-
-	return links
 }
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"calories-counter/common"
+	"calories-counter/models"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestPageParams(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected models.ListParams
+	}{
+		{
+			name:     "Defaults",
+			query:    "",
+			expected: models.ListParams{PerPage: 10},
+		},
+		{
+			name:     "PerPageLimit",
+			query:    "?page=2&per_page=1000",
+			expected: models.ListParams{Page: 2, PerPage: 100},
+		},
+		{
+			name:     "FilterAndSort",
+			query:    "?filter=calories%20gt%2010&sort=-date,calories",
+			expected: models.ListParams{PerPage: 10, Filter: "calories gt 10", Sort: "-date,calories"},
+		},
+		{
+			name:     "Cursor",
+			query:    "?cursor=abc&per_page=5",
+			expected: models.ListParams{PerPage: 5, Cursor: "abc"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/v1/meals"+tc.query, nil)
+
+			params := PageParams(c)
+			if !reflect.DeepEqual(params, tc.expected) {
+				t.Errorf("Expected params %+v but was %+v", tc.expected, params)
+			}
+		})
+	}
+}
+
+func TestCreateLinks(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "http://example.com/v1/meals?page=1&per_page=2&sort=-date,calories", nil)
+
+	links := CreateLinks(c, 5, PageParams(c), "")
+
+	expected := map[string]string{
+		"self":  "example.com/v1/meals?page=1&per_page=2&sort=-date%2Ccalories",
+		"first": "example.com/v1/meals?page=0&per_page=2&sort=-date%2Ccalories",
+		"prev":  "example.com/v1/meals?page=0&per_page=2&sort=-date%2Ccalories",
+		"next":  "example.com/v1/meals?page=2&per_page=2&sort=-date%2Ccalories",
+		"last":  "example.com/v1/meals?page=2&per_page=2&sort=-date%2Ccalories",
+	}
+	if len(links) != len(expected) {
+		t.Fatalf("Expected %d links but was %d", len(expected), len(links))
+	}
+	for _, link := range links {
+		if link.Href == nil || *link.Href != expected[link.Rel] {
+			t.Errorf("Expected %s link to be `%s` but was `%v`", link.Rel, expected[link.Rel], link.Href)
+		}
+	}
+}
+
+func TestCreateCursorLinks(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "http://example.com/v1/meals?per_page=2&cursor=abc&filter=calories%20gt%201", nil)
+
+	links := CreateLinks(c, 5, PageParams(c), "def")
+
+	expected := map[string]*string{
+		"self":  common.String("example.com/v1/meals?cursor=abc&filter=calories+gt+1&per_page=2"),
+		"first": common.String("example.com/v1/meals?filter=calories+gt+1&page=0&per_page=2"),
+		"prev":  nil,
+		"next":  common.String("example.com/v1/meals?cursor=def&filter=calories+gt+1&per_page=2"),
+		"last":  common.String("example.com/v1/meals?filter=calories+gt+1&page=2&per_page=2"),
+	}
+	if len(links) != len(expected) {
+		t.Fatalf("Expected %d links but was %d", len(expected), len(links))
+	}
+	for _, link := range links {
+		if !reflect.DeepEqual(link.Href, expected[link.Rel]) {
+			t.Errorf("Expected %s link to be `%v` but was `%v`", link.Rel, expected[link.Rel], link.Href)
+		}
+	}
+}
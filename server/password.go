@@ -0,0 +1,173 @@
+package server
+
+import (
+	"calories-counter/models"
+	passwords "calories-counter/password"
+	"calories-counter/policy"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+// PasswordResetTokenTTL is lifetime of a password reset token
+const PasswordResetTokenTTL = 24 * time.Hour
+
+// PasswordResetResponse is returned to the admin or user manager who passes the token to the user
+type PasswordResetResponse struct {
+	ResetToken string    `json:"reset_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// setPassword replaces password of the user, signs out all its sessions and deletes its API keys in one
+// unit of work, a key stolen together with the password stops working with it
+func (s *Server) setPassword(c *gin.Context, user *models.User, password string) error {
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	return s.unitOfWork.Do(c.Request.Context(), func(ctx context.Context, repos models.Repositories) error {
+		err := repos.Credentials.UpdateUserPassword(ctx, user.AccountID, user.ID, hash)
+		if err != nil {
+			return err
+		}
+		err = repos.Revocations.RevokeUserTokenFamilies(ctx, user.AccountID, user.ID)
+		if err != nil {
+			return err
+		}
+		return repos.APIKeys.DeleteUserAPIKeys(ctx, user.AccountID, user.ID)
+	})
+}
+
+// verifyCallerPassword returns ErrWrongPassword when password is not the caller's one, failures are
+// throttled like failed sign ins so a stolen access token is not enough to guess the password
+func (s *Server) verifyCallerPassword(c *gin.Context, password string) error {
+	caller := getCaller(c)
+	userKey := models.UserThrottleKey(caller.AccountID, caller.Username)
+	if err := s.checkSignInThrottle(c, userKey); err != nil {
+		return err
+	}
+	pass, err := s.credentials.GetUserPassword(c.Request.Context(), caller.AccountID, caller.Username)
+	if err != nil {
+		return err
+	}
+	ok, err := passwords.Verify(*pass, password)
+	if err != nil {
+		return err
+	} else if !ok {
+		s.recordSignInFailure(c, userKey)
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// ChangePassword replaces password of the caller, other sessions are signed out, API keys of the caller
+// are deleted and the caller gets new tokens
+func (s *Server) ChangePassword(c *gin.Context) {
+	caller := getCaller(c)
+	var body PasswordPutBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := s.verifyCallerPassword(c, body.OldPassword); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := s.setPassword(c, &caller, body.NewPassword); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	tokens, err := s.issueTokens(c.Request.Context(), &caller, uuid.New().String())
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreatePasswordReset issues one-time token which lets the user set a new password,
+// callers who manage only standard users can reset password only of them
+func (s *Server) CreatePasswordReset(c *gin.Context) {
+	caller := getCaller(c)
+
+	user, err := s.users.GetUserById(c.Request.Context(), caller.AccountID, c.Param("user_id"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if err := s.authorize(c, policy.WriteUser, user); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	resetToken, err := randomToken()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	expiresAt := time.Now().Add(PasswordResetTokenTTL)
+	err = s.passwordResets.SavePasswordResetToken(c.Request.Context(), models.PasswordResetToken{
+		TokenHash: hashToken(resetToken),
+		UserID:    user.ID,
+		AccountID: user.AccountID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, PasswordResetResponse{ResetToken: resetToken, ExpiresAt: expiresAt})
+}
+
+// ResetPassword sets a new password of the user with password reset token,
+// sessions and API keys of the user are revoked and failed sign ins forgotten
+func (s *Server) ResetPassword(c *gin.Context) {
+	resetToken := c.PostForm("reset_token")
+	password := c.PostForm("password")
+	if resetToken == "" {
+		handleErrorResponse(c, ErrMissingResetToken)
+		return
+	}
+	// invalid password does not use up the token
+	if err := ValidatePassword(password); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	stored, err := s.passwordResets.UsePasswordResetToken(ctx, hashToken(resetToken))
+	if err == models.ErrPasswordResetTokenNotFound {
+		handleErrorResponse(c, ErrInvalidResetToken)
+		return
+	} else if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		handleErrorResponse(c, ErrInvalidResetToken)
+		return
+	}
+
+	user, err := s.users.GetUserById(ctx, stored.AccountID, stored.UserID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if err := s.setPassword(c, user, password); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	s.resetSignInFailures(c, models.UserThrottleKey(user.AccountID, user.Username))
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/models"
+	passwords "calories-counter/password"
+	"calories-counter/policy"
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func resetForm(token, password string) string {
+	form := url.Values{}
+	form.Set("reset_token", token)
+	form.Set("password", password)
+	return form.Encode()
+}
+
+func TestChangePassword(t *testing.T) {
+	hash, err := passwords.Hash("Xyz123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caller := models.User{ID: "3", AccountID: "1", Username: "user"}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "MissingOldPassword",
+			caller:        caller,
+			body:          `{"new_password": "Abc123"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingPassword,
+		},
+		{
+			name:          "InvalidNewPassword",
+			caller:        caller,
+			body:          `{"old_password": "Xyz123", "new_password": "abc123"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidPassword,
+		},
+		{
+			name:          "WrongOldPassword",
+			caller:        caller,
+			body:          `{"old_password": "Xyz124", "new_password": "Abc123"}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrWrongPassword,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				expectSignInFailure(m, "user:1:user")
+			},
+		},
+		{
+			name:          "UserLocked",
+			caller:        caller,
+			body:          `{"old_password": "Xyz123", "new_password": "Abc123"}`,
+			expectedCode:  http.StatusLocked,
+			expectedError: ErrUserLocked,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetLoginFailures(gomock.Any(), "user:1:user").
+					Return(models.LoginFailures{Failures: LockoutThreshold, LastFailure: time.Now()}, nil)
+			},
+		},
+		{
+			name:          "ErrWhenRevokeSessions",
+			caller:        caller,
+			body:          `{"old_password": "Xyz123", "new_password": "Abc123"}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				expectUnitOfWork(m)
+				m.EXPECT().UpdateUserPassword(gomock.Any(), "1", "3", passwordOf("Abc123")).Return(nil)
+				m.EXPECT().RevokeUserTokenFamilies(gomock.Any(), "1", "3").Return(errors.New("err"))
+			},
+		},
+		{
+			name:          "ErrWhenDeleteAPIKeys",
+			caller:        caller,
+			body:          `{"old_password": "Xyz123", "new_password": "Abc123"}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				expectUnitOfWork(m)
+				m.EXPECT().UpdateUserPassword(gomock.Any(), "1", "3", passwordOf("Abc123")).Return(nil)
+				m.EXPECT().RevokeUserTokenFamilies(gomock.Any(), "1", "3").Return(nil)
+				m.EXPECT().DeleteUserAPIKeys(gomock.Any(), "1", "3").Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "PasswordChanged",
+			caller:       caller,
+			body:         `{"old_password": "Xyz123", "new_password": "Abc123"}`,
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetUserPassword(gomock.Any(), "1", "user").Return(&hash, nil)
+				expectUnitOfWork(m)
+				m.EXPECT().UpdateUserPassword(gomock.Any(), "1", "3", passwordOf("Abc123")).Return(nil)
+				m.EXPECT().RevokeUserTokenFamilies(gomock.Any(), "1", "3").Return(nil)
+				m.EXPECT().DeleteUserAPIKeys(gomock.Any(), "1", "3").Return(nil)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(caller)).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "PUT", "/v1/me/password", tc)
+		})
+	}
+}
+
+func TestCreatePasswordReset(t *testing.T) {
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "UserNotFound",
+			caller:        models.User{AccountID: "2", RoleID: models.AdminRole},
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrUserNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "2", "1").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "InsufficientPermissionForUserManagerToResetUserManager",
+			caller:        models.User{AccountID: "2", RoleID: models.UserManagerRole},
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "2", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
+			},
+		},
+		{
+			name:          "InsufficientPermissionForUserManagerToResetUserWithBroaderCustomRole",
+			caller:        models.User{AccountID: "2", RoleID: models.UserManagerRole},
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "2", "1").Return(&models.User{ID: "1", AccountID: "2", RoleID: 100}, nil)
+				m.EXPECT().GetRole(gomock.Any(), "2", 100).Return(&models.Role{ID: 100, AccountID: "2", Name: "dietitian",
+					Permissions: []string{policy.MealsReadAny, policy.MealsWriteAny}}, nil)
+			},
+		},
+		{
+			name:          "ErrWhenSaveToken",
+			caller:        models.User{AccountID: "2", RoleID: models.AdminRole},
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "2", "1").Return(&models.User{ID: "1", AccountID: "2"}, nil)
+				m.EXPECT().SavePasswordResetToken(gomock.Any(), gomock.Any()).Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "TokenIssuedByUserManager",
+			caller:       models.User{AccountID: "2", RoleID: models.UserManagerRole},
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "2", "1").
+					Return(&models.User{ID: "1", AccountID: "2", RoleID: models.UserRole}, nil)
+				m.EXPECT().SavePasswordResetToken(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, token models.PasswordResetToken) error {
+						if token.UserID != "1" || token.AccountID != "2" || len(token.TokenHash) != 64 ||
+							!token.ExpiresAt.After(time.Now().Add(PasswordResetTokenTTL-time.Minute)) {
+							t.Errorf("Unexpected password reset token %+v", token)
+						}
+						return nil
+					})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/users/1/password/reset", tc)
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	user := &models.User{ID: "3", AccountID: "1", Username: "user"}
+	stored := &models.PasswordResetToken{
+		TokenHash: hashToken("token"),
+		UserID:    "3",
+		AccountID: "1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	expired := *stored
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "MissingResetToken",
+			postForm:      true,
+			body:          resetForm("", "Abc123"),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingResetToken,
+		},
+		{
+			name:          "InvalidPassword",
+			postForm:      true,
+			body:          resetForm("token", "abc"),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidPasswordLength,
+		},
+		{
+			name:          "UnknownToken",
+			postForm:      true,
+			body:          resetForm("token", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidResetToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UsePasswordResetToken(gomock.Any(), hashToken("token")).Return(nil, models.ErrPasswordResetTokenNotFound)
+			},
+		},
+		{
+			name:          "ExpiredToken",
+			postForm:      true,
+			body:          resetForm("token", "Abc123"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidResetToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UsePasswordResetToken(gomock.Any(), hashToken("token")).Return(&expired, nil)
+			},
+		},
+
+		// success tests
+		{
+			name:         "PasswordReset",
+			postForm:     true,
+			body:         resetForm("token", "Abc123"),
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UsePasswordResetToken(gomock.Any(), hashToken("token")).Return(stored, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectUnitOfWork(m)
+				m.EXPECT().UpdateUserPassword(gomock.Any(), "1", "3", passwordOf("Abc123")).Return(nil)
+				m.EXPECT().RevokeUserTokenFamilies(gomock.Any(), "1", "3").Return(nil)
+				m.EXPECT().DeleteUserAPIKeys(gomock.Any(), "1", "3").Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/password/reset", tc)
+		})
+	}
+}
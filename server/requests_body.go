@@ -3,6 +3,8 @@ package server
 import (
 	"calories-counter/common"
 	"calories-counter/models"
+	"calories-counter/policy"
+	"time"
 	"unicode"
 )
 
@@ -21,6 +23,11 @@ type Validator interface {
 	Validate() error
 }
 
+// validRoleID accepts built-in roles up to maxBuiltin and IDs of custom roles, handlers check custom roles exist
+func validRoleID(roleID, maxBuiltin int) bool {
+	return roleID >= models.UserRole && roleID <= maxBuiltin || roleID >= models.CustomRoleMinID
+}
+
 func (body *UserPostBody) Validate() error {
 	if err := ValidateUsername(body.Username); err != nil {
 		return err
@@ -28,7 +35,7 @@ func (body *UserPostBody) Validate() error {
 	if err := ValidatePassword(body.Password); err != nil {
 		return err
 	}
-	if body.RoleID < models.UserRole || body.RoleID > models.AdminRole {
+	if !validRoleID(body.RoleID, models.AdminRole) {
 		return ErrInvalidRoleID
 	}
 
@@ -52,11 +59,28 @@ func ValidateUsername(username string) error {
 	return nil
 }
 
+// ValidateAccountName checks length and chars of account name, they are the same as of usernames
+func ValidateAccountName(name string) error {
+	if name == "" {
+		return ErrMissingAccountName
+	}
+	if len(name) > MaxAccountNameLength || len(name) < MinAccountNameLength {
+		return ErrInvalidAccountNameLength
+	}
+	for _, c := range name {
+		if !(unicode.IsLetter(c) || unicode.IsNumber(c) || unicode.IsPunct(c)) {
+			return ErrInvalidAccountName
+		}
+	}
+
+	return nil
+}
+
 func (body *UserPutBody) Validate() error {
 	if err := ValidateUsername(body.Username); err != nil {
 		return err
 	}
-	if body.RoleID != nil && (*body.RoleID < models.UserRole || *body.RoleID > models.AdminRole) {
+	if body.RoleID != nil && !validRoleID(*body.RoleID, models.AdminRole) {
 		return ErrInvalidRoleID
 	}
 
@@ -90,6 +114,18 @@ func ValidatePassword(password string) error {
 	return nil
 }
 
+type PasswordPutBody struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+func (body *PasswordPutBody) Validate() error {
+	if body.OldPassword == "" {
+		return ErrMissingPassword
+	}
+	return ValidatePassword(body.NewPassword)
+}
+
 type MealPostBody struct {
 	Date     *common.Date `json:"date"`
 	Time     *common.Time `json:"time"`
@@ -137,3 +173,128 @@ func (body *SettingsPutBody) Validate() error {
 	}
 	return nil
 }
+
+// TwoFactorCodeBody holds TOTP code or a recovery code
+type TwoFactorCodeBody struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorRolesBody struct {
+	RoleIDs []int `json:"role_ids"`
+}
+
+func (body *TwoFactorRolesBody) Validate() error {
+	if body.RoleIDs == nil {
+		return ErrInvalidJSON
+	}
+	for _, roleID := range body.RoleIDs {
+		if !validRoleID(roleID, models.OwnerRole) {
+			return ErrInvalidRoleID
+		}
+	}
+	return nil
+}
+
+// APIKeyPostBody creates API key, key without scopes has all permissions of the user and key without
+// ExpiresAt does not expire
+type APIKeyPostBody struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (body *APIKeyPostBody) Validate() error {
+	if body.Name == "" {
+		return ErrMissingName
+	}
+	if len(body.Name) > MaxNameLength {
+		return ErrInvalidNameLength
+	}
+	seen := make(map[string]bool)
+	for _, scope := range body.Scopes {
+		valid := false
+		for _, s := range models.Scopes {
+			valid = valid || s == scope
+		}
+		if !valid || seen[scope] {
+			return ErrInvalidScope
+		}
+		seen[scope] = true
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		return ErrInvalidExpiry
+	}
+	return nil
+}
+
+// RoleBody creates or updates custom role, see package policy for permissions
+type RoleBody struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+func (body *RoleBody) Validate() error {
+	if body.Name == "" {
+		return ErrMissingName
+	}
+	if len(body.Name) > MaxNameLength {
+		return ErrInvalidNameLength
+	}
+	seen := make(map[string]bool)
+	for _, permission := range body.Permissions {
+		if !policy.ValidPermission(permission) || seen[permission] {
+			return ErrInvalidPermission
+		}
+		seen[permission] = true
+	}
+	return nil
+}
+
+// OwnershipTransferBody offers ownership to UserID, the owner confirms it with its Password
+type OwnershipTransferBody struct {
+	UserID        string `json:"user_id"`
+	Password      string `json:"password"`
+	KeepOwnership bool   `json:"keep_ownership"`
+}
+
+func (body *OwnershipTransferBody) Validate() error {
+	if body.UserID == "" {
+		return ErrMissingUserID
+	}
+	if body.Password == "" {
+		return ErrMissingPassword
+	}
+	return nil
+}
+
+// AccountDeleteBody confirms deletion of the account with the owner's Password
+type AccountDeleteBody struct {
+	Password string `json:"password"`
+}
+
+func (body *AccountDeleteBody) Validate() error {
+	if body.Password == "" {
+		return ErrMissingPassword
+	}
+	return nil
+}
+
+// InvitationPostBody invites a user with RoleID, Name tells managers whom the invitation is for
+type InvitationPostBody struct {
+	Name   string `json:"name"`
+	RoleID int    `json:"role_id"`
+}
+
+func (body *InvitationPostBody) Validate() error {
+	if body.Name == "" {
+		return ErrMissingName
+	}
+	if len(body.Name) > MaxNameLength {
+		return ErrInvalidNameLength
+	}
+	if !validRoleID(body.RoleID, models.AdminRole) {
+		return ErrInvalidRoleID
+	}
+	return nil
+}
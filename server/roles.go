@@ -0,0 +1,221 @@
+package server
+
+import (
+	"calories-counter/models"
+	"calories-counter/policy"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// getRole returns built-in or custom role of the account
+func (s *Server) getRole(ctx context.Context, accountID string, roleID int) (*models.Role, error) {
+	if role, ok := policy.BuiltinRole(roleID); ok {
+		return &role, nil
+	}
+	return s.roles.GetRole(ctx, accountID, roleID)
+}
+
+// subject resolves role of the user, role which does not exist grants no permissions
+func (s *Server) subject(ctx context.Context, user models.User) (policy.Subject, error) {
+	role, err := s.getRole(ctx, user.AccountID, user.RoleID)
+	if err == models.ErrRoleNotFound {
+		return policy.Subject{UserID: user.ID, Role: models.Role{ID: user.RoleID}}, nil
+	} else if err != nil {
+		return policy.Subject{}, err
+	}
+	return policy.Subject{UserID: user.ID, Role: *role}, nil
+}
+
+// callerSubject resolves role of the caller once per request
+func (s *Server) callerSubject(c *gin.Context) (policy.Subject, error) {
+	if subject, ok := c.Get(callerSubjectKey); ok {
+		return subject.(policy.Subject), nil
+	}
+	subject, err := s.subject(c.Request.Context(), getCaller(c))
+	if err != nil {
+		return policy.Subject{}, err
+	}
+	c.Set(callerSubjectKey, subject)
+	return subject, nil
+}
+
+// authorize returns ErrInsufficientPermissions when the caller can't do action to target,
+// target is nil for actions which are not done to a particular user
+func (s *Server) authorize(c *gin.Context, action policy.Action, target *models.User) error {
+	actor, err := s.callerSubject(c)
+	if err != nil {
+		return err
+	}
+	var targetSubject *policy.Subject
+	if target != nil {
+		subject, err := s.subject(c.Request.Context(), *target)
+		if err != nil {
+			return err
+		}
+		if policy.RequiresTeam(actor, action) {
+			subject.ManagerIDs, err = s.teams.GetTeamManagers(c.Request.Context(), target.AccountID, target.ID)
+			if err != nil {
+				return err
+			}
+		}
+		targetSubject = &subject
+	}
+
+	if !policy.Can(actor, action, targetSubject) {
+		return ErrInsufficientPermissions
+	}
+	return nil
+}
+
+// authorizeRole returns role the caller can give to users, ErrInsufficientPermissions when it can't
+// and ErrInvalidRoleID when the role does not exist
+func (s *Server) authorizeRole(c *gin.Context, roleID int) (*models.Role, error) {
+	actor, err := s.callerSubject(c)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.getRole(c.Request.Context(), getCaller(c).AccountID, roleID)
+	if err == models.ErrRoleNotFound {
+		return nil, ErrInvalidRoleID
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !policy.CanAssign(actor.Role, *role) {
+		return nil, ErrInsufficientPermissions
+	}
+	return role, nil
+}
+
+// customRoleID parses role_id path parameter, built-in roles can't be changed
+func customRoleID(c *gin.Context) (int, error) {
+	roleID, err := strconv.Atoi(c.Param("role_id"))
+	if err != nil {
+		return 0, models.ErrRoleNotFound
+	}
+	if _, ok := policy.BuiltinRole(roleID); ok {
+		return 0, ErrBuiltinRole
+	}
+	return roleID, nil
+}
+
+// GetRoles lists built-in roles followed by custom roles of the caller's account
+func (s *Server) GetRoles(c *gin.Context) {
+	caller := getCaller(c)
+	custom, err := s.roles.GetRoles(c.Request.Context(), caller.AccountID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": append(policy.BuiltinRoles(), custom...)})
+}
+
+func (s *Server) CreateRole(c *gin.Context) {
+	caller := getCaller(c)
+	var body RoleBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	role, err := s.roles.SaveRole(c.Request.Context(), models.Role{
+		AccountID:   caller.AccountID,
+		Name:        body.Name,
+		Permissions: append([]string{}, body.Permissions...),
+	})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole replaces name and permissions of custom role, users of the role get them with their next request
+func (s *Server) UpdateRole(c *gin.Context) {
+	caller := getCaller(c)
+	roleID, err := customRoleID(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	var body RoleBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	role, err := s.roles.UpdateRole(c.Request.Context(), models.Role{
+		ID:          roleID,
+		AccountID:   caller.AccountID,
+		Name:        body.Name,
+		Permissions: append([]string{}, body.Permissions...),
+	})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole deletes custom role which is not assigned to any user, its pending invitations are revoked
+// and the role stops requiring two-factor authentication so a new role which gets its ID inherits neither
+func (s *Server) DeleteRole(c *gin.Context) {
+	caller := getCaller(c)
+	roleID, err := customRoleID(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	err = s.unitOfWork.Do(c.Request.Context(), func(ctx context.Context, repos models.Repositories) error {
+		count, err := repos.Users.CountUsersWithRole(ctx, caller.AccountID, roleID)
+		if err != nil {
+			return err
+		} else if count > 0 {
+			return ErrRoleInUse
+		}
+
+		err = repos.Roles.DeleteRole(ctx, caller.AccountID, roleID)
+		if err != nil {
+			return err
+		}
+		err = repos.Invitations.DeleteRoleInvitations(ctx, caller.AccountID, roleID)
+		if err != nil {
+			return err
+		}
+
+		roleIDs, err := repos.TwoFactor.GetTwoFactorRoles(ctx, caller.AccountID)
+		if err != nil {
+			return err
+		}
+		remaining := make([]int, 0, len(roleIDs))
+		for _, id := range roleIDs {
+			if id != roleID {
+				remaining = append(remaining, id)
+			}
+		}
+		if len(remaining) == len(roleIDs) {
+			return nil
+		}
+		return repos.TwoFactor.SetTwoFactorRoles(ctx, caller.AccountID, remaining)
+	})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
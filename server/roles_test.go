@@ -0,0 +1,239 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/models"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"testing"
+)
+
+func TestGetRoles(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", RoleID: models.OwnerRole}
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "ErrWhenGetRoles",
+			caller:        caller,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRoles(gomock.Any(), "1").Return(nil, errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "BuiltinAndCustomRoles",
+			caller:       caller,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":[` +
+				`{"id":0,"name":"user","permissions":["meals:read:own","meals:write:own"]},` +
+				`{"id":1,"name":"user manager","permissions":["meals:read:team","users:read","users:write:standard"]},` +
+				`{"id":2,"name":"admin","permissions":["meals:read:any","meals:write:any","users:read","users:write:any"]},` +
+				`{"id":3,"name":"owner","permissions":["users:read","users:write:any","account:manage"]},` +
+				`{"id":100,"name":"dietitian","permissions":["meals:read:any"]}]}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRoles(gomock.Any(), "1").Return([]models.Role{
+					{ID: 100, AccountID: "1", Name: "dietitian", Permissions: []string{"meals:read:any"}},
+				}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "GET", "/v1/roles", tc)
+		})
+	}
+}
+
+func TestCreateRole(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", RoleID: models.OwnerRole}
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "InvalidBody",
+			caller:        caller,
+			body:          `{`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidJSON,
+		},
+		{
+			name:          "MissingName",
+			caller:        caller,
+			body:          `{"permissions": ["meals:read:any"]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingName,
+		},
+		{
+			name:          "InvalidPermission",
+			caller:        caller,
+			body:          `{"name": "dietitian", "permissions": ["meals:delete"]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidPermission,
+		},
+		{
+			name:          "AccountPermissionNotGranted",
+			caller:        caller,
+			body:          `{"name": "co-owner", "permissions": ["account:manage"]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidPermission,
+		},
+		{
+			name:          "RoleAlreadyExists",
+			caller:        caller,
+			body:          `{"name": "dietitian", "permissions": ["meals:read:any"]}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: models.ErrRoleAlreadyExists,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveRole(gomock.Any(), models.Role{AccountID: "1", Name: "dietitian",
+					Permissions: []string{"meals:read:any"}}).Return(nil, models.ErrRoleAlreadyExists)
+			},
+		},
+
+		// success tests
+		{
+			name:         "RoleCreated",
+			caller:       caller,
+			body:         `{"name": "dietitian", "permissions": ["meals:read:any"]}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":100,"name":"dietitian","permissions":["meals:read:any"]}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveRole(gomock.Any(), models.Role{AccountID: "1", Name: "dietitian",
+					Permissions: []string{"meals:read:any"}}).
+					Return(&models.Role{ID: 100, AccountID: "1", Name: "dietitian", Permissions: []string{"meals:read:any"}}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/roles", tc)
+		})
+	}
+}
+
+func TestUpdateRole(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", RoleID: models.OwnerRole}
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "RoleNotFound",
+			caller:        caller,
+			body:          `{"name": "auditor", "permissions": ["users:read"]}`,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrRoleNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().UpdateRole(gomock.Any(), models.Role{ID: 100, AccountID: "1", Name: "auditor",
+					Permissions: []string{"users:read"}}).Return(nil, models.ErrRoleNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "RoleUpdated",
+			caller:       caller,
+			body:         `{"name": "auditor", "permissions": ["users:read"]}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":100,"name":"auditor","permissions":["users:read"]}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				role := models.Role{ID: 100, AccountID: "1", Name: "auditor", Permissions: []string{"users:read"}}
+				m.EXPECT().UpdateRole(gomock.Any(), role).Return(&role, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "PUT", "/v1/roles/100", tc)
+		})
+	}
+
+	t.Run("BuiltinRole", func(t *testing.T) {
+		runTest(t, "PUT", "/v1/roles/2", testCase{
+			caller:        caller,
+			body:          `{"name": "admin", "permissions": []}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrBuiltinRole,
+		})
+	})
+	t.Run("InvalidRoleID", func(t *testing.T) {
+		runTest(t, "PUT", "/v1/roles/auditor", testCase{
+			caller:        caller,
+			body:          `{"name": "auditor", "permissions": []}`,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrRoleNotFound,
+		})
+	})
+}
+
+func TestDeleteRole(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", RoleID: models.OwnerRole}
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "RoleInUse",
+			caller:        caller,
+			expectedCode:  http.StatusConflict,
+			expectedError: ErrRoleInUse,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				m.EXPECT().CountUsersWithRole(gomock.Any(), "1", 100).Return(1, nil)
+			},
+		},
+		{
+			name:          "RoleNotFound",
+			caller:        caller,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrRoleNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				m.EXPECT().CountUsersWithRole(gomock.Any(), "1", 100).Return(0, nil)
+				m.EXPECT().DeleteRole(gomock.Any(), "1", 100).Return(models.ErrRoleNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "RoleDeleted",
+			caller:       caller,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				m.EXPECT().CountUsersWithRole(gomock.Any(), "1", 100).Return(0, nil)
+				m.EXPECT().DeleteRole(gomock.Any(), "1", 100).Return(nil)
+				m.EXPECT().DeleteRoleInvitations(gomock.Any(), "1", 100).Return(nil)
+				m.EXPECT().GetTwoFactorRoles(gomock.Any(), "1").Return([]int{models.OwnerRole}, nil)
+			},
+		},
+		{
+			name:         "RoleDeletedWithTwoFactorRequirement",
+			caller:       caller,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				m.EXPECT().CountUsersWithRole(gomock.Any(), "1", 100).Return(0, nil)
+				m.EXPECT().DeleteRole(gomock.Any(), "1", 100).Return(nil)
+				m.EXPECT().DeleteRoleInvitations(gomock.Any(), "1", 100).Return(nil)
+				m.EXPECT().GetTwoFactorRoles(gomock.Any(), "1").Return([]int{models.OwnerRole, 100}, nil)
+				m.EXPECT().SetTwoFactorRoles(gomock.Any(), "1", []int{models.OwnerRole}).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "DELETE", "/v1/roles/100", tc)
+		})
+	}
+
+	t.Run("BuiltinRole", func(t *testing.T) {
+		runTest(t, "DELETE", "/v1/roles/0", testCase{
+			caller:        caller,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrBuiltinRole,
+		})
+	})
+}
@@ -2,61 +2,136 @@ package server
 
 import (
 	"calories-counter/models"
+	"calories-counter/policy"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(r *gin.Engine, secretKey string, userDatastore models.UserDatastore, caloriesDatastore models.CaloriesDatastore) {
-	r.Use(SetVars(map[string]interface{}{
-		"userDatastore":     userDatastore,
-		"caloriesDatastore": caloriesDatastore,
-		"secretKey":         secretKey,
-	}))
-	r.POST("/v1/signup", SignUp)
-	r.POST("/v1/account/:account_id/signin", SignIn)
+// SetupRouter registers all routes of the server
+func (s *Server) SetupRouter(r *gin.Engine) {
+	r.Use(RequestTimeout(s.requestTimeout))
+	r.GET("/.well-known/jwks.json", s.JWKS)
+	r.POST("/v1/signup", s.SignUp)
+	r.POST("/v1/account/:account_id/signin", s.SignIn)
+	r.POST("/v1/signin", s.SignInByAccountName)
+	r.POST("/v1/signin/2fa", s.SignInTwoFactor)
+	r.POST("/v1/signin/2fa/enroll", s.SignInTwoFactorEnroll)
+	r.POST("/v1/token/refresh", s.RefreshToken)
+	r.POST("/v1/password/reset", s.ResetPassword)
+	r.POST("/v1/signup/invitation", s.AcceptInvitation)
+	// restore is not under /account, it would conflict with sign in by account ID
+	r.POST("/v1/restore", s.RestoreAccount)
 
 	authorized := r.Group("/v1")
-	authorized.Use(AuthVerify())
+	authorized.Use(s.AuthVerify())
 	{
+		authorized.POST("/signout", SessionVerify(), s.SignOut)
+		authorized.POST("/signout/all", SessionVerify(), s.SignOutAll)
+		authorized.GET("/account", s.GetAccount)
+
+		me := authorized.Group("/me")
+		me.Use(SessionVerify())
+		{
+			me.PUT("/password", s.ChangePassword)
+			me.POST("/2fa", s.EnrollTOTP)
+			me.POST("/2fa/confirm", s.ConfirmTOTP)
+			me.DELETE("/2fa", s.DisableTOTP)
+			me.POST("/api-keys", s.CreateAPIKey)
+			me.GET("/api-keys", s.GetAPIKeys)
+			me.DELETE("/api-keys/:key_id", s.DeleteAPIKey)
+		}
+
+		account := authorized.Group("/account")
+		account.Use(SessionVerify(), s.PermissionVerify(policy.ManageAccount, policy.ManageAccount))
+		{
+			account.DELETE("", s.DeleteAccount)
+			account.GET("/2fa", s.GetTwoFactorRoles)
+			account.PUT("/2fa", s.UpdateTwoFactorRoles)
+		}
+
+		// roles are not under /account, POST would conflict with sign in by account ID
+		roles := authorized.Group("/roles")
+		roles.Use(SessionVerify(), s.PermissionVerify(policy.ReadUsers, policy.ManageAccount))
+		{
+			roles.GET("/", s.GetRoles)
+			roles.POST("/", s.CreateRole)
+			roles.PUT("/:role_id", s.UpdateRole)
+			roles.DELETE("/:role_id", s.DeleteRole)
+		}
+
+		// the offered user accepts ownership without account:manage
+		authorized.POST("/owners/transfer/accept", SessionVerify(), s.AcceptOwnershipTransfer)
+		owners := authorized.Group("/owners")
+		owners.Use(SessionVerify(), s.PermissionVerify(policy.ManageAccount, policy.ManageAccount))
+		{
+			owners.POST("/transfer", s.CreateOwnershipTransfer)
+			owners.GET("/transfer", s.GetOwnershipTransfer)
+			owners.DELETE("/transfer", s.CancelOwnershipTransfer)
+		}
+
 		users := authorized.Group("/users")
-		users.Use(RoleAccessVerify(models.AdminRole, models.UserManagerRole, models.OwnerRole))
+		users.Use(s.PermissionVerify(policy.ReadUsers, policy.WriteUser),
+			ScopeVerify(models.ScopeUsersManage, models.ScopeUsersManage))
+		{
+			users.POST("/", s.CreateUser)
+			users.GET("/", s.GetUsers)
+			users.GET("/:user_id", s.GetUser)
+			users.PUT("/:user_id", s.UpdateUser)
+			users.DELETE("/:user_id", s.DeleteUser)
+			users.POST("/:user_id/unlock", s.UnlockUser)
+			users.POST("/:user_id/password/reset", s.CreatePasswordReset)
+			users.GET("/:user_id/team", s.GetTeam)
+			users.PUT("/:user_id/team/:member_id", s.AddTeamMember)
+			users.DELETE("/:user_id/team/:member_id", s.RemoveTeamMember)
+		}
+
+		invitations := authorized.Group("/invitations")
+		invitations.Use(s.PermissionVerify(policy.ReadUsers, policy.WriteUser),
+			ScopeVerify(models.ScopeUsersManage, models.ScopeUsersManage))
 		{
-			users.POST("/", CreateUser)
-			users.GET("/", GetUsers)
-			users.GET("/:user_id", GetUser)
-			users.PUT("/:user_id", UpdateUser)
-			users.DELETE("/:user_id", DeleteUser)
+			invitations.POST("/", s.CreateInvitation)
+			invitations.GET("/", s.GetInvitations)
+			invitations.POST("/:invitation_id/resend", s.ResendInvitation)
+			invitations.DELETE("/:invitation_id", s.RevokeInvitation)
 		}
 
 		meals := authorized.Group("/meals")
-		meals.Use(RoleAccessVerify(models.UserRole))
+		meals.Use(s.PermissionVerify(policy.ReadMeals, policy.WriteMeals),
+			ScopeVerify(models.ScopeMealsRead, models.ScopeMealsWrite))
 		{
-			meals.POST("/", CreateMeal)
-			meals.GET("/", GetMeals)
-			meals.GET("/:meal_id", GetMeal)
-			meals.PUT("/:meal_id", UpdateMeal)
-			meals.DELETE("/:meal_id", DeleteMeal)
+			meals.POST("/", s.CreateMeal)
+			meals.GET("/", s.GetMeals)
+			meals.GET("/:meal_id", s.GetMeal)
+			meals.PUT("/:meal_id", s.UpdateMeal)
+			meals.DELETE("/:meal_id", s.DeleteMeal)
 		}
 		settings := authorized.Group("/settings")
-		settings.Use(RoleAccessVerify(models.UserRole))
+		settings.Use(s.PermissionVerify(policy.ReadMeals, policy.WriteMeals),
+			ScopeVerify(models.ScopeMealsRead, models.ScopeMealsWrite))
 		{
-			settings.PUT("/", UpdateSettings)
-			settings.GET("/", GetSettings)
+			settings.PUT("/", s.UpdateSettings)
+			settings.GET("/", s.GetSettings)
 		}
+		authorized.GET("/summary", s.PermissionVerify(policy.ReadMeals, policy.WriteMeals),
+			ScopeVerify(models.ScopeMealsRead, models.ScopeMealsWrite), s.GetSummary)
 
 		adminMeals := authorized.Group("/users/:user_id/meals")
-		adminMeals.Use(RoleAccessVerify(models.AdminRole), UserVerify())
+		adminMeals.Use(ScopeVerify(models.ScopeMealsRead, models.ScopeMealsWrite),
+			s.UserVerify(policy.ReadMeals, policy.WriteMeals))
 		{
-			adminMeals.POST("/", CreateMeal)
-			adminMeals.GET("/", GetMeals)
-			adminMeals.GET("/:meal_id", GetMeal)
-			adminMeals.PUT("/:meal_id", UpdateMeal)
-			adminMeals.DELETE("/:meal_id", DeleteMeal)
+			adminMeals.POST("/", s.CreateMeal)
+			adminMeals.GET("/", s.GetMeals)
+			adminMeals.GET("/:meal_id", s.GetMeal)
+			adminMeals.PUT("/:meal_id", s.UpdateMeal)
+			adminMeals.DELETE("/:meal_id", s.DeleteMeal)
 		}
 		adminSettings := authorized.Group("/users/:user_id/settings")
-		adminSettings.Use(RoleAccessVerify(models.AdminRole), UserVerify())
+		adminSettings.Use(ScopeVerify(models.ScopeMealsRead, models.ScopeMealsWrite),
+			s.UserVerify(policy.ReadMeals, policy.WriteMeals))
 		{
-			adminSettings.PUT("/", UpdateSettings)
-			adminSettings.GET("/", GetSettings)
+			adminSettings.PUT("/", s.UpdateSettings)
+			adminSettings.GET("/", s.GetSettings)
 		}
+		authorized.GET("/users/:user_id/summary", ScopeVerify(models.ScopeMealsRead, models.ScopeMealsWrite),
+			s.UserVerify(policy.ReadMeals, policy.WriteMeals), s.GetSummary)
 	}
 }
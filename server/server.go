@@ -0,0 +1,124 @@
+package server
+
+import (
+	"calories-counter/models"
+	"calories-counter/signing"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMissingSigningKeys    = errors.New("server config: missing signing keys")
+	ErrMissingDatastore      = errors.New("server config: missing datastore")
+	ErrInvalidRequestTimeout = errors.New("server config: request timeout can not be negative")
+	ErrInvalidTokenTTL       = errors.New("server config: token TTL can not be negative")
+)
+
+// Config holds dependencies of the Server, all fields except RequestTimeout and token TTLs are required
+type Config struct {
+	// Keys sign access tokens
+	Keys *signing.KeySet
+	// RequestTimeout limits time of every request, 0 disables the limit
+	RequestTimeout time.Duration
+	// AccessTokenTTL and RefreshTokenTTL default to DefaultAccessTokenTTL and DefaultRefreshTokenTTL when 0
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// UnitOfWork composes writes of the repositories into one transaction, e.g. a meal and its daily total
+	UnitOfWork         models.UnitOfWork
+	Credentials        models.CredentialStore
+	Accounts           models.AccountRepository
+	RefreshTokens      models.RefreshTokenRepository
+	Revocations        models.TokenRevocationStore
+	TwoFactor          models.TwoFactorRepository
+	LoginThrottle      models.LoginThrottleRepository
+	PasswordResets     models.PasswordResetRepository
+	APIKeys            models.APIKeyRepository
+	Roles              models.RoleRepository
+	Teams              models.TeamRepository
+	OwnershipTransfers models.OwnershipTransferRepository
+	Invitations        models.InvitationRepository
+	Users              models.UserRepository
+	Meals              models.MealRepository
+	Settings           models.SettingsRepository
+	DailyTotals        models.DailyTotalRepository
+	Calories           models.CaloriesDatastore
+}
+
+// Server exposes the REST api, handlers are its methods
+type Server struct {
+	keys            *signing.KeySet
+	requestTimeout  time.Duration
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+
+	unitOfWork         models.UnitOfWork
+	credentials        models.CredentialStore
+	accounts           models.AccountRepository
+	refreshTokens      models.RefreshTokenRepository
+	revocations        models.TokenRevocationStore
+	twoFactor          models.TwoFactorRepository
+	loginThrottle      models.LoginThrottleRepository
+	passwordResets     models.PasswordResetRepository
+	apiKeys            models.APIKeyRepository
+	roles              models.RoleRepository
+	teams              models.TeamRepository
+	ownershipTransfers models.OwnershipTransferRepository
+	invitations        models.InvitationRepository
+	users              models.UserRepository
+	meals              models.MealRepository
+	settings           models.SettingsRepository
+	dailyTotals        models.DailyTotalRepository
+	calories           models.CaloriesDatastore
+}
+
+// New validates config and creates the Server
+func New(cfg Config) (*Server, error) {
+	if cfg.Keys == nil {
+		return nil, ErrMissingSigningKeys
+	}
+	if cfg.RequestTimeout < 0 {
+		return nil, ErrInvalidRequestTimeout
+	}
+	if cfg.AccessTokenTTL < 0 || cfg.RefreshTokenTTL < 0 {
+		return nil, ErrInvalidTokenTTL
+	}
+	if cfg.AccessTokenTTL == 0 {
+		cfg.AccessTokenTTL = DefaultAccessTokenTTL
+	}
+	if cfg.RefreshTokenTTL == 0 {
+		cfg.RefreshTokenTTL = DefaultRefreshTokenTTL
+	}
+	if cfg.UnitOfWork == nil || cfg.Credentials == nil || cfg.Accounts == nil || cfg.RefreshTokens == nil ||
+		cfg.Revocations == nil || cfg.TwoFactor == nil || cfg.LoginThrottle == nil || cfg.PasswordResets == nil || cfg.APIKeys == nil ||
+		cfg.Roles == nil || cfg.Teams == nil || cfg.OwnershipTransfers == nil || cfg.Invitations == nil ||
+		cfg.Users == nil || cfg.Meals == nil || cfg.Settings == nil || cfg.DailyTotals == nil ||
+		cfg.Calories == nil {
+		return nil, ErrMissingDatastore
+	}
+
+	return &Server{
+		keys:               cfg.Keys,
+		requestTimeout:     cfg.RequestTimeout,
+		accessTokenTTL:     cfg.AccessTokenTTL,
+		refreshTokenTTL:    cfg.RefreshTokenTTL,
+		unitOfWork:         cfg.UnitOfWork,
+		credentials:        cfg.Credentials,
+		accounts:           cfg.Accounts,
+		refreshTokens:      cfg.RefreshTokens,
+		revocations:        cfg.Revocations,
+		twoFactor:          cfg.TwoFactor,
+		loginThrottle:      cfg.LoginThrottle,
+		passwordResets:     cfg.PasswordResets,
+		apiKeys:            cfg.APIKeys,
+		roles:              cfg.Roles,
+		teams:              cfg.Teams,
+		ownershipTransfers: cfg.OwnershipTransfers,
+		invitations:        cfg.Invitations,
+		users:              cfg.Users,
+		meals:              cfg.Meals,
+		settings:           cfg.Settings,
+		dailyTotals:        cfg.DailyTotals,
+		calories:           cfg.Calories,
+	}, nil
+}
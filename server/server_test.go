@@ -0,0 +1,104 @@
+package server
+
+import (
+	"calories-counter/adapters/calories_datastore"
+	"calories-counter/adapters/user_datastore"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	userDatastore := user_datastore.NewMemoryStore()
+	caloriesDatastore := &calories_datastore.MockCaloriesDatastore{}
+	validConfig := func() Config {
+		return Config{
+			Keys:               testKeys,
+			UnitOfWork:         userDatastore,
+			Credentials:        userDatastore,
+			Accounts:           userDatastore,
+			RefreshTokens:      userDatastore,
+			Revocations:        userDatastore,
+			TwoFactor:          userDatastore,
+			LoginThrottle:      userDatastore,
+			PasswordResets:     userDatastore,
+			APIKeys:            userDatastore,
+			Roles:              userDatastore,
+			Teams:              userDatastore,
+			OwnershipTransfers: userDatastore,
+			Invitations:        userDatastore,
+			Users:              userDatastore,
+			Meals:              userDatastore,
+			Settings:           userDatastore,
+			DailyTotals:        userDatastore,
+			Calories:           caloriesDatastore,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		modify        func(cfg *Config)
+		expectedError error
+	}{
+		// error tests
+		{
+			name:          "MissingSigningKeys",
+			modify:        func(cfg *Config) { cfg.Keys = nil },
+			expectedError: ErrMissingSigningKeys,
+		},
+		{
+			name:          "NegativeRequestTimeout",
+			modify:        func(cfg *Config) { cfg.RequestTimeout = -time.Second },
+			expectedError: ErrInvalidRequestTimeout,
+		},
+		{
+			name:          "NegativeAccessTokenTTL",
+			modify:        func(cfg *Config) { cfg.AccessTokenTTL = -time.Minute },
+			expectedError: ErrInvalidTokenTTL,
+		},
+		{
+			name:          "MissingRefreshTokenRepository",
+			modify:        func(cfg *Config) { cfg.RefreshTokens = nil },
+			expectedError: ErrMissingDatastore,
+		},
+		{
+			name:          "MissingUnitOfWork",
+			modify:        func(cfg *Config) { cfg.UnitOfWork = nil },
+			expectedError: ErrMissingDatastore,
+		},
+		{
+			name:          "MissingMealRepository",
+			modify:        func(cfg *Config) { cfg.Meals = nil },
+			expectedError: ErrMissingDatastore,
+		},
+		{
+			name:          "MissingDailyTotalRepository",
+			modify:        func(cfg *Config) { cfg.DailyTotals = nil },
+			expectedError: ErrMissingDatastore,
+		},
+		{
+			name:          "MissingCaloriesDatastore",
+			modify:        func(cfg *Config) { cfg.Calories = nil },
+			expectedError: ErrMissingDatastore,
+		},
+
+		// success tests
+		{
+			name:   "ValidConfig",
+			modify: func(cfg *Config) { cfg.RequestTimeout = time.Second },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.modify(&cfg)
+			s, err := New(cfg)
+			if err != tc.expectedError {
+				t.Errorf("Expected error `%v` but was `%v`", tc.expectedError, err)
+			}
+			if err == nil && s == nil {
+				t.Errorf("Expected server to be created")
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"calories-counter/models"
+	"calories-counter/policy"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// teamManager returns user of user_id whose team the caller manages, managers manage their own teams
+// and users who can update the manager manage its team too
+func (s *Server) teamManager(c *gin.Context) (*models.User, error) {
+	caller := getCaller(c)
+	manager, err := s.users.GetUserById(c.Request.Context(), caller.AccountID, c.Param("user_id"))
+	if err != nil {
+		return nil, err
+	}
+	if manager.ID != caller.ID {
+		if err := s.authorize(c, policy.WriteUser, manager); err != nil {
+			return nil, err
+		}
+	}
+	return manager, nil
+}
+
+// addToCreatorTeam assigns user with role to team of the manager who created or invited it
+// when the manager reads meals of its team and the role is standard
+func addToCreatorTeam(ctx context.Context, teams models.TeamRepository, creator policy.Subject, user models.User,
+	role models.Role) error {
+	if !policy.RequiresTeam(creator, policy.ReadMeals) || !policy.Standard(role) {
+		return nil
+	}
+	return teams.AddTeamMember(ctx, user.AccountID, creator.UserID, user.ID)
+}
+
+// GetTeam lists users assigned to team of the user
+func (s *Server) GetTeam(c *gin.Context) {
+	caller := getCaller(c)
+	members, err := s.teams.GetTeamMembers(c.Request.Context(), caller.AccountID, c.Param("user_id"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": members})
+}
+
+// authorizeTeamMember returns ErrTeamMemberAssigned when the caller can't take member over from teams
+// of other managers, only users who manage all users move members between teams
+func (s *Server) authorizeTeamMember(c *gin.Context, member models.User, managerID string) error {
+	actor, err := s.callerSubject(c)
+	if err != nil {
+		return err
+	}
+	if policy.Has(actor.Role, policy.UsersWriteAny) {
+		return nil
+	}
+	managerIDs, err := s.teams.GetTeamManagers(c.Request.Context(), member.AccountID, member.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range managerIDs {
+		if id != managerID {
+			return ErrTeamMemberAssigned
+		}
+	}
+	return nil
+}
+
+// AddTeamMember assigns standard user to team of the manager, the manager can read meals and settings of the member.
+// Users managing only standard users add only users who are not in a team of another manager.
+func (s *Server) AddTeamMember(c *gin.Context) {
+	manager, err := s.teamManager(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	member, err := s.users.GetUserById(c.Request.Context(), manager.AccountID, c.Param("member_id"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if err := s.authorize(c, policy.WriteUser, member); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	subject, err := s.subject(c.Request.Context(), *member)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if member.ID == manager.ID || !policy.Standard(subject.Role) {
+		handleErrorResponse(c, ErrInvalidTeamMember)
+		return
+	}
+	if err := s.authorizeTeamMember(c, *member, manager.ID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	err = s.teams.AddTeamMember(c.Request.Context(), manager.AccountID, manager.ID, member.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) RemoveTeamMember(c *gin.Context) {
+	manager, err := s.teamManager(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	err = s.teams.RemoveTeamMember(c.Request.Context(), manager.AccountID, manager.ID, c.Param("member_id"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/models"
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"testing"
+)
+
+func TestGetTeam(t *testing.T) {
+	caller := models.User{ID: "4", AccountID: "1", RoleID: models.UserManagerRole}
+	members := []models.User{{ID: "1", AccountID: "1", Username: "member", RoleID: models.UserRole}}
+	jsonMembers, _ := json.Marshal(members)
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "ErrWhenGetTeamMembers",
+			caller:        caller,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTeamMembers(gomock.Any(), "1", "4").Return(nil, errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "TeamListed",
+			caller:       caller,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":` + string(jsonMembers) + `}`,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTeamMembers(gomock.Any(), "1", "4").Return(members, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "GET", "/v1/users/4/team", tc)
+		})
+	}
+}
+
+func TestAddTeamMember(t *testing.T) {
+	manager := models.User{ID: "4", AccountID: "1", RoleID: models.UserManagerRole}
+	otherManager := models.User{ID: "5", AccountID: "1", RoleID: models.UserManagerRole}
+	admin := models.User{ID: "6", AccountID: "1", RoleID: models.AdminRole}
+	member := models.User{ID: "1", AccountID: "1", RoleID: models.UserRole}
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "ManagerNotFound",
+			caller:        admin,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrUserNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "OtherManagerAddsToTeam",
+			caller:        otherManager,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+			},
+		},
+		{
+			name:          "MemberNotFound",
+			caller:        manager,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrUserNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "ManagerAddsAdmin",
+			caller:        manager,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&admin, nil)
+			},
+		},
+		{
+			name:          "AdminAddsManager",
+			caller:        admin,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidTeamMember,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&otherManager, nil)
+			},
+		},
+		{
+			name:          "CustomRoleNotFound",
+			caller:        manager,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				dietitian := models.User{ID: "1", AccountID: "1", RoleID: 100}
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&dietitian, nil)
+				m.EXPECT().GetRole(gomock.Any(), "1", 100).Return(nil, errors.New("err"))
+			},
+		},
+		{
+			name:          "ManagerAddsMemberOfOtherTeam",
+			caller:        manager,
+			expectedCode:  http.StatusConflict,
+			expectedError: ErrTeamMemberAssigned,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&member, nil)
+				m.EXPECT().GetTeamManagers(gomock.Any(), "1", "1").Return([]string{"5"}, nil)
+			},
+		},
+		{
+			name:          "ErrWhenGetTeamManagers",
+			caller:        manager,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&member, nil)
+				m.EXPECT().GetTeamManagers(gomock.Any(), "1", "1").Return(nil, errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "ManagerAddsUser",
+			caller:       manager,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&member, nil)
+				m.EXPECT().GetTeamManagers(gomock.Any(), "1", "1").Return(nil, nil)
+				m.EXPECT().AddTeamMember(gomock.Any(), "1", "4", "1").Return(nil)
+			},
+		},
+		{
+			name:         "ManagerAddsOwnMemberAgain",
+			caller:       manager,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&member, nil)
+				m.EXPECT().GetTeamManagers(gomock.Any(), "1", "1").Return([]string{"4"}, nil)
+				m.EXPECT().AddTeamMember(gomock.Any(), "1", "4", "1").Return(nil)
+			},
+		},
+		{
+			name:         "AdminAddsUserToTeamOfManager",
+			caller:       admin,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "1", "1").Return(&member, nil)
+				m.EXPECT().AddTeamMember(gomock.Any(), "1", "4", "1").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "PUT", "/v1/users/4/team/1", tc)
+		})
+	}
+}
+
+func TestRemoveTeamMember(t *testing.T) {
+	manager := models.User{ID: "4", AccountID: "1", RoleID: models.UserManagerRole}
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "MemberNotFound",
+			caller:        manager,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrTeamMemberNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().RemoveTeamMember(gomock.Any(), "1", "4", "1").Return(models.ErrTeamMemberNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "MemberRemoved",
+			caller:       manager,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "4").Return(&manager, nil)
+				m.EXPECT().RemoveTeamMember(gomock.Any(), "1", "4", "1").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "DELETE", "/v1/users/4/team/1", tc)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"calories-counter/models"
+	"calories-counter/policy"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"time"
+)
+
+const (
+	// FreeSignInAttempts is the number of failed sign ins allowed before backoff starts
+	FreeSignInAttempts = 3
+	// LockoutThreshold is the number of failed sign ins which lock the user for LockoutDuration
+	LockoutThreshold = 10
+	LockoutDuration  = 15 * time.Minute
+
+	// backoff doubles with every failed sign in after the free ones
+	signInBackoffBase = time.Second
+	signInBackoffMax  = 5 * time.Minute
+	// failures older than signInFailureWindow are forgotten
+	signInFailureWindow = time.Hour
+)
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// signInBackoff returns time the client has to wait after the last of failures
+func signInBackoff(failures int) time.Duration {
+	exp := failures - FreeSignInAttempts
+	if exp < 0 {
+		return 0
+	}
+	if exp >= 20 {
+		return signInBackoffMax
+	}
+	backoff := signInBackoffBase << uint(exp)
+	if backoff > signInBackoffMax {
+		return signInBackoffMax
+	}
+	return backoff
+}
+
+// checkSignInThrottle refuses sign in of a locked user or during backoff of the user or the client IP,
+// Retry-After header is set when it does
+func (s *Server) checkSignInThrottle(c *gin.Context, userKey string) error {
+	now := time.Now()
+	for _, key := range []string{userKey, ipThrottleKey(c.ClientIP())} {
+		failures, err := s.loginThrottle.GetLoginFailures(c.Request.Context(), key)
+		if err != nil {
+			return err
+		}
+		if failures.Failures == 0 || now.Sub(failures.LastFailure) >= signInFailureWindow {
+			continue
+		}
+
+		// only users are locked, clients behind a shared IP just slow down
+		if key == userKey && failures.Failures >= LockoutThreshold {
+			if until := failures.LastFailure.Add(LockoutDuration); now.Before(until) {
+				setRetryAfter(c, until.Sub(now))
+				return ErrUserLocked
+			}
+			continue
+		}
+		if until := failures.LastFailure.Add(signInBackoff(failures.Failures)); now.Before(until) {
+			setRetryAfter(c, until.Sub(now))
+			return ErrTooManySignInAttempts
+		}
+	}
+	return nil
+}
+
+func setRetryAfter(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+}
+
+// recordSignInFailure counts failed sign in of the user and the client IP
+func (s *Server) recordSignInFailure(c *gin.Context, userKey string) {
+	now := time.Now()
+	for _, key := range []string{userKey, ipThrottleKey(c.ClientIP())} {
+		err := s.loginThrottle.AddLoginFailure(c.Request.Context(), key, now, now.Add(-signInFailureWindow))
+		if err != nil {
+			log.WithError(err).WithField("key", key).Warn("couldn't record failed sign in")
+		}
+	}
+}
+
+// resetSignInFailures forgets failures of the user after successful sign in,
+// failures of the client IP expire on their own so one valid account can't reset them
+func (s *Server) resetSignInFailures(c *gin.Context, userKey string) {
+	err := s.loginThrottle.ResetLoginFailures(c.Request.Context(), userKey)
+	if err != nil {
+		log.WithError(err).WithField("key", userKey).Warn("couldn't reset failed sign ins")
+	}
+}
+
+// UnlockUser forgets failed sign ins of the user, callers who manage only standard users can unlock only them
+func (s *Server) UnlockUser(c *gin.Context) {
+	caller := getCaller(c)
+
+	user, err := s.users.GetUserById(c.Request.Context(), caller.AccountID, c.Param("user_id"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if err := s.authorize(c, policy.WriteUser, user); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	err = s.loginThrottle.ResetLoginFailures(c.Request.Context(), models.UserThrottleKey(user.AccountID, user.Username))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/models"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignInBackoff(t *testing.T) {
+	testCases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{FreeSignInAttempts - 1, 0},
+		{FreeSignInAttempts, time.Second},
+		{FreeSignInAttempts + 3, 8 * time.Second},
+		{FreeSignInAttempts + 20, signInBackoffMax},
+		{1000, signInBackoffMax},
+	}
+
+	for _, tc := range testCases {
+		if backoff := signInBackoff(tc.failures); backoff != tc.expected {
+			t.Errorf("Expected backoff %v after %d failures but was %v", tc.expected, tc.failures, backoff)
+		}
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	testCases := []testCase{
+		// error tests
+		{
+			name:          "UserNotFound",
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrUserNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "InsufficientPermissionForUserManagerToUnlockUserManager",
+			caller:        models.User{RoleID: models.UserManagerRole},
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
+			},
+		},
+		{
+			name:          "ErrWhenResetLoginFailures",
+			caller:        models.User{RoleID: models.AdminRole},
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{ID: "1", AccountID: "2", Username: "user"}, nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:2:user").Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "UserUnlockedByUserManager",
+			caller:       models.User{RoleID: models.UserManagerRole},
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").
+					Return(&models.User{ID: "1", AccountID: "2", Username: "user", RoleID: models.UserRole}, nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:2:user").Return(nil)
+			},
+		},
+		{
+			name:         "UserManagerUnlockedByAdmin",
+			caller:       models.User{RoleID: models.AdminRole},
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").
+					Return(&models.User{ID: "1", AccountID: "2", Username: "manager", RoleID: models.UserManagerRole}, nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:2:manager").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/users/1/unlock", tc)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"calories-counter/common"
+	"calories-counter/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// TokenResponse is returned by sign in and token refresh, ExpiresIn is lifetime of Token in seconds
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// hashToken returns hash under which random tokens and codes are stored,
+// they are long enough to not need a slow hash
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns 256 bits of randomness encoded for use in URLs and forms
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *Server) accessToken(user *models.User, familyID string) (string, error) {
+	now := time.Now()
+	claims := common.JWTClaims{
+		UUID:      user.ID,
+		AccountID: user.AccountID,
+		FamilyID:  familyID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.accessTokenTTL).Unix(),
+		},
+	}
+	return s.keys.Sign(claims)
+}
+
+// issueTokens creates access token and a new refresh token of familyID
+func (s *Server) issueTokens(ctx context.Context, user *models.User, familyID string) (*TokenResponse, error) {
+	accessToken, err := s.accessToken(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.refreshTokens.SaveRefreshToken(ctx, models.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		AccountID: user.AccountID,
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTokenTTL / time.Second),
+	}, nil
+}
+
+// RefreshToken exchanges refresh token for a new access token and refresh token of the same family,
+// presenting already used refresh token revokes the whole family
+func (s *Server) RefreshToken(c *gin.Context) {
+	refreshToken := c.PostForm("refresh_token")
+	if refreshToken == "" {
+		handleErrorResponse(c, ErrMissingRefreshToken)
+		return
+	}
+
+	ctx := c.Request.Context()
+	stored, err := s.refreshTokens.UseRefreshToken(ctx, hashToken(refreshToken))
+	if err == models.ErrRefreshTokenNotFound {
+		handleErrorResponse(c, ErrInvalidRefreshToken)
+		return
+	} else if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	switch {
+	case stored.Revoked:
+		handleErrorResponse(c, ErrInvalidRefreshToken)
+		return
+	case stored.Used:
+		// the token was rotated already, so either the client or an attacker holds a stolen copy
+		log.WithField("user_id", stored.UserID).Warn("refresh token reused, revoking token family")
+		if err := s.refreshTokens.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		handleErrorResponse(c, ErrRefreshTokenReused)
+		return
+	case time.Now().After(stored.ExpiresAt):
+		handleErrorResponse(c, ErrInvalidRefreshToken)
+		return
+	}
+
+	user, err := s.users.GetUserById(ctx, stored.AccountID, stored.UserID)
+	if err == models.ErrUserNotFound {
+		handleErrorResponse(c, ErrInvalidRefreshToken)
+		return
+	} else if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	tokens, err := s.issueTokens(ctx, user, stored.FamilyID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tokens)
+}
+
+// SignOut revokes access token of the request and refresh tokens of its sign in
+func (s *Server) SignOut(c *gin.Context) {
+	claims := getClaims(c)
+	ctx := c.Request.Context()
+
+	err := s.revocations.RevokeAccessToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	err = s.refreshTokens.RevokeRefreshTokenFamily(ctx, claims.FamilyID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SignOutAll revokes tokens of all sessions of the caller including the current one
+func (s *Server) SignOutAll(c *gin.Context) {
+	caller := getCaller(c)
+
+	err := s.revocations.RevokeUserTokenFamilies(c.Request.Context(), caller.AccountID, caller.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// JWKS publishes public keys which verify access tokens, so other services don't need a shared secret
+func (s *Server) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, s.keys.JWKS())
+}
@@ -0,0 +1,376 @@
+package server
+
+import (
+	"calories-counter/common"
+	"calories-counter/models"
+	"calories-counter/totp"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// TOTPIssuer names the service in authenticator apps
+	TOTPIssuer        = "calories-counter"
+	RecoveryCodeCount = 10
+	// mfaTokenTTL limits time between the password and the second step of sign in
+	mfaTokenTTL = 5 * time.Minute
+	// mfaAudience distinguishes mfa tokens from access tokens signed by the same keys
+	mfaAudience = "calories-counter/2fa"
+)
+
+// TwoFactorChallenge is returned by sign in instead of tokens when the user has to pass the second step,
+// EnrollmentRequired is set when role of the user requires two-factor authentication the user has not enabled yet
+type TwoFactorChallenge struct {
+	MFAToken           string `json:"mfa_token"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+}
+
+// TOTPEnrollment is a pending TOTP secret, ProvisioningURI is meant to be shown as QR code
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// twoFactorChallenge returns challenge when the user can't sign in with password only
+func (s *Server) twoFactorChallenge(ctx context.Context, user *models.User) (*TwoFactorChallenge, error) {
+	stored, err := s.twoFactor.GetTOTP(ctx, user.AccountID, user.ID)
+	if err != nil && err != models.ErrTOTPNotFound {
+		return nil, err
+	}
+	enabled := err == nil && stored.Enabled
+
+	var required bool
+	if !enabled {
+		required, err = s.twoFactorRequired(ctx, *user)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+	}
+
+	now := time.Now()
+	token, err := s.keys.Sign(common.JWTClaims{
+		UUID:      user.ID,
+		AccountID: user.AccountID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  mfaAudience,
+			Id:        uuid.New().String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(mfaTokenTTL).Unix(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{MFAToken: token, EnrollmentRequired: !enabled}, nil
+}
+
+// twoFactorRequired reports whether role of the user requires two-factor authentication in its account
+func (s *Server) twoFactorRequired(ctx context.Context, user models.User) (bool, error) {
+	roleIDs, err := s.twoFactor.GetTwoFactorRoles(ctx, user.AccountID)
+	if err != nil {
+		return false, err
+	}
+	for _, roleID := range roleIDs {
+		if roleID == user.RoleID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mfaUser returns user of mfa token issued by SignIn
+func (s *Server) mfaUser(c *gin.Context) (*models.User, error) {
+	tokenString := c.PostForm("mfa_token")
+	if tokenString == "" {
+		return nil, ErrMissingMFAToken
+	}
+	claims := &common.JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keys.Keyfunc)
+	if err != nil || !token.Valid || !claims.VerifyAudience(mfaAudience, true) {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.users.GetUserById(c.Request.Context(), claims.AccountID, claims.UUID)
+	if err == models.ErrUserNotFound {
+		return nil, ErrInvalidMFAToken
+	}
+	return user, err
+}
+
+func (s *Server) startTOTPEnrollment(ctx context.Context, user models.User) (*TOTPEnrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = s.twoFactor.SaveTOTP(ctx, models.TOTP{UserID: user.ID, AccountID: user.AccountID, Secret: secret})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(TOTPIssuer, user.Username, secret),
+	}, nil
+}
+
+// confirmTOTPEnrollment enables pending TOTP with a valid code and returns new recovery codes
+func (s *Server) confirmTOTPEnrollment(ctx context.Context, stored *models.TOTP, code string) ([]string, error) {
+	if stored.Enabled {
+		return nil, models.ErrTOTPAlreadyEnabled
+	}
+	if err := s.checkTOTPCode(ctx, stored, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	err := s.twoFactor.EnableTOTP(ctx, stored.AccountID, stored.UserID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode ignores case and separators users may type differently
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(code)
+}
+
+// checkTOTPCode accepts a valid code which was not used before
+func (s *Server) checkTOTPCode(ctx context.Context, stored *models.TOTP, code string) error {
+	if code == "" {
+		return ErrMissingTwoFactorCode
+	}
+	step, ok := totp.Validate(stored.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	ok, err := s.twoFactor.UseTOTPStep(ctx, stored.AccountID, stored.UserID, step)
+	if err != nil {
+		return err
+	} else if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// checkSecondFactor accepts TOTP code or, when it is given, an unused recovery code
+func (s *Server) checkSecondFactor(ctx context.Context, stored *models.TOTP, code, recoveryCode string) error {
+	if recoveryCode == "" {
+		return s.checkTOTPCode(ctx, stored, code)
+	}
+
+	ok, err := s.twoFactor.UseRecoveryCode(ctx, stored.AccountID, stored.UserID, hashRecoveryCode(recoveryCode))
+	if err != nil {
+		return err
+	} else if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// SignInTwoFactor is the second step of sign in, it exchanges mfa token and TOTP or recovery code for tokens.
+// Pending enrollment started by SignInTwoFactorEnroll is confirmed by the code and recovery codes are returned.
+func (s *Server) SignInTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := s.mfaUser(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	userKey := models.UserThrottleKey(user.AccountID, user.Username)
+	if err := s.checkSignInThrottle(c, userKey); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	stored, err := s.twoFactor.GetTOTP(ctx, user.AccountID, user.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	var recoveryCodes []string
+	if stored.Enabled {
+		err = s.checkSecondFactor(ctx, stored, c.PostForm("code"), c.PostForm("recovery_code"))
+	} else {
+		recoveryCodes, err = s.confirmTOTPEnrollment(ctx, stored, c.PostForm("code"))
+	}
+	if err == ErrInvalidTwoFactorCode {
+		s.recordSignInFailure(c, userKey)
+	}
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	tokens, err := s.issueTokens(ctx, user, uuid.New().String())
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	s.resetSignInFailures(c, userKey)
+
+	c.JSON(http.StatusCreated, struct {
+		*TokenResponse
+		RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	}{
+		TokenResponse: tokens,
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// SignInTwoFactorEnroll starts TOTP enrollment of user whose role requires two-factor authentication
+// before the user can sign in
+func (s *Server) SignInTwoFactorEnroll(c *gin.Context) {
+	user, err := s.mfaUser(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	enrollment, err := s.startTOTPEnrollment(c.Request.Context(), *user)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+// EnrollTOTP starts TOTP enrollment of the caller, it is enabled by ConfirmTOTP
+func (s *Server) EnrollTOTP(c *gin.Context) {
+	enrollment, err := s.startTOTPEnrollment(c.Request.Context(), getCaller(c))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+func (s *Server) ConfirmTOTP(c *gin.Context) {
+	caller := getCaller(c)
+	var body TwoFactorCodeBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+
+	stored, err := s.twoFactor.GetTOTP(c.Request.Context(), caller.AccountID, caller.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	recoveryCodes, err := s.confirmTOTPEnrollment(c.Request.Context(), stored, body.Code)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// DisableTOTP turns off two-factor authentication of the caller, it needs a valid code
+// and is refused when role of the caller requires two-factor authentication
+func (s *Server) DisableTOTP(c *gin.Context) {
+	caller := getCaller(c)
+	ctx := c.Request.Context()
+	var body TwoFactorCodeBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+
+	stored, err := s.twoFactor.GetTOTP(ctx, caller.AccountID, caller.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if stored.Enabled {
+		required, err := s.twoFactorRequired(ctx, caller)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		} else if required {
+			handleErrorResponse(c, ErrTwoFactorRequired)
+			return
+		}
+		if err := s.checkSecondFactor(ctx, stored, body.Code, body.RecoveryCode); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	err = s.twoFactor.DeleteTOTP(ctx, caller.AccountID, caller.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) GetTwoFactorRoles(c *gin.Context) {
+	caller := getCaller(c)
+	roleIDs, err := s.twoFactor.GetTwoFactorRoles(c.Request.Context(), caller.AccountID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TwoFactorRolesBody{RoleIDs: roleIDs})
+}
+
+// UpdateTwoFactorRoles sets roles which can't sign in without two-factor authentication in the account
+// of the caller, users of those roles enroll at their next sign in
+func (s *Server) UpdateTwoFactorRoles(c *gin.Context) {
+	caller := getCaller(c)
+	var body TwoFactorRolesBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		handleErrorResponse(c, ErrInvalidJSON)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	for _, roleID := range body.RoleIDs {
+		_, err := s.getRole(c.Request.Context(), caller.AccountID, roleID)
+		if err == models.ErrRoleNotFound {
+			handleErrorResponse(c, ErrInvalidRoleID)
+			return
+		} else if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	err := s.twoFactor.SetTwoFactorRoles(c.Request.Context(), caller.AccountID, body.RoleIDs)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, body)
+}
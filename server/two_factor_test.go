@@ -0,0 +1,502 @@
+package server
+
+import (
+	"calories-counter/adapters/user_datastore"
+	"calories-counter/common"
+	"calories-counter/models"
+	"calories-counter/totp"
+	"context"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func currentTOTPCode(t *testing.T) string {
+	code, err := totp.Code(testTOTPSecret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func testMFAToken(t *testing.T, user *models.User, audience string) string {
+	token, err := testKeys.Sign(common.JWTClaims{
+		UUID:      user.ID,
+		AccountID: user.AccountID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  audience,
+			Id:        "jti",
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func mfaForm(mfaToken, code, recoveryCode string) string {
+	form := url.Values{}
+	form.Set("mfa_token", mfaToken)
+	form.Set("code", code)
+	form.Set("recovery_code", recoveryCode)
+	return form.Encode()
+}
+
+func TestSignInTwoFactor(t *testing.T) {
+	user := &models.User{ID: "3", AccountID: "1", Username: "user", RoleID: models.AdminRole}
+	mfaToken := testMFAToken(t, user, mfaAudience)
+	code := currentTOTPCode(t)
+	enabled := &models.TOTP{UserID: "3", AccountID: "1", Secret: testTOTPSecret, Enabled: true}
+	pending := &models.TOTP{UserID: "3", AccountID: "1", Secret: testTOTPSecret}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "MissingMFAToken",
+			postForm:      true,
+			body:          mfaForm("", code, ""),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingMFAToken,
+		},
+		{
+			name:          "AccessTokenAsMFAToken",
+			postForm:      true,
+			body:          mfaForm(testMFAToken(t, user, ""), code, ""),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidMFAToken,
+		},
+		{
+			name:          "MissingCode",
+			postForm:      true,
+			body:          mfaForm(mfaToken, "", ""),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrMissingTwoFactorCode,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+			},
+		},
+		{
+			name:          "UserLocked",
+			postForm:      true,
+			body:          mfaForm(mfaToken, code, ""),
+			expectedCode:  http.StatusLocked,
+			expectedError: ErrUserLocked,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				m.EXPECT().GetLoginFailures(gomock.Any(), "user:1:user").
+					Return(models.LoginFailures{Failures: LockoutThreshold, LastFailure: time.Now()}, nil)
+			},
+		},
+		{
+			name:          "InvalidCode",
+			postForm:      true,
+			body:          mfaForm(mfaToken, "000000", ""),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidTwoFactorCode,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+				expectSignInFailure(m, "user:1:user")
+			},
+		},
+		{
+			name:          "ReplayedCode",
+			postForm:      true,
+			body:          mfaForm(mfaToken, code, ""),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidTwoFactorCode,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+				m.EXPECT().UseTOTPStep(gomock.Any(), "1", "3", gomock.Any()).Return(false, nil)
+				expectSignInFailure(m, "user:1:user")
+			},
+		},
+		{
+			name:          "UsedRecoveryCode",
+			postForm:      true,
+			body:          mfaForm(mfaToken, "", "abcd-efgh-ijkl-mnop"),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidTwoFactorCode,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+				m.EXPECT().UseRecoveryCode(gomock.Any(), "1", "3", hashRecoveryCode("abcd-efgh-ijkl-mnop")).Return(false, nil)
+				expectSignInFailure(m, "user:1:user")
+			},
+		},
+		{
+			name:          "NotEnrolled",
+			postForm:      true,
+			body:          mfaForm(mfaToken, code, ""),
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrTOTPNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(nil, models.ErrTOTPNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "SignedInWithCode",
+			postForm:     true,
+			body:         mfaForm(mfaToken, code, ""),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+				m.EXPECT().UseTOTPStep(gomock.Any(), "1", "3", gomock.Any()).Return(true, nil)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+		{
+			name:         "SignedInWithRecoveryCode",
+			postForm:     true,
+			body:         mfaForm(mfaToken, "", "ABCD EFGH IJKL MNOP"),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+				m.EXPECT().UseRecoveryCode(gomock.Any(), "1", "3", hashRecoveryCode("abcd-efgh-ijkl-mnop")).Return(true, nil)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+		{
+			name:         "EnrollmentConfirmed",
+			postForm:     true,
+			body:         mfaForm(mfaToken, code, ""),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				expectSignInAllowed(m, "user:1:user")
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(pending, nil)
+				m.EXPECT().UseTOTPStep(gomock.Any(), "1", "3", gomock.Any()).Return(true, nil)
+				m.EXPECT().EnableTOTP(gomock.Any(), "1", "3", gomock.Len(RecoveryCodeCount)).Return(nil)
+				m.EXPECT().SaveRefreshToken(gomock.Any(), refreshTokenOf(*user)).Return(nil)
+				m.EXPECT().ResetLoginFailures(gomock.Any(), "user:1:user").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/signin/2fa", tc)
+		})
+	}
+}
+
+func TestSignInTwoFactorEnroll(t *testing.T) {
+	user := &models.User{ID: "3", AccountID: "1", Username: "user", RoleID: models.AdminRole}
+	mfaToken := testMFAToken(t, user, mfaAudience)
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "DeletedUser",
+			postForm:      true,
+			body:          mfaForm(mfaToken, "", ""),
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidMFAToken,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "AlreadyEnabled",
+			postForm:      true,
+			body:          mfaForm(mfaToken, "", ""),
+			expectedCode:  http.StatusConflict,
+			expectedError: models.ErrTOTPAlreadyEnabled,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				m.EXPECT().SaveTOTP(gomock.Any(), gomock.Any()).Return(models.ErrTOTPAlreadyEnabled)
+			},
+		},
+
+		// success tests
+		{
+			name:         "EnrollmentStarted",
+			postForm:     true,
+			body:         mfaForm(mfaToken, "", ""),
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "1", "3").Return(user, nil)
+				m.EXPECT().SaveTOTP(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, stored models.TOTP) error {
+					if stored.UserID != "3" || stored.AccountID != "1" || stored.Secret == "" || stored.Enabled {
+						t.Errorf("Unexpected pending TOTP %+v", stored)
+					}
+					return nil
+				})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/signin/2fa/enroll", tc)
+		})
+	}
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", Username: "user"}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "AlreadyEnabled",
+			caller:        caller,
+			expectedCode:  http.StatusConflict,
+			expectedError: models.ErrTOTPAlreadyEnabled,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveTOTP(gomock.Any(), gomock.Any()).Return(models.ErrTOTPAlreadyEnabled)
+			},
+		},
+
+		// success tests
+		{
+			name:         "EnrollmentStarted",
+			caller:       caller,
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SaveTOTP(gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/me/2fa", tc)
+		})
+	}
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", Username: "user"}
+	code := currentTOTPCode(t)
+	pending := &models.TOTP{UserID: "3", AccountID: "1", Secret: testTOTPSecret}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "InvalidJSON",
+			caller:        caller,
+			body:          `{"code":1}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidJSON,
+		},
+		{
+			name:          "NotEnrolled",
+			caller:        caller,
+			body:          `{"code":"` + code + `"}`,
+			expectedCode:  http.StatusNotFound,
+			expectedError: models.ErrTOTPNotFound,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(nil, models.ErrTOTPNotFound)
+			},
+		},
+		{
+			name:          "AlreadyEnabled",
+			caller:        caller,
+			body:          `{"code":"` + code + `"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: models.ErrTOTPAlreadyEnabled,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(&models.TOTP{Enabled: true}, nil)
+			},
+		},
+		{
+			name:          "InvalidCode",
+			caller:        caller,
+			body:          `{"code":"abc"}`,
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidTwoFactorCode,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(pending, nil)
+			},
+		},
+
+		// success tests
+		{
+			name:         "Confirmed",
+			caller:       caller,
+			body:         `{"code":"` + code + `"}`,
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(pending, nil)
+				m.EXPECT().UseTOTPStep(gomock.Any(), "1", "3", gomock.Any()).Return(true, nil)
+				m.EXPECT().EnableTOTP(gomock.Any(), "1", "3", gomock.Len(RecoveryCodeCount)).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "POST", "/v1/me/2fa/confirm", tc)
+		})
+	}
+}
+
+func TestDisableTOTP(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", Username: "user", RoleID: models.AdminRole}
+	code := currentTOTPCode(t)
+	enabled := &models.TOTP{UserID: "3", AccountID: "1", Secret: testTOTPSecret, Enabled: true}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "RequiredForRole",
+			caller:        caller,
+			body:          `{"code":"` + code + `"}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrTwoFactorRequired,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+				m.EXPECT().GetTwoFactorRoles(gomock.Any(), "1").Return([]int{models.AdminRole}, nil)
+			},
+		},
+		{
+			name:          "InvalidCode",
+			caller:        caller,
+			body:          `{"code":"000000"}`,
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: ErrInvalidTwoFactorCode,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+				m.EXPECT().GetTwoFactorRoles(gomock.Any(), "1").Return([]int{}, nil)
+			},
+		},
+		{
+			name:          "ErrWhenDeleteTOTP",
+			caller:        caller,
+			body:          `{}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(&models.TOTP{}, nil)
+				m.EXPECT().DeleteTOTP(gomock.Any(), "1", "3").Return(errors.New("err"))
+			},
+		},
+
+		// success tests
+		{
+			name:         "Disabled",
+			caller:       caller,
+			body:         `{"code":"` + code + `"}`,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(enabled, nil)
+				m.EXPECT().GetTwoFactorRoles(gomock.Any(), "1").Return([]int{models.OwnerRole}, nil)
+				m.EXPECT().UseTOTPStep(gomock.Any(), "1", "3", gomock.Any()).Return(true, nil)
+				m.EXPECT().DeleteTOTP(gomock.Any(), "1", "3").Return(nil)
+			},
+		},
+		{
+			name:         "PendingEnrollmentCanceled",
+			caller:       caller,
+			body:         `{}`,
+			expectedCode: http.StatusNoContent,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetTOTP(gomock.Any(), "1", "3").Return(&models.TOTP{}, nil)
+				m.EXPECT().DeleteTOTP(gomock.Any(), "1", "3").Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "DELETE", "/v1/me/2fa", tc)
+		})
+	}
+}
+
+func TestUpdateTwoFactorRoles(t *testing.T) {
+	caller := models.User{ID: "3", AccountID: "1", Username: "owner", RoleID: models.OwnerRole}
+
+	testCases := []testCase{
+		// errors
+		{
+			name:          "MissingRoles",
+			caller:        caller,
+			body:          `{}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidJSON,
+		},
+		{
+			name:          "InvalidRoleID",
+			caller:        caller,
+			body:          `{"role_ids":[2,7]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidRoleID,
+		},
+		{
+			name:          "CustomRoleNotFound",
+			caller:        caller,
+			body:          `{"role_ids":[2,100]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidRoleID,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "1", 100).Return(nil, models.ErrRoleNotFound)
+			},
+		},
+
+		// success tests
+		{
+			name:         "Updated",
+			caller:       caller,
+			body:         `{"role_ids":[2,3]}`,
+			expectedBody: `{"role_ids":[2,3]}`,
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().SetTwoFactorRoles(gomock.Any(), "1", []int{models.AdminRole, models.OwnerRole}).Return(nil)
+			},
+		},
+		{
+			name:         "UpdatedWithCustomRole",
+			caller:       caller,
+			body:         `{"role_ids":[100]}`,
+			expectedBody: `{"role_ids":[100]}`,
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "1", 100).Return(&models.Role{ID: 100, AccountID: "1", Name: "auditor"}, nil)
+				m.EXPECT().SetTwoFactorRoles(gomock.Any(), "1", []int{100}).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runTest(t, "PUT", "/v1/account/2fa", tc)
+		})
+	}
+}
+
+func TestGetTwoFactorRoles(t *testing.T) {
+	tc := testCase{
+		caller:       models.User{ID: "3", AccountID: "1", Username: "owner", RoleID: models.OwnerRole},
+		expectedBody: `{"role_ids":[3]}`,
+		expectedCode: http.StatusOK,
+		setupMockUser: func(m *user_datastore.MockUserDatastore) {
+			m.EXPECT().GetTwoFactorRoles(gomock.Any(), "1").Return([]int{models.OwnerRole}, nil)
+		},
+	}
+	runTest(t, "GET", "/v1/account/2fa", tc)
+}
@@ -2,13 +2,15 @@ package server
 
 import (
 	"calories-counter/models"
+	passwords "calories-counter/password"
+	"calories-counter/policy"
+	"context"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
 
-func CreateUser(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	caller := c.MustGet("caller").(models.User)
+func (s *Server) CreateUser(c *gin.Context) {
+	caller := getCaller(c)
 	var body UserPostBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		handleErrorResponse(c, ErrInvalidJSON)
@@ -20,13 +22,33 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	// user manager can create only standard users
-	if caller.RoleID == models.UserManagerRole && body.RoleID != models.UserRole {
-		handleErrorResponse(c, ErrInsufficientPermissions)
+	role, err := s.authorizeRole(c, body.RoleID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	hash, err := passwords.Hash(body.Password)
+	if err != nil {
+		handleErrorResponse(c, err)
 		return
 	}
 
-	newUser, err := userRepo.SaveUser(caller.AccountID, body.Username, body.Password, body.RoleID)
+	actor, err := s.callerSubject(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	var newUser *models.User
+	err = s.unitOfWork.Do(c.Request.Context(), func(ctx context.Context, repos models.Repositories) error {
+		var err error
+		newUser, err = repos.Users.SaveUser(ctx, caller.AccountID, body.Username, hash, body.RoleID)
+		if err != nil {
+			return err
+		}
+		return addToCreatorTeam(ctx, repos.Teams, actor, *newUser, *role)
+	})
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -35,11 +57,10 @@ func CreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, newUser)
 }
 
-func GetUsers(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	caller := c.MustGet("caller").(models.User)
-	page, perPage, filter := PageParams(c)
-	users, err := userRepo.GetUsers(caller.AccountID, page, perPage, filter)
+func (s *Server) GetUsers(c *gin.Context) {
+	caller := getCaller(c)
+	params := PageParams(c)
+	users, err := s.users.GetUsers(c.Request.Context(), caller.AccountID, params)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -50,17 +71,16 @@ func GetUsers(c *gin.Context) {
 		Links []Link `json:"links"`
 	}{
 		UserSlice: users,
-		Links:     CreateLinks(c, users.Total, page, perPage),
+		Links:     CreateLinks(c, users.Total, params, ""),
 	}
 
 	c.PureJSON(http.StatusOK, result)
 }
 
-func GetUser(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	caller := c.MustGet("caller").(models.User)
+func (s *Server) GetUser(c *gin.Context) {
+	caller := getCaller(c)
 
-	user, err := userRepo.GetUserById(caller.AccountID, c.Param("user_id"))
+	user, err := s.users.GetUserById(c.Request.Context(), caller.AccountID, c.Param("user_id"))
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -69,9 +89,8 @@ func GetUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
-func UpdateUser(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	caller := c.MustGet("caller").(models.User)
+func (s *Server) UpdateUser(c *gin.Context) {
+	caller := getCaller(c)
 	var body UserPutBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		handleErrorResponse(c, ErrInvalidJSON)
@@ -83,23 +102,26 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := userRepo.GetUserById(caller.AccountID, c.Param("user_id"))
+	user, err := s.users.GetUserById(c.Request.Context(), caller.AccountID, c.Param("user_id"))
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
 
-	// user manager cannot change role or modify non standard users
-	if caller.RoleID == models.UserManagerRole && (user.RoleID != models.UserRole || body.RoleID != nil) {
-		handleErrorResponse(c, ErrInsufficientPermissions)
+	if err := s.authorize(c, policy.WriteUser, user); err != nil {
+		handleErrorResponse(c, err)
 		return
 	}
 
 	user.Username = body.Username
-	if body.RoleID != nil {
+	if body.RoleID != nil && *body.RoleID != user.RoleID {
+		if _, err := s.authorizeRole(c, *body.RoleID); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
 		user.RoleID = *body.RoleID
 	}
-	newUser, err := userRepo.UpdateUser(*user)
+	newUser, err := s.users.UpdateUser(c.Request.Context(), *user)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -108,25 +130,24 @@ func UpdateUser(c *gin.Context) {
 	c.JSON(http.StatusOK, newUser)
 }
 
-func DeleteUser(c *gin.Context) {
-	userRepo := c.MustGet("userDatastore").(models.UserDatastore)
-	caller := c.MustGet("caller").(models.User)
+func (s *Server) DeleteUser(c *gin.Context) {
+	caller := getCaller(c)
 
-	user, err := userRepo.GetUserById(caller.AccountID, c.Param("user_id"))
+	user, err := s.users.GetUserById(c.Request.Context(), caller.AccountID, c.Param("user_id"))
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
 
-	// user manager cannot delete non standard users
-	if caller.RoleID == models.UserManagerRole && user.RoleID != models.UserRole {
-		handleErrorResponse(c, ErrInsufficientPermissions)
+	if err := s.authorize(c, policy.WriteUser, user); err != nil {
+		handleErrorResponse(c, err)
 		return
 	}
 
-	err = userRepo.DeleteUser(caller.AccountID, c.Param("user_id"))
+	err = s.users.DeleteUser(c.Request.Context(), caller.AccountID, c.Param("user_id"))
 	if err != nil {
 		handleErrorResponse(c, err)
+		return
 	}
 
 	c.Status(http.StatusNoContent)
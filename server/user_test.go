@@ -3,7 +3,11 @@ package server
 import (
 	"calories-counter/adapters/user_datastore"
 	"calories-counter/models"
+	"calories-counter/policy"
+	"context"
 	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
 	"net/http"
 	"testing"
 )
@@ -24,7 +28,15 @@ func TestGetUser(t *testing.T) {
 			expectedCode:  http.StatusNotFound,
 			expectedError: models.ErrUserNotFound,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(nil, models.ErrUserNotFound)
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "RequestTimeout",
+			expectedCode:  http.StatusGatewayTimeout,
+			expectedError: ErrRequestTimeout,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(nil, context.DeadlineExceeded)
 			},
 		},
 
@@ -34,7 +46,7 @@ func TestGetUser(t *testing.T) {
 			expectedCode: http.StatusOK,
 			expectedBody: string(jsonTestUser),
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(testUser, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(testUser, nil)
 			},
 		},
 	}
@@ -53,7 +65,7 @@ func TestGetUsers(t *testing.T) {
 			name:         "UserReturnedSuccessfully",
 			expectedCode: http.StatusOK,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUsers("", 0, 10, "").Return(models.UserSlice{
+				m.EXPECT().GetUsers(gomock.Any(), "", models.ListParams{PerPage: 10}).Return(models.UserSlice{
 					Items: []models.User{
 						{AccountID: "1", RoleID: models.AdminRole, Username: "admin1"},
 						{AccountID: "1", RoleID: models.UserRole, Username: "user1"},
@@ -92,15 +104,78 @@ func TestCreateUser(t *testing.T) {
 			expectedCode:  http.StatusForbidden,
 			expectedError: ErrInsufficientPermissions,
 		},
+		{
+			name:          "CustomRoleNotFound",
+			body:          `{"username":"testuser", "password": "Xyz123", "role_id": 100}`,
+			caller:        models.User{RoleID: models.AdminRole},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: ErrInvalidRoleID,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "", 100).Return(nil, models.ErrRoleNotFound)
+			},
+		},
+		{
+			name:          "InsufficientPermissionForUserManagerToAssignCustomManagingRole",
+			body:          `{"username":"testuser", "password": "Xyz123", "role_id": 100}`,
+			caller:        models.User{RoleID: models.UserManagerRole},
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "", 100).
+					Return(&models.Role{ID: 100, Name: "auditor", Permissions: []string{policy.UsersRead}}, nil)
+			},
+		},
+		{
+			name:          "UserManagerCreatedUserWithBroaderCustomRole",
+			body:          `{"username":"testuser", "password": "Xyz123", "role_id": 100}`,
+			caller:        models.User{ID: "4", RoleID: models.UserManagerRole},
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "", 100).
+					Return(&models.Role{ID: 100, Name: "dietitian", Permissions: []string{policy.MealsReadAny}}, nil)
+			},
+		},
+
+		{
+			name:          "ErrWhenAddTeamMember",
+			body:          `{"username":"testuser", "password": "Xyz123", "role_id": 0}`,
+			caller:        models.User{ID: "4", RoleID: models.UserManagerRole},
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: ErrInternalServerError,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				expectUnitOfWork(m)
+				m.EXPECT().SaveUser(gomock.Any(), "", "testuser", passwordOf("Xyz123"), 0).
+					Return(&models.User{ID: "5", Username: "testuser"}, nil)
+				m.EXPECT().AddTeamMember(gomock.Any(), "", "4", "5").Return(errors.New("err"))
+			},
+		},
 
 		// success tests
+		{
+			name:         "UserManagerCreatedUserWithCustomRole",
+			body:         `{"username":"testuser", "password": "Xyz123", "role_id": 100}`,
+			caller:       models.User{ID: "4", RoleID: models.UserManagerRole},
+			expectedCode: http.StatusCreated,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetRole(gomock.Any(), "", 100).Return(&models.Role{ID: 100, Name: "coach",
+					Permissions: []string{policy.MealsReadOwn, policy.MealsWriteOwn, policy.MealsReadTeam}}, nil)
+				expectUnitOfWork(m)
+				m.EXPECT().SaveUser(gomock.Any(), "", "testuser", passwordOf("Xyz123"), 100).
+					Return(&models.User{ID: "5", Username: "testuser", RoleID: 100}, nil)
+				m.EXPECT().AddTeamMember(gomock.Any(), "", "4", "5").Return(nil)
+			},
+		},
 		{
 			name:         "UserManagerCreatedUser",
 			body:         `{"username":"testuser", "password": "Xyz123", "role_id": 0}`,
-			caller:       models.User{RoleID: models.UserManagerRole},
+			caller:       models.User{ID: "4", RoleID: models.UserManagerRole},
 			expectedCode: http.StatusCreated,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().SaveUser("", "testuser", "Xyz123", 0)
+				expectUnitOfWork(m)
+				m.EXPECT().SaveUser(gomock.Any(), "", "testuser", passwordOf("Xyz123"), 0).
+					Return(&models.User{ID: "5", Username: "testuser"}, nil)
+				m.EXPECT().AddTeamMember(gomock.Any(), "", "4", "5").Return(nil)
 			},
 		},
 		{
@@ -109,7 +184,9 @@ func TestCreateUser(t *testing.T) {
 			caller:       models.User{RoleID: models.AdminRole},
 			expectedCode: http.StatusCreated,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().SaveUser("", "usermanager", "Xyz123", 1)
+				expectUnitOfWork(m)
+				m.EXPECT().SaveUser(gomock.Any(), "", "usermanager", passwordOf("Xyz123"), 1).
+					Return(&models.User{ID: "5", Username: "usermanager", RoleID: 1}, nil)
 			},
 		},
 	}
@@ -147,7 +224,7 @@ func TestUpdateUser(t *testing.T) {
 			expectedCode:  http.StatusNotFound,
 			expectedError: models.ErrUserNotFound,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(nil, models.ErrUserNotFound)
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(nil, models.ErrUserNotFound)
 			},
 		},
 		{
@@ -157,7 +234,7 @@ func TestUpdateUser(t *testing.T) {
 			expectedCode:  http.StatusForbidden,
 			expectedError: ErrInsufficientPermissions,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(&models.User{}, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{}, nil)
 			},
 		},
 		{
@@ -167,7 +244,32 @@ func TestUpdateUser(t *testing.T) {
 			expectedCode:  http.StatusForbidden,
 			expectedError: ErrInsufficientPermissions,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
+			},
+		},
+
+		{
+			name:          "InsufficientPermissionForUserManagerToMakeChangeForCustomManagingRole",
+			body:          `{"username":"testuser"}`,
+			caller:        models.User{RoleID: models.UserManagerRole},
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: 100}, nil)
+				m.EXPECT().GetRole(gomock.Any(), "", 100).
+					Return(&models.Role{ID: 100, Name: "auditor", Permissions: []string{policy.UsersRead}}, nil)
+			},
+		},
+		{
+			name:          "LastOwnerDemoted",
+			body:          `{"username":"testuser", "role_id":2}`,
+			caller:        models.User{RoleID: models.OwnerRole},
+			expectedCode:  http.StatusConflict,
+			expectedError: models.ErrLastOwner,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.OwnerRole}, nil)
+				m.EXPECT().UpdateUser(gomock.Any(), models.User{Username: "testuser", RoleID: 2}).
+					Return(nil, models.ErrLastOwner)
 			},
 		},
 
@@ -178,8 +280,8 @@ func TestUpdateUser(t *testing.T) {
 			caller:       models.User{RoleID: models.UserManagerRole},
 			expectedCode: http.StatusOK,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(&models.User{}, nil)
-				m.EXPECT().UpdateUser(models.User{Username: "testuser"})
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{}, nil)
+				m.EXPECT().UpdateUser(gomock.Any(), models.User{Username: "testuser"})
 			},
 		},
 		{
@@ -188,8 +290,42 @@ func TestUpdateUser(t *testing.T) {
 			caller:       models.User{RoleID: models.AdminRole},
 			expectedCode: http.StatusOK,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
-				m.EXPECT().UpdateUser(models.User{Username: "testuser", RoleID: 2})
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
+				m.EXPECT().UpdateUser(gomock.Any(), models.User{Username: "testuser", RoleID: 2})
+			},
+		},
+		{
+			name:          "UserManagerAssignedBroaderCustomRole",
+			body:          `{"username":"testuser", "role_id":100}`,
+			caller:        models.User{RoleID: models.UserManagerRole},
+			expectedCode:  http.StatusForbidden,
+			expectedError: ErrInsufficientPermissions,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{}, nil)
+				m.EXPECT().GetRole(gomock.Any(), "", 100).
+					Return(&models.Role{ID: 100, Name: "dietitian", Permissions: []string{policy.MealsReadAny}}, nil)
+			},
+		},
+		{
+			name:         "UserManagerAssignedCustomRole",
+			body:         `{"username":"testuser", "role_id":100}`,
+			caller:       models.User{RoleID: models.UserManagerRole},
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{}, nil)
+				m.EXPECT().GetRole(gomock.Any(), "", 100).
+					Return(&models.Role{ID: 100, Name: "coach", Permissions: []string{policy.MealsWriteOwn}}, nil)
+				m.EXPECT().UpdateUser(gomock.Any(), models.User{Username: "testuser", RoleID: 100})
+			},
+		},
+		{
+			name:         "OneOfOwnersDemoted",
+			body:         `{"username":"testuser", "role_id":2}`,
+			caller:       models.User{RoleID: models.OwnerRole},
+			expectedCode: http.StatusOK,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.OwnerRole}, nil)
+				m.EXPECT().UpdateUser(gomock.Any(), models.User{Username: "testuser", RoleID: 2})
 			},
 		},
 	}
@@ -209,7 +345,7 @@ func TestDeleteUser(t *testing.T) {
 			expectedCode:  http.StatusNotFound,
 			expectedError: models.ErrUserNotFound,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(nil, models.ErrUserNotFound)
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(nil, models.ErrUserNotFound)
 			},
 		},
 		{
@@ -218,7 +354,17 @@ func TestDeleteUser(t *testing.T) {
 			expectedCode:  http.StatusForbidden,
 			expectedError: ErrInsufficientPermissions,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
+			},
+		},
+		{
+			name:          "LastOwnerDeleted",
+			caller:        models.User{RoleID: models.OwnerRole},
+			expectedCode:  http.StatusConflict,
+			expectedError: models.ErrLastOwner,
+			setupMockUser: func(m *user_datastore.MockUserDatastore) {
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.OwnerRole}, nil)
+				m.EXPECT().DeleteUser(gomock.Any(), "", "1").Return(models.ErrLastOwner)
 			},
 		},
 
@@ -228,8 +374,8 @@ func TestDeleteUser(t *testing.T) {
 			caller:       models.User{RoleID: models.UserManagerRole},
 			expectedCode: http.StatusNoContent,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(&models.User{RoleID: models.UserRole}, nil)
-				m.EXPECT().DeleteUser("", "1")
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.UserRole}, nil)
+				m.EXPECT().DeleteUser(gomock.Any(), "", "1")
 			},
 		},
 		{
@@ -237,8 +383,8 @@ func TestDeleteUser(t *testing.T) {
 			caller:       models.User{RoleID: models.AdminRole},
 			expectedCode: http.StatusNoContent,
 			setupMockUser: func(m *user_datastore.MockUserDatastore) {
-				m.EXPECT().GetUserById("", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
-				m.EXPECT().DeleteUser("", "1")
+				m.EXPECT().GetUserById(gomock.Any(), "", "1").Return(&models.User{RoleID: models.UserManagerRole}, nil)
+				m.EXPECT().DeleteUser(gomock.Any(), "", "1")
 			},
 		},
 	}
@@ -0,0 +1,71 @@
+package signing
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// keyFile is an entry of keys file, PrivateKey is a path of PEM file relative to the keys file
+type keyFile struct {
+	ID         string     `json:"kid"`
+	Algorithm  string     `json:"alg"`
+	PrivateKey string     `json:"private_key"`
+	NotBefore  time.Time  `json:"not_before"`
+	NotAfter   *time.Time `json:"not_after"`
+}
+
+// LoadKeySet reads keys file, a JSON list of keys, e.g.
+//
+//	[{"kid": "2020-06", "alg": "ES256", "private_key": "2020-06.pem", "not_before": "2020-06-01T00:00:00Z"}]
+//
+// Keys are rotated by adding a new key with future not_before and setting not_after of the old key
+// past the lifetime of tokens it signed.
+func LoadKeySet(path string) (*KeySet, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []keyFile
+	if err := json.Unmarshal(b, &files); err != nil {
+		return nil, fmt.Errorf("signing: invalid keys file %s: %w", path, err)
+	}
+
+	keys := make([]Key, 0, len(files))
+	for _, f := range files {
+		pemPath := f.PrivateKey
+		if !filepath.IsAbs(pemPath) {
+			pemPath = filepath.Join(filepath.Dir(path), pemPath)
+		}
+		private, err := loadPrivateKey(pemPath, f.Algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("signing: key %s: %w", f.ID, err)
+		}
+		key := Key{ID: f.ID, Algorithm: f.Algorithm, PrivateKey: private, NotBefore: f.NotBefore}
+		if f.NotAfter != nil {
+			key.NotAfter = *f.NotAfter
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(keys...)
+}
+
+func loadPrivateKey(path, algorithm string) (crypto.Signer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch algorithm {
+	case RS256:
+		return jwt.ParseRSAPrivateKeyFromPEM(b)
+	case ES256:
+		return jwt.ParseECPrivateKeyFromPEM(b)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+}
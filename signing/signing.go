@@ -0,0 +1,153 @@
+// Package signing keeps asymmetric keys which sign access tokens. Keys are identified by `kid` header of tokens
+// and rotated by schedule: a key is published in JWKS as soon as it is configured, signs new tokens from NotBefore
+// and verifies tokens until NotAfter, so other services can fetch it before the first token signed with it.
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"sort"
+	"time"
+)
+
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrNoKeys             = errors.New("signing: no keys")
+	ErrNoActiveKey        = errors.New("signing: no active signing key")
+	ErrUnknownKey         = errors.New("signing: unknown key")
+	ErrUnexpectedMethod   = errors.New("signing: unexpected signing method")
+	ErrMissingKeyID       = errors.New("signing: missing key id")
+	ErrDuplicateKeyID     = errors.New("signing: duplicate key id")
+	ErrInvalidKeySchedule = errors.New("signing: key NotAfter has to be after NotBefore")
+)
+
+// Key is a private key with its rotation schedule, zero NotAfter means the key is never retired
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	NotBefore  time.Time
+	NotAfter   time.Time
+}
+
+func (k Key) method() jwt.SigningMethod {
+	if k.Algorithm == RS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodES256
+}
+
+func (k Key) validate() error {
+	if k.ID == "" {
+		return ErrMissingKeyID
+	}
+	switch k.Algorithm {
+	case RS256:
+		if _, ok := k.PrivateKey.(*rsa.PrivateKey); !ok {
+			return fmt.Errorf("signing: key %s: %s requires RSA private key", k.ID, k.Algorithm)
+		}
+	case ES256:
+		if key, ok := k.PrivateKey.(*ecdsa.PrivateKey); !ok || key.Curve != elliptic.P256() {
+			return fmt.Errorf("signing: key %s: %s requires P-256 ECDSA private key", k.ID, k.Algorithm)
+		}
+	default:
+		return fmt.Errorf("signing: key %s: unsupported algorithm %q", k.ID, k.Algorithm)
+	}
+	if !k.NotAfter.IsZero() && !k.NotAfter.After(k.NotBefore) {
+		return ErrInvalidKeySchedule
+	}
+	return nil
+}
+
+func (k Key) retiredAt(t time.Time) bool {
+	return !k.NotAfter.IsZero() && !t.Before(k.NotAfter)
+}
+
+// GenerateKey creates ES256 key which is active right away
+func GenerateKey(id string) (Key, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{ID: id, Algorithm: ES256, PrivateKey: private}, nil
+}
+
+// KeySet signs tokens with the newest active key and verifies them with the key named by their `kid`
+type KeySet struct {
+	// keys are ordered by NotBefore
+	keys []Key
+	now  func() time.Time
+}
+
+// NewKeySet validates keys, key ids have to be unique
+func NewKeySet(keys ...Key) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if err := k.validate(); err != nil {
+			return nil, err
+		}
+		if seen[k.ID] {
+			return nil, ErrDuplicateKeyID
+		}
+		seen[k.ID] = true
+	}
+
+	sorted := append([]Key{}, keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].NotBefore.Before(sorted[j].NotBefore)
+	})
+	return &KeySet{keys: sorted, now: time.Now}, nil
+}
+
+// SigningKey returns the key which signs new tokens, it is the active key with the latest NotBefore
+func (s *KeySet) SigningKey() (Key, error) {
+	now := s.now()
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		k := s.keys[i]
+		if !now.Before(k.NotBefore) && !k.retiredAt(now) {
+			return k, nil
+		}
+	}
+	return Key{}, ErrNoActiveKey
+}
+
+// Sign returns token with claims signed by the signing key
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key, err := s.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// Keyfunc returns public key of token for jwt.Parse, the token has to be signed by a key of the set
+// which is not retired and with its algorithm
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	now := s.now()
+	for _, k := range s.keys {
+		if k.ID != kid || k.retiredAt(now) {
+			continue
+		}
+		if token.Method.Alg() != k.Algorithm {
+			return nil, ErrUnexpectedMethod
+		}
+		return k.PrivateKey.Public(), nil
+	}
+	return nil, ErrUnknownKey
+}
@@ -0,0 +1,192 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC)
+
+func newKey(t *testing.T, id, algorithm string, notBefore, notAfter time.Time) Key {
+	t.Helper()
+	key := Key{ID: id, Algorithm: algorithm, NotBefore: notBefore, NotAfter: notAfter}
+	var err error
+	if algorithm == RS256 {
+		key.PrivateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		key.PrivateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newTestKeySet(t *testing.T, keys ...Key) *KeySet {
+	t.Helper()
+	s, err := NewKeySet(keys...)
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestRotation(t *testing.T) {
+	retired := newKey(t, "2020-04", ES256, now.AddDate(0, -2, 0), now.AddDate(0, 0, -1))
+	old := newKey(t, "2020-05", RS256, now.AddDate(0, -1, 0), now.AddDate(0, 1, 0))
+	current := newKey(t, "2020-06", ES256, now.AddDate(0, 0, -1), time.Time{})
+	next := newKey(t, "2020-07", ES256, now.AddDate(0, 0, 15), time.Time{})
+	s := newTestKeySet(t, next, current, retired, old)
+
+	key, err := s.SigningKey()
+	if err != nil {
+		t.Fatalf("SigningKey failed: %v", err)
+	}
+	if key.ID != current.ID {
+		t.Errorf("Expected signing key %s but was %s", current.ID, key.ID)
+	}
+
+	var kids []string
+	for _, k := range s.JWKS().Keys {
+		kids = append(kids, k.Kid)
+	}
+	if expected := []string{"2020-05", "2020-06", "2020-07"}; !reflect.DeepEqual(kids, expected) {
+		t.Errorf("Expected JWKS keys %v but was %v", expected, kids)
+	}
+
+	s.now = func() time.Time { return next.NotBefore }
+	if key, _ := s.SigningKey(); key.ID != next.ID {
+		t.Errorf("Expected signing key %s after rotation but was %s", next.ID, key.ID)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey := newKey(t, "rsa", RS256, now.AddDate(0, -1, 0), now.AddDate(0, 1, 0))
+	ecKey := newKey(t, "ec", ES256, now.AddDate(0, 0, -1), time.Time{})
+	retiredKey := newKey(t, "retired", ES256, now.AddDate(0, -2, 0), now.AddDate(0, 0, -1))
+	s := newTestKeySet(t, rsaKey, ecKey, retiredKey)
+	claims := jwt.StandardClaims{Subject: "user"}
+
+	signedBy := func(key Key, method jwt.SigningMethod) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = key.ID
+		signed, err := token.SignedString(key.PrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	signed, err := s.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hmacToken.Header["kid"] = rsaKey.ID
+	hmacSigned, _ := hmacToken.SignedString([]byte("secret"))
+
+	testCases := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "SignedBySet", token: signed, valid: true},
+		{name: "PreviousKey", token: signedBy(rsaKey, jwt.SigningMethodRS256), valid: true},
+		{name: "RetiredKey", token: signedBy(retiredKey, jwt.SigningMethodES256)},
+		{name: "UnknownKey", token: signedBy(newKey(t, "ec", ES256, now, time.Time{}), jwt.SigningMethodES256)},
+		{name: "HMAC", token: hmacSigned},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := jwt.Parse(tc.token, s.Keyfunc)
+			valid := err == nil && token.Valid
+			if valid != tc.valid {
+				t.Errorf("Expected token to be valid %t but was %t, err: %v", tc.valid, valid, err)
+			}
+		})
+	}
+}
+
+func TestNewKeySetError(t *testing.T) {
+	ecKey := newKey(t, "ec", ES256, now, time.Time{})
+	testCases := []struct {
+		name string
+		keys []Key
+	}{
+		{name: "NoKeys"},
+		{name: "MissingKeyID", keys: []Key{{Algorithm: ES256, PrivateKey: ecKey.PrivateKey}}},
+		{name: "AlgorithmMismatch", keys: []Key{{ID: "ec", Algorithm: RS256, PrivateKey: ecKey.PrivateKey}}},
+		{name: "UnsupportedAlgorithm", keys: []Key{{ID: "ec", Algorithm: "HS256", PrivateKey: ecKey.PrivateKey}}},
+		{name: "DuplicateKeyID", keys: []Key{ecKey, ecKey}},
+		{name: "InvalidSchedule", keys: []Key{{ID: "ec", Algorithm: ES256, PrivateKey: ecKey.PrivateKey,
+			NotBefore: now, NotAfter: now}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewKeySet(tc.keys...); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	ecKey := newKey(t, "ec", ES256, now, time.Time{})
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey.PrivateKey.(*ecdsa.PrivateKey))
+	rsaKey := newKey(t, "rsa", RS256, now, time.Time{})
+	rsaDER := x509.MarshalPKCS1PrivateKey(rsaKey.PrivateKey.(*rsa.PrivateKey))
+	files := map[string][]byte{
+		"ec.pem":  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}),
+		"rsa.pem": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: rsaDER}),
+		"keys.json": []byte(`[
+			{"kid": "rsa", "alg": "RS256", "private_key": "rsa.pem", "not_before": "2020-05-01T00:00:00Z",
+				"not_after": "2020-07-01T00:00:00Z"},
+			{"kid": "ec", "alg": "ES256", "private_key": "ec.pem", "not_before": "2020-06-01T00:00:00Z"}
+		]`),
+	}
+	for name, b := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := LoadKeySet(filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	s.now = func() time.Time { return now }
+
+	jwks := s.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("Expected 2 keys but was %d", len(jwks.Keys))
+	}
+	rsaJWK, ecJWK := jwks.Keys[0], jwks.Keys[1]
+	if rsaJWK.Kty != "RSA" || rsaJWK.Kid != "rsa" || rsaJWK.Alg != RS256 || rsaJWK.E != "AQAB" || rsaJWK.N == "" {
+		t.Errorf("Unexpected RSA key %+v", rsaJWK)
+	}
+	if ecJWK.Kty != "EC" || ecJWK.Kid != "ec" || ecJWK.Crv != "P-256" || len(ecJWK.X) != 43 || len(ecJWK.Y) != 43 {
+		t.Errorf("Unexpected EC key %+v", ecJWK)
+	}
+	if key, _ := s.SigningKey(); key.ID != "ec" {
+		t.Errorf("Expected signing key ec but was %s", key.ID)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"calories-counter/adapters/user_datastore"
 	"calories-counter/models"
 	"calories-counter/server"
+	"calories-counter/signing"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -14,22 +15,66 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 var (
-	secretKey = os.Getenv("TOKEN_SECRET")
-	dbSource  = os.Getenv("MYSQL_DB_SOURCE")
-	appID     = os.Getenv("API_APP_ID")
-	apiKey    = os.Getenv("API_KEY")
+	dbSource = os.Getenv("DB_SOURCE")
+	appID    = os.Getenv("API_APP_ID")
+	apiKey   = os.Getenv("API_KEY")
 )
 
+func newUserDatastore(t *testing.T) models.UserDatastore {
+	if dbSource == "" {
+		t.Log("DB_SOURCE is not set, using in-memory datastore")
+	}
+	userDatastore, err := user_datastore.Open(dbSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userDatastore.Close() })
+	return userDatastore
+}
+
 // this test uses original database and api instead of mocks
 func TestUserCRUD(t *testing.T) {
 	caloriesDatastore := calories_datastore.NewNutritionixApi(appID, apiKey)
-	userDatastore, _ := user_datastore.NewMySQLStore(dbSource)
-	defer func() { _ = userDatastore.Close() }()
+	userDatastore := newUserDatastore(t)
+	key, err := signing.GenerateKey("e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := signing.NewKeySet(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := server.New(server.Config{
+		Keys:               keys,
+		RequestTimeout:     10 * time.Second,
+		UnitOfWork:         userDatastore,
+		Credentials:        userDatastore,
+		Accounts:           userDatastore,
+		RefreshTokens:      userDatastore,
+		Revocations:        userDatastore,
+		TwoFactor:          userDatastore,
+		LoginThrottle:      userDatastore,
+		PasswordResets:     userDatastore,
+		APIKeys:            userDatastore,
+		Roles:              userDatastore,
+		Teams:              userDatastore,
+		OwnershipTransfers: userDatastore,
+		Invitations:        userDatastore,
+		Users:              userDatastore,
+		Meals:              userDatastore,
+		Settings:           userDatastore,
+		DailyTotals:        userDatastore,
+		Calories:           caloriesDatastore,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 	r := gin.Default()
-	server.SetupRouter(r, secretKey, userDatastore, caloriesDatastore)
+	s.SetupRouter(r)
 
 	ownerUsername := "userAdmin"
 	pass := "userAdmin1"
@@ -50,12 +95,139 @@ func TestUserCRUD(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w = httptest.NewRecorder()
 	r.ServeHTTP(w, req)
-	token := struct {
-		Token string `json:"token"`
-	}{}
+	var token server.TokenResponse
 	_ = json.NewDecoder(w.Body).Decode(&token)
 
-	t.Log("3. Create user")
+	// account is named after its owner unless the name was chosen at sign up
+	nameForm := url.Values{}
+	nameForm.Set("account", ownerUsername)
+	nameForm.Set("username", ownerUsername)
+	nameForm.Set("password", pass)
+	req, _ = http.NewRequest("POST", "/v1/signin", strings.NewReader(nameForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("sign in by account name failed")
+	}
+
+	t.Log("3. Refresh token")
+	refreshForm := url.Values{}
+	refreshForm.Set("refresh_token", token.RefreshToken)
+	req, _ = http.NewRequest("POST", "/v1/token/refresh", strings.NewReader(refreshForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var refreshed server.TokenResponse
+	_ = json.NewDecoder(w.Body).Decode(&refreshed)
+	if w.Code != http.StatusCreated || refreshed.RefreshToken == "" || refreshed.RefreshToken == token.RefreshToken {
+		t.Errorf("refresh token failed")
+	}
+	token = refreshed
+
+	req, _ = http.NewRequest("POST", "/v1/token/refresh", strings.NewReader(refreshForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("reused refresh token was accepted")
+	}
+
+	// reuse revokes access tokens of the sign in as well
+	req, _ = http.NewRequest("GET", "/v1/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("token of revoked family was accepted")
+	}
+	req, _ = http.NewRequest("POST", "/v1/account/"+userAdmin.AccountID+"/signin", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	_ = json.NewDecoder(w.Body).Decode(&token)
+
+	t.Log("4. Sign out other session")
+	req, _ = http.NewRequest("POST", "/v1/account/"+userAdmin.AccountID+"/signin", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var otherSession server.TokenResponse
+	_ = json.NewDecoder(w.Body).Decode(&otherSession)
+	req, _ = http.NewRequest("POST", "/v1/signout", nil)
+	req.Header.Set("Authorization", "Bearer "+otherSession.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("sign out failed")
+	}
+	req, _ = http.NewRequest("GET", "/v1/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+otherSession.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("signed out token was accepted")
+	}
+
+	t.Log("5. Change password")
+	newPass := "userAdmin2"
+	req, _ = http.NewRequest("PUT", "/v1/me/password", strings.NewReader(fmt.Sprintf(`{"old_password":"%s","new_password":"%s"}`, pass, newPass)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	oldToken := token.Token
+	_ = json.NewDecoder(w.Body).Decode(&token)
+	if w.Code != http.StatusOK || token.Token == oldToken {
+		t.Errorf("change password failed")
+	}
+	req, _ = http.NewRequest("GET", "/v1/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+oldToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("token issued before password change was accepted")
+	}
+
+	t.Log("6. API key")
+	req, _ = http.NewRequest("POST", "/v1/me/api-keys", strings.NewReader(`{"name":"sync","scopes":["users:manage"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var apiKey server.APIKeyResponse
+	_ = json.NewDecoder(w.Body).Decode(&apiKey)
+	if w.Code != http.StatusCreated || apiKey.Key == "" {
+		t.Errorf("create API key failed")
+	}
+	req, _ = http.NewRequest("GET", "/v1/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+apiKey.Key)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("API key was not accepted")
+	}
+	req, _ = http.NewRequest("POST", "/v1/me/api-keys", strings.NewReader(`{"name":"escalate"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey.Key)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("API key created another API key")
+	}
+	req, _ = http.NewRequest("DELETE", "/v1/me/api-keys/"+apiKey.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	req, _ = http.NewRequest("GET", "/v1/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+apiKey.Key)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("deleted API key was accepted")
+	}
+
+	t.Log("7. Create user")
 	testUsername := "usertest"
 	req, _ = http.NewRequest("POST", "/v1/users/", strings.NewReader(fmt.Sprintf(`{"username":"%s","password":"%s"}`, testUsername, pass)))
 	req.Header.Set("Content-Type", "application/json")
@@ -68,7 +240,7 @@ func TestUserCRUD(t *testing.T) {
 		t.Errorf("create user failed")
 	}
 
-	t.Log("4. Update user")
+	t.Log("8. Update user")
 	newTestUsername := "newTestUsername"
 	req, _ = http.NewRequest("PUT", "/v1/users/"+user.ID, strings.NewReader(fmt.Sprintf(`{"username":"%s"}`, newTestUsername)))
 	req.Header.Set("Content-Type", "application/json")
@@ -81,13 +253,200 @@ func TestUserCRUD(t *testing.T) {
 		t.Errorf("update user failed")
 	}
 
-	t.Log("5. Delete user")
+	t.Log("9. Reset user password")
+	req, _ = http.NewRequest("POST", "/v1/users/"+user.ID+"/password/reset", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var reset server.PasswordResetResponse
+	_ = json.NewDecoder(w.Body).Decode(&reset)
+	resetForm := url.Values{}
+	resetForm.Set("reset_token", reset.ResetToken)
+	resetForm.Set("password", newPass)
+	req, _ = http.NewRequest("POST", "/v1/password/reset", strings.NewReader(resetForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("reset password failed")
+	}
+	userForm := url.Values{}
+	userForm.Set("username", newTestUsername)
+	userForm.Set("password", newPass)
+	req, _ = http.NewRequest("POST", "/v1/account/"+userAdmin.AccountID+"/signin", strings.NewReader(userForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("sign in with reset password failed")
+	}
+
+	t.Log("10. Delete user")
 	req, _ = http.NewRequest("DELETE", "/v1/users/"+user.ID, nil)
 	req.Header.Set("Authorization", "Bearer "+token.Token)
 	w = httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
-	t.Log("6. Delete myself")
+	t.Log("11. Custom role")
+	req, _ = http.NewRequest("POST", "/v1/roles/", strings.NewReader(`{"name":"auditor","permissions":["users:read"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var role models.Role
+	_ = json.NewDecoder(w.Body).Decode(&role)
+	if w.Code != http.StatusCreated || role.ID != models.CustomRoleMinID {
+		t.Errorf("create role failed")
+	}
+	req, _ = http.NewRequest("POST", "/v1/users/", strings.NewReader(fmt.Sprintf(`{"username":"auditor","password":"%s","role_id":%d}`, newPass, role.ID)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	auditorForm := url.Values{}
+	auditorForm.Set("username", "auditor")
+	auditorForm.Set("password", newPass)
+	req, _ = http.NewRequest("POST", "/v1/account/"+userAdmin.AccountID+"/signin", strings.NewReader(auditorForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var auditorToken server.TokenResponse
+	_ = json.NewDecoder(w.Body).Decode(&auditorToken)
+	req, _ = http.NewRequest("GET", "/v1/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+auditorToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("user of custom role can't read users")
+	}
+	req, _ = http.NewRequest("DELETE", "/v1/users/"+userAdmin.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+auditorToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("user of custom role deleted a user")
+	}
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/v1/roles/%d", role.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("role assigned to a user was deleted")
+	}
+
+	t.Log("12. Manager team")
+	req, _ = http.NewRequest("POST", "/v1/users/", strings.NewReader(fmt.Sprintf(`{"username":"manager","password":"%s","role_id":%d}`, newPass, models.UserManagerRole)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var manager models.User
+	_ = json.NewDecoder(w.Body).Decode(&manager)
+	managerForm := url.Values{}
+	managerForm.Set("username", "manager")
+	managerForm.Set("password", newPass)
+	req, _ = http.NewRequest("POST", "/v1/account/"+userAdmin.AccountID+"/signin", strings.NewReader(managerForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var managerToken server.TokenResponse
+	_ = json.NewDecoder(w.Body).Decode(&managerToken)
+	req, _ = http.NewRequest("POST", "/v1/users/", strings.NewReader(fmt.Sprintf(`{"username":"member","password":"%s"}`, newPass)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var member models.User
+	_ = json.NewDecoder(w.Body).Decode(&member)
+	req, _ = http.NewRequest("GET", "/v1/users/"+member.ID+"/meals/", nil)
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("manager can't read meals of team member")
+	}
+	req, _ = http.NewRequest("PUT", "/v1/users/"+member.ID+"/settings/", strings.NewReader(`{"expected_daily_calories":2000}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("manager changed settings of team member")
+	}
+	req, _ = http.NewRequest("GET", "/v1/users/"+member.ID+"/summary?from=2020-01-01", nil)
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("manager can't read daily summary of team member")
+	}
+
+	t.Log("13. Invitation")
+	req, _ = http.NewRequest("POST", "/v1/invitations/", strings.NewReader(`{"name":"Invited member"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var invitation server.InvitationResponse
+	_ = json.NewDecoder(w.Body).Decode(&invitation)
+	if w.Code != http.StatusCreated || invitation.Token == "" {
+		t.Errorf("invite user failed")
+	}
+	acceptForm := url.Values{}
+	acceptForm.Set("invitation_token", invitation.Token)
+	acceptForm.Set("username", "invited")
+	acceptForm.Set("password", newPass)
+	req, _ = http.NewRequest("POST", "/v1/signup/invitation", strings.NewReader(acceptForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var invited models.User
+	_ = json.NewDecoder(w.Body).Decode(&invited)
+	if w.Code != http.StatusCreated || invited.AccountID != userAdmin.AccountID {
+		t.Errorf("accept invitation failed")
+	}
+	req, _ = http.NewRequest("POST", "/v1/signup/invitation", strings.NewReader(acceptForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("invitation was accepted twice")
+	}
+	req, _ = http.NewRequest("GET", "/v1/users/"+invited.ID+"/meals/", nil)
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("invited user did not join team of manager")
+	}
+
+	t.Log("14. Transfer ownership")
+	req, _ = http.NewRequest("DELETE", "/v1/users/"+userAdmin.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("the only owner was deleted")
+	}
+	req, _ = http.NewRequest("POST", "/v1/owners/transfer", strings.NewReader(fmt.Sprintf(`{"user_id":"%s","password":"%s"}`, manager.ID, newPass)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("offer ownership failed")
+	}
+	req, _ = http.NewRequest("POST", "/v1/owners/transfer/accept", nil)
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var newOwner models.User
+	_ = json.NewDecoder(w.Body).Decode(&newOwner)
+	if w.Code != http.StatusOK || newOwner.RoleID != models.OwnerRole {
+		t.Errorf("accept ownership failed")
+	}
+
+	t.Log("15. Delete myself")
 	req, _ = http.NewRequest("DELETE", "/v1/users/"+userAdmin.ID, nil)
 	req.Header.Set("Authorization", "Bearer "+token.Token)
 	w = httptest.NewRecorder()
@@ -100,4 +459,46 @@ func TestUserCRUD(t *testing.T) {
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("delete user failed")
 	}
+
+	t.Log("16. Delete account")
+	req, _ = http.NewRequest("DELETE", "/v1/account", strings.NewReader(fmt.Sprintf(`{"password":"%s"}`, newPass)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var deletion server.AccountDeletionResponse
+	_ = json.NewDecoder(w.Body).Decode(&deletion)
+	if w.Code != http.StatusAccepted || deletion.RestoreToken == "" {
+		t.Errorf("delete account failed")
+	}
+	req, _ = http.NewRequest("GET", "/v1/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("token of deleted account still works")
+	}
+	restoreForm := url.Values{}
+	restoreForm.Set("restore_token", deletion.RestoreToken)
+	req, _ = http.NewRequest("POST", "/v1/restore", strings.NewReader(restoreForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("restore account failed")
+	}
+	req, _ = http.NewRequest("POST", "/v1/restore", strings.NewReader(restoreForm.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("restore token was used twice")
+	}
+	req, _ = http.NewRequest("GET", "/v1/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+managerToken.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("token of restored account does not work")
+	}
 }
@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret of RFC 6238 test vectors, ASCII "12345678901234567890"
+const testSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	testCases := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(testSecret, Step(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code failed: %v", err)
+		}
+		if code != tc.expected {
+			t.Errorf("Expected code at %d to be %s but was %s", tc.unix, tc.expected, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	testCases := []struct {
+		name  string
+		code  string
+		valid bool
+	}{
+		{name: "Current", code: "050471", valid: true},
+		{name: "Previous", code: "081804", valid: true},
+		{name: "TooOld", code: "005924"},
+		{name: "Short", code: "05047"},
+		{name: "NotNumber", code: "abcdef"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step, valid := Validate(testSecret, tc.code, now)
+			if valid != tc.valid {
+				t.Errorf("Expected code to be valid %t but was %t", tc.valid, valid)
+			}
+			if valid && (step < Step(now)-Skew || step > Step(now)+Skew) {
+				t.Errorf("Unexpected step %d", step)
+			}
+		})
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := ProvisioningURI("calories-counter", "user@example.com", secret)
+	expectedPrefix := "otpauth://totp/calories-counter:user@example.com?algorithm=SHA1&digits=6&issuer=calories-counter&period=30&secret="
+	if !strings.HasPrefix(uri, expectedPrefix) || !strings.HasSuffix(uri, secret) {
+		t.Errorf("Unexpected provisioning URI `%s`", uri)
+	}
+}